@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ANSI escape sequences used by the console log format
+const (
+	ansiReset  = "\033[0m"
+	ansiFaint  = "\033[2m"
+	ansiRed    = "\033[91m"
+	ansiGreen  = "\033[92m"
+	ansiYellow = "\033[93m"
+	ansiBlue   = "\033[94m"
+)
+
+// consoleMessageWidth is the width messages are padded to, so that the
+// attributes of consecutive lines start in the same column
+const consoleMessageWidth = 44
+
+// consoleHandler is a slog handler writing human-friendly lines such as
+//
+//	15:04:05.000 INF aggregation completed           routers=3 services=2
+//
+// Level labels, attribute keys and errors are colored when color is set.
+type consoleHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex // Serializes writes of handlers sharing w
+	level slog.Leveler
+	color bool
+
+	attrs  string // Preformatted attributes added through WithAttrs
+	groups string // Prefix of attribute keys added through WithGroup, e.g. "a.b."
+}
+
+// newConsoleHandler creates a console handler writing to w
+func newConsoleHandler(w io.Writer, opts *slog.HandlerOptions, color bool) *consoleHandler {
+	var level slog.Leveler = slog.LevelInfo
+	if opts != nil && opts.Level != nil {
+		level = opts.Level
+	}
+
+	return &consoleHandler{w: w, mu: &sync.Mutex{}, level: level, color: color}
+}
+
+// isTerminal reports whether w is a terminal that should receive colored
+// output. Colors are disabled by setting the NO_COLOR environment variable.
+func isTerminal(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Enabled reports whether records of the level are written
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle writes the record as a single line
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+
+	if !r.Time.IsZero() {
+		h.paint(&b, ansiFaint, r.Time.Format(time.TimeOnly+".000"))
+		b.WriteByte(' ')
+	}
+
+	label, color := levelLabel(r.Level)
+	h.paint(&b, color, label)
+	b.WriteByte(' ')
+	b.WriteString(r.Message)
+
+	attrs := h.attrs
+
+	if r.NumAttrs() > 0 {
+		var a strings.Builder
+
+		r.Attrs(func(attr slog.Attr) bool {
+			h.appendAttr(&a, h.groups, attr)
+			return true
+		})
+
+		attrs += a.String()
+	}
+
+	if attrs != "" {
+		if pad := consoleMessageWidth - len(r.Message); pad > 0 {
+			b.WriteString(strings.Repeat(" ", pad))
+		}
+
+		b.WriteString(attrs)
+	}
+
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := io.WriteString(h.w, b.String())
+
+	return err
+}
+
+// WithAttrs returns a handler adding attrs to every record
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	var b strings.Builder
+
+	for _, attr := range attrs {
+		h.appendAttr(&b, h.groups, attr)
+	}
+
+	clone := *h
+	clone.attrs += b.String()
+
+	return &clone
+}
+
+// WithGroup returns a handler qualifying the keys of later attributes with name
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.groups += name + "."
+
+	return &clone
+}
+
+// appendAttr writes " key=value" for the attribute, flattening groups into
+// dotted keys
+func (h *consoleHandler) appendAttr(b *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+
+		for _, member := range attr.Value.Group() {
+			h.appendAttr(b, prefix, member)
+		}
+
+		return
+	}
+
+	b.WriteByte(' ')
+	h.paint(b, ansiFaint, prefix+attr.Key+"=")
+
+	value := consoleValue(attr.Value)
+
+	if _, isErr := attr.Value.Any().(error); isErr {
+		h.paint(b, ansiRed, value)
+	} else {
+		b.WriteString(value)
+	}
+}
+
+// paint writes s wrapped in the color sequence when colors are enabled
+func (h *consoleHandler) paint(b *strings.Builder, color, s string) {
+	if !h.color || color == "" {
+		b.WriteString(s)
+		return
+	}
+
+	b.WriteString(color)
+	b.WriteString(s)
+	b.WriteString(ansiReset)
+}
+
+// levelLabel returns the three-letter label of the level and its color.
+// Levels between the standard ones are shown relative to the one below,
+// e.g. "INF+2".
+func levelLabel(level slog.Level) (string, string) {
+	var (
+		label string
+		color string
+		base  slog.Level
+	)
+
+	switch {
+	case level < slog.LevelInfo:
+		label, color, base = "DBG", ansiBlue, slog.LevelDebug
+	case level < slog.LevelWarn:
+		label, color, base = "INF", ansiGreen, slog.LevelInfo
+	case level < slog.LevelError:
+		label, color, base = "WRN", ansiYellow, slog.LevelWarn
+	default:
+		label, color, base = "ERR", ansiRed, slog.LevelError
+	}
+
+	if delta := level - base; delta != 0 {
+		label += fmt.Sprintf("%+d", delta)
+	}
+
+	return label, color
+}
+
+// consoleValue formats an attribute value, quoting it when it is empty or
+// contains spaces, quotes or control characters
+func consoleValue(v slog.Value) string {
+	var s string
+
+	switch v.Kind() {
+	case slog.KindTime:
+		s = v.Time().Format(time.RFC3339Nano)
+	default:
+		s = v.String()
+	}
+
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r)
+	}) {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleHandlerLevelLabels(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{slog.LevelDebug, "DBG"},
+		{slog.LevelInfo, "INF"},
+		{slog.LevelWarn, "WRN"},
+		{slog.LevelError, "ERR"},
+		{slog.LevelInfo + 2, "INF+2"},
+		{slog.LevelDebug - 1, "DBG-1"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+
+		logger := slog.New(newConsoleHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug - 4}, false))
+		logger.Log(t.Context(), tt.level, "polled upstream")
+
+		fields := strings.Fields(buf.String())
+		if assert.Len(t, fields, 4, buf.String()) {
+			assert.Equal(t, tt.want, fields[1])
+		}
+	}
+}
+
+func TestConsoleHandlerAttributes(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(newConsoleHandler(&buf, nil, false)).With("upstream", "host1").WithGroup("poll")
+	logger.Debug("not written")
+	logger.Error("poll failed", "error", errors.New("connection refused"), slog.Group("http", "status", 502))
+
+	line := buf.String()
+
+	// Attributes start at the same column whatever the message length
+	assert.Equal(t, 12+len(" ERR ")+consoleMessageWidth, strings.Index(line, " upstream="))
+	assert.True(t, strings.HasSuffix(line, ` upstream=host1 poll.error="connection refused" poll.http.status=502`+"\n"), line)
+}
+
+func TestConsoleHandlerColors(t *testing.T) {
+	var buf bytes.Buffer
+
+	slog.New(newConsoleHandler(&buf, nil, true)).Warn("slow upstream", "error", errors.New("timeout"))
+
+	assert.Contains(t, buf.String(), ansiYellow+"WRN"+ansiReset)
+	assert.Contains(t, buf.String(), ansiRed+"timeout"+ansiReset)
+
+	// Writers other than terminals get no colors
+	assert.False(t, isTerminal(&buf))
+
+	buf.Reset()
+	setupLogger(config.LogConfig{Format: "console", Level: "info"}, &buf).Info("started")
+
+	assert.NotContains(t, buf.String(), "\033[")
+	assert.Contains(t, buf.String(), " INF started\n")
+}
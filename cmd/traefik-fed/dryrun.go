@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/chickenzord/traefik-fed/internal/output"
+)
+
+// runDryRun runs a single aggregation and writes the result as YAML to w.
+// It fails when the aggregation produced zero routers.
+func runDryRun(cfg *config.Config, logger *slog.Logger, w io.Writer) error {
+	dynConfig, err := aggregator.New(cfg, logger).Aggregate()
+	if err != nil {
+		return fmt.Errorf("aggregation failed: %w", err)
+	}
+
+	routers := len(dynConfig.HTTP.Routers)
+	if dynConfig.TCP != nil {
+		routers += len(dynConfig.TCP.Routers)
+	}
+
+	if dynConfig.UDP != nil {
+		routers += len(dynConfig.UDP.Routers)
+	}
+
+	if routers == 0 {
+		return fmt.Errorf("aggregation produced zero routers")
+	}
+
+	return output.EncodeYAML(w, dynConfig)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockUpstream(t *testing.T, routersJSON string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/http/routers", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(routersJSON))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func dryRunConfig(adminURL string) *config.Config {
+	return &config.Config{
+		Upstreams: []config.Upstream{
+			{Name: "host1", AdminURL: adminURL, ServerURL: "http://192.168.1.10:80"},
+		},
+		Routers: config.RouterConfig{
+			Selector: config.RouterSelector{Status: "enabled"},
+		},
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	upstream := mockUpstream(t, `[
+		{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(`+"`app.example.com`"+`)"},
+		{"name": "api@internal", "provider": "internal", "status": "enabled", "rule": "PathPrefix(`+"`/api`"+`)"}
+	]`)
+
+	var out bytes.Buffer
+
+	err := runDryRun(dryRunConfig(upstream.URL), slog.New(slog.NewTextHandler(io.Discard, nil)), &out)
+	require.NoError(t, err)
+
+	assert.Contains(t, out.String(), "http:")
+	assert.Contains(t, out.String(), "host1-webapp")
+	assert.Contains(t, out.String(), "host1-traefik")
+	assert.NotContains(t, out.String(), "host1-api")
+}
+
+func TestRunDryRunZeroRouters(t *testing.T) {
+	upstream := mockUpstream(t, `[]`)
+
+	var out bytes.Buffer
+
+	err := runDryRun(dryRunConfig(upstream.URL), slog.New(slog.NewTextHandler(io.Discard, nil)), &out)
+	require.Error(t, err)
+	assert.Empty(t, out.String())
+}
@@ -3,9 +3,13 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
+	"reflect"
+	"sync"
 	"syscall"
 	"time"
 
@@ -17,11 +21,37 @@ import (
 
 func main() {
 	configPath := flag.String("config", "config.yaml", "Path to configuration file")
+	watchConfig := flag.Bool("watch", true, "Reload configuration automatically when the file changes")
+	dryRun := flag.Bool("dry-run", false, "Aggregate once, print the result as YAML to stdout and exit")
+	once := flag.Bool("once", false, "Aggregate once, write the file outputs and exit, e.g. when run from cron")
+	validate := flag.Bool("validate", false, "Check the configuration file and exit without contacting upstreams")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration with defaults applied as YAML and exit")
+	strictEnv := flag.Bool("strict-env", false, "Fail when the config references an undefined environment variable without a default")
 
 	flag.Parse()
 
 	// Load configuration first (we need it for logger setup)
-	cfg, err := config.Load(*configPath)
+	loadOptions := config.LoadOptions{StrictEnv: *strictEnv}
+
+	if *validate {
+		if err := runValidate(*configPath, loadOptions, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if *printConfig {
+		if err := runPrintConfig(*configPath, loadOptions, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	cfg, err := config.LoadWithOptions(*configPath, loadOptions)
 	if err != nil {
 		// Use default logger for config loading errors
 		logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
@@ -41,14 +71,56 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Dry run prints the config to stdout, so keep logs on stderr
+	if *dryRun {
+		logger := setupLogger(cfg.Log, os.Stderr)
+		if err := runDryRun(cfg, logger, os.Stdout); err != nil {
+			logger.Error("dry run failed", "error", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	// Setup logger based on configuration
-	logger := setupLogger(cfg.Log)
+	logger := setupLogger(cfg.Log, os.Stdout)
+
+	for _, warning := range cfg.Warnings() {
+		logger.Warn(warning)
+	}
+
+	// Once mode writes the file outputs without serving or polling
+	if *once {
+		if err := runOnce(context.Background(), cfg, logger); err != nil {
+			logger.Error("once mode failed", "error", err)
+			os.Exit(1)
+		}
+
+		return
+	}
 
 	logger.Info("loaded configuration",
 		"upstreams", len(cfg.Upstreams),
 		"poll_interval", cfg.Server.PollInterval,
 		"http_enabled", cfg.Output.HTTP.Enabled,
-		"file_enabled", cfg.Output.File.Enabled)
+		"file_outputs", len(cfg.Output.FileOutputs()),
+		"webhook_enabled", cfg.Output.Webhook.Enabled)
+
+	// Export spans of poll cycles and upstream requests if enabled
+	shutdownTracing, err := setupTracing(context.Background(), cfg.Server.Tracing)
+	if err != nil {
+		logger.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	defer func() {
+		flushCtx, cancelFlush := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelFlush()
+
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Warn("failed to flush traces", "error", err)
+		}
+	}()
 
 	// Create aggregator
 	agg := aggregator.New(cfg, logger)
@@ -57,14 +129,30 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Verify upstreams are reachable before serving anything
+	if cfg.Server.StartupCheck.Enabled {
+		if err := runStartupCheck(ctx, agg, cfg.Server.StartupCheck, logger); err != nil {
+			logger.Error("startup check failed", "error", err)
+			cancel()
+			os.Exit(1)
+		}
+	}
+
 	// Handle signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	// SIGHUP forces a config reload, e.g. to pick up rotated secret files
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	// Outputs receiving every aggregated configuration
+	var sinks []output.Sink
+
 	// Start HTTP server if enabled
 	var httpServer *output.HTTPServer
 	if cfg.Output.HTTP.Enabled {
-		httpServer = output.NewHTTPServer(cfg.Output.HTTP.Port, cfg.Output.HTTP.Path, logger)
+		httpServer = output.NewHTTPServer(cfg.Output.HTTP, logger)
 
 		go func() {
 			if err := httpServer.Start(); err != nil {
@@ -72,76 +160,335 @@ func main() {
 				cancel()
 			}
 		}()
+
+		sinks = append(sinks, httpServer)
 	}
 
-	// Start file writer if enabled
-	var fileConfigChan chan *dynamic.HTTPConfiguration
-	if cfg.Output.File.Enabled {
-		fileConfigChan = make(chan *dynamic.HTTPConfiguration, 1)
-		fileWriter := output.NewFileWriter(cfg.Output.File.Path, cfg.Output.File.Interval, logger)
+	// Start file writers if enabled. They, like the webhook sender, write the
+	// latest configuration one last time on shutdown, which is awaited before
+	// exiting.
+	var flushers sync.WaitGroup
 
-		go func() {
-			if err := fileWriter.Start(fileConfigChan); err != nil {
-				logger.Error("file writer failed", "error", err)
+	for _, fileOutput := range cfg.Output.FileOutputs() {
+		fileWriter := output.NewFileWriter(fileOutput, logger)
+		sinks = append(sinks, fileWriter)
+
+		flushers.Go(func() {
+			if err := fileWriter.Run(ctx); err != nil {
+				logger.Error("file writer failed", "output", fileWriter.Name(), "error", err)
 				cancel()
 			}
+		})
+	}
+
+	// Start webhook sender if enabled
+	if cfg.Output.Webhook.Enabled {
+		webhookSender := output.NewWebhookSender(cfg.Output.Webhook, logger)
+		sinks = append(sinks, webhookSender)
+
+		flushers.Go(func() {
+			if err := webhookSender.Run(ctx); err != nil {
+				logger.Error("webhook sender failed", "error", err)
+			}
+		})
+	}
+
+	// Start Redis writer if enabled
+	if cfg.Output.Redis.Enabled {
+		redisWriter := output.NewRedisWriter(cfg.Output.Redis, logger)
+		sinks = append(sinks, redisWriter)
+
+		go func() {
+			if err := redisWriter.Run(ctx); err != nil {
+				logger.Error("redis writer failed", "error", err)
+			}
 		}()
 	}
 
-	// Main polling loop
-	ticker := time.NewTicker(cfg.Server.PollInterval)
-	defer ticker.Stop()
+	// Write the router metadata sidecar if enabled
+	var metadataWriter *output.MetadataWriter
+	if cfg.Output.Metadata.Enabled {
+		metadataWriter = output.NewMetadataWriter(cfg.Output.Metadata, logger)
+	}
+
+	// Watch configuration file if enabled
+	var (
+		configChan <-chan *config.Config
+		watcher    *config.Watcher
+	)
+
+	if *watchConfig {
+		var err error
+
+		watcher, err = config.NewWatcher(*configPath, logger)
+		if err != nil {
+			logger.Error("failed to watch configuration file, automatic reload disabled", "error", err)
+		} else {
+			watcher.SetLoadOptions(loadOptions)
+			configChan = watcher.Configs()
+
+			go func() {
+				if err := watcher.Run(ctx); err != nil {
+					logger.Error("config watcher failed", "error", err)
+				}
+			}()
+		}
+	}
+
+	// Aggregation requests made through POST /reload
+	var reloads <-chan chan int
+	if httpServer != nil {
+		reloads = httpServer.Reloads()
+	}
+
+	// Run initial aggregation, then poll each upstream on its own interval
+	updates := make(chan struct{}, 1)
+	stopPolling, initialized := startPolling(ctx, agg, updates)
+	delayCtx, stopDelay := context.WithCancel(ctx)
+	applied := delayUpdates(delayCtx, cfg.Server.ApplyDelay, updates)
 
-	// Run initial aggregation
-	runAggregation(agg, httpServer, fileConfigChan, logger)
+	// An empty initial configuration is treated as a deployment error
+	if !cfg.Server.FailOnEmpty {
+		initialized = nil
+	}
 
 	for {
 		select {
+		case <-initialized:
+			initialized = nil
+
+			if err := checkNotEmpty(agg); err != nil {
+				logger.Error("no routers aggregated", "error", err)
+				cancel()
+				os.Exit(1)
+			}
 		case <-ctx.Done():
 			logger.Info("shutting down")
+			awaitFlush(cancel, &flushers, shutdownTimeout, logger)
+
 			return
 		case <-sigChan:
 			logger.Info("received shutdown signal")
+			awaitFlush(cancel, &flushers, shutdownTimeout, logger)
+
 			return
-		case <-ticker.C:
-			runAggregation(agg, httpServer, fileConfigChan, logger)
+		case <-hupChan:
+			if watcher == nil {
+				logger.Warn("received SIGHUP but configuration reload is disabled")
+				continue
+			}
+
+			watcher.Reload()
+		case <-applied:
+			publish(ctx, agg, httpServer, metadataWriter, sinks, logger)
+		case reply := <-reloads:
+			reply <- reload(ctx, agg, httpServer, metadataWriter, sinks, logger)
+		case newCfg := <-configChan:
+			if !reflect.DeepEqual(newCfg.Output, cfg.Output) {
+				logger.Warn("output configuration changed, restart required to apply it")
+			}
+
+			stopPolling()
+
+			// Updates delayed by the old apply_delay are superseded by the
+			// initial poll of the new configuration
+			if newCfg.Server.ApplyDelay != cfg.Server.ApplyDelay {
+				stopDelay()
+
+				delayCtx, stopDelay = context.WithCancel(ctx)
+				applied = delayUpdates(delayCtx, newCfg.Server.ApplyDelay, updates)
+			}
+
+			cfg = newCfg
+			agg = aggregator.New(cfg, logger)
+
+			logger.Info("reloaded configuration",
+				"upstreams", len(cfg.Upstreams),
+				"poll_interval", cfg.Server.PollInterval)
+
+			var reinitialized <-chan struct{}
+
+			stopPolling, reinitialized = startPolling(ctx, agg, updates)
+
+			// A pending startup check applies to the new configuration
+			if initialized != nil {
+				initialized = reinitialized
+			}
+		}
+	}
+}
+
+// shutdownTimeout bounds the wait for outputs to flush on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// awaitFlush stops the outputs and waits up to timeout for them to write or
+// send the latest configuration
+func awaitFlush(cancel context.CancelFunc, writers *sync.WaitGroup, timeout time.Duration, logger *slog.Logger) {
+	cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		writers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		logger.Warn("timed out waiting for outputs to flush", "timeout", timeout)
+	}
+}
+
+// startPolling runs an initial aggregation of all upstreams and then polls
+// each upstream, all in the background, signalling updates after every poll.
+// It returns a function that stops the polling and a channel closed once the
+// initial aggregation is done.
+func startPolling(ctx context.Context, agg *aggregator.Aggregator, updates chan<- struct{}) (context.CancelFunc, <-chan struct{}) {
+	notify := func() {
+		select {
+		case updates <- struct{}{}:
+		default:
+			// An update is already pending
 		}
 	}
+
+	pollCtx, cancel := context.WithCancel(ctx)
+	initialized := make(chan struct{})
+
+	// Slow upstreams must not keep the main loop from handling signals
+	go func() {
+		agg.Refresh(pollCtx)
+		close(initialized)
+		notify()
+
+		agg.Run(pollCtx, notify)
+	}()
+
+	return cancel, initialized
 }
 
-func runAggregation(
+// delayUpdates batches update signals: the first signal starts a timer of
+// delay, and a single signal is forwarded when it fires, however many arrived
+// meanwhile. Without a delay, updates are returned as is.
+func delayUpdates(ctx context.Context, delay time.Duration, updates <-chan struct{}) <-chan struct{} {
+	if delay <= 0 {
+		return updates
+	}
+
+	delayed := make(chan struct{}, 1)
+
+	go func() {
+		var timer <-chan time.Time
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-updates:
+				if timer == nil {
+					timer = time.After(delay)
+				}
+			case <-timer:
+				timer = nil
+
+				select {
+				case delayed <- struct{}{}:
+				default:
+					// An update is already pending
+				}
+			}
+		}
+	}()
+
+	return delayed
+}
+
+// reload polls all upstreams immediately, publishes the result and returns
+// the number of HTTP routers served
+func reload(
+	ctx context.Context,
 	agg *aggregator.Aggregator,
 	httpServer *output.HTTPServer,
-	fileConfigChan chan *dynamic.HTTPConfiguration,
+	metadataWriter *output.MetadataWriter,
+	sinks []output.Sink,
 	logger *slog.Logger,
-) {
-	httpConfig, err := agg.Aggregate()
-	if err != nil {
-		logger.Error("aggregation failed", "error", err)
-		return
+) int {
+	logger.Info("reload requested, polling all upstreams")
+
+	agg.Refresh(ctx)
+	dynConfig := publish(ctx, agg, httpServer, metadataWriter, sinks, logger)
+
+	return len(dynConfig.HTTP.Routers)
+}
+
+// publish sends the latest aggregated configuration to all outputs and
+// returns it. The HTTP server, when enabled, also receives the router mappings
+// and stats, and the metadata writer, when enabled, the router mappings.
+func publish(
+	ctx context.Context,
+	agg *aggregator.Aggregator,
+	httpServer *output.HTTPServer,
+	metadataWriter *output.MetadataWriter,
+	sinks []output.Sink,
+	logger *slog.Logger,
+) *dynamic.Configuration {
+	dynConfig := agg.Snapshot()
+	stats := agg.Stats()
+
+	logArgs := []any{
+		"routers", len(dynConfig.HTTP.Routers),
+		"services", len(dynConfig.HTTP.Services),
+		"duration_ms", stats.DurationMs,
+	}
+	if dynConfig.TCP != nil {
+		logArgs = append(logArgs,
+			"tcp_routers", len(dynConfig.TCP.Routers),
+			"tcp_services", len(dynConfig.TCP.Services))
+	}
+
+	if dynConfig.UDP != nil {
+		logArgs = append(logArgs,
+			"udp_routers", len(dynConfig.UDP.Routers),
+			"udp_services", len(dynConfig.UDP.Services))
 	}
 
-	logger.Info("aggregation completed",
-		"routers", len(httpConfig.Routers),
-		"services", len(httpConfig.Services))
+	logger.Info("aggregation completed", logArgs...)
+
+	groups := agg.Groups()
 
-	// Update HTTP server if enabled
 	if httpServer != nil {
-		httpServer.UpdateConfig(httpConfig)
+		httpServer.UpdateGroups(groups)
+		httpServer.UpdateMappings(agg.Mappings())
+		httpServer.UpdateRawRouters(agg.RawRouters())
+		httpServer.UpdateStats(stats)
 	}
 
-	// Send to file writer if enabled
-	if fileConfigChan != nil {
-		select {
-		case fileConfigChan <- httpConfig:
-		default:
-			// Channel full, skip this update
+	if metadataWriter != nil {
+		if err := metadataWriter.Update(agg.Mappings()); err != nil {
+			logger.Error("failed to write router metadata", "error", err)
 		}
 	}
+
+	for _, sink := range sinks {
+		var err error
+
+		if upstreamSink, ok := sink.(output.UpstreamSink); ok {
+			err = upstreamSink.UpdateUpstreams(ctx, dynConfig, groups)
+		} else {
+			err = sink.Update(ctx, dynConfig)
+		}
+
+		if err != nil {
+			logger.Error("failed to update output", "output", sink.Name(), "error", err)
+		}
+	}
+
+	return dynConfig
 }
 
 // setupLogger creates a logger based on configuration
-func setupLogger(cfg config.LogConfig) *slog.Logger {
+func setupLogger(cfg config.LogConfig, w io.Writer) *slog.Logger {
 	// Parse log level
 	var level slog.Level
 
@@ -167,11 +514,13 @@ func setupLogger(cfg config.LogConfig) *slog.Logger {
 
 	switch cfg.Format {
 	case "json":
-		handler = slog.NewJSONHandler(os.Stdout, handlerOpts)
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	case "console":
+		handler = newConsoleHandler(w, handlerOpts, isTerminal(w))
 	case "plain":
-		handler = slog.NewTextHandler(os.Stdout, handlerOpts)
+		handler = slog.NewTextHandler(w, handlerOpts)
 	default:
-		handler = slog.NewTextHandler(os.Stdout, handlerOpts)
+		handler = slog.NewTextHandler(w, handlerOpts)
 	}
 
 	return slog.New(handler)
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/chickenzord/traefik-fed/internal/output"
+)
+
+// runOnce runs a single aggregation and writes it to every file output, for
+// running as a periodic job instead of a daemon. No server is started and no
+// polling happens. Nothing is written when an upstream failed, since unlike
+// the daemon there is no last good state to keep serving its routers from,
+// nor with server.fail_on_empty when the aggregation produced no routers.
+func runOnce(ctx context.Context, cfg *config.Config, logger *slog.Logger) error {
+	fileOutputs := cfg.Output.FileOutputs()
+	if len(fileOutputs) == 0 {
+		return fmt.Errorf("once mode requires a file output")
+	}
+
+	agg := aggregator.New(cfg, logger)
+	agg.Refresh(ctx)
+
+	dynConfig := agg.Snapshot()
+	groups := agg.Groups()
+
+	var failed []error
+
+	for _, mapping := range agg.Mappings() {
+		if mapping.Error != "" {
+			failed = append(failed, fmt.Errorf("upstream %s: %s", mapping.Upstream, mapping.Error))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("not writing the file outputs without every upstream: %w", errors.Join(failed...))
+	}
+
+	if cfg.Server.FailOnEmpty {
+		if err := checkNotEmpty(agg); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+
+	for _, fileOutput := range fileOutputs {
+		fileWriter := output.NewFileWriter(fileOutput, logger)
+
+		if err := fileWriter.WriteOnce(dynConfig, groups); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", fileWriter.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
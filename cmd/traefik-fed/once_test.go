@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunOnceWritesFileOutputs(t *testing.T) {
+	var polls atomic.Int32
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/http/routers" {
+			http.NotFound(w, r)
+			return
+		}
+
+		polls.Add(1)
+		_, _ = w.Write([]byte(`[{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)"}]`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "federation.yml")
+	second := filepath.Join(dir, "federation.json")
+
+	cfg := dryRunConfig(upstream.URL)
+	cfg.Output.Files = []config.FileOutput{{Path: first}, {Path: second, Format: "json"}}
+
+	// runOnce returns after a single poll instead of serving
+	require.NoError(t, runOnce(t.Context(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	assert.Equal(t, int32(1), polls.Load())
+
+	for _, path := range []string{first, second} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "host1-webapp", path)
+	}
+}
+
+func TestRunOnceFailOnEmpty(t *testing.T) {
+	upstream := mockUpstream(t, `[]`)
+	path := filepath.Join(t.TempDir(), "federation.yml")
+
+	cfg := dryRunConfig(upstream.URL)
+	cfg.Output.Files = []config.FileOutput{{Path: path}}
+	cfg.Server.FailOnEmpty = true
+
+	require.Error(t, runOnce(t.Context(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	assert.NoFileExists(t, path)
+}
+
+func TestRunOnceUpstreamFailure(t *testing.T) {
+	upstream := mockUpstream(t, `[{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(`+"`app.example.com`"+`)"}]`)
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(unreachable.Close)
+
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	require.NoError(t, os.WriteFile(path, []byte("previous"), 0o600))
+
+	cfg := dryRunConfig(upstream.URL)
+	cfg.Upstreams = append(cfg.Upstreams, config.Upstream{Name: "host2", AdminURL: unreachable.URL, ServerURL: "http://192.168.1.11:80"})
+	cfg.Output.Files = []config.FileOutput{{Path: path}}
+
+	// The file keeps the routers of the failing upstream from the last run
+	err := runOnce(t.Context(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream host2: ")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "previous", string(data))
+}
+
+func TestRunOnceRequiresFileOutput(t *testing.T) {
+	cfg := dryRunConfig("http://127.0.0.1:1")
+	cfg.Output.HTTP.Enabled = true
+
+	err := runOnce(t.Context(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.EqualError(t, err, "once mode requires a file output")
+}
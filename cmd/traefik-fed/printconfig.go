@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secret values in the printed configuration
+const redacted = "REDACTED"
+
+// runPrintConfig loads and validates the configuration file and writes the
+// effective configuration, with env vars expanded and defaults applied, to w
+// as YAML. Secrets are redacted and no upstream is contacted.
+func runPrintConfig(path string, opts config.LoadOptions, w io.Writer) error {
+	cfg, err := config.LoadWithOptions(path, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	effectiveConfig(cfg)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to close encoder: %w", err)
+	}
+
+	return nil
+}
+
+// effectiveConfig resolves unset optional settings to their effective values
+// and redacts secrets in place
+func effectiveConfig(cfg *config.Config) {
+	preservePriority := cfg.Routers.ShouldPreservePriority()
+	namespaceServices := cfg.Routers.ShouldNamespaceServices()
+	preserveEntryPoints := cfg.Routers.ShouldPreserveEntryPoints()
+
+	cfg.Routers.PreservePriority = &preservePriority
+	cfg.Routers.NamespaceServices = &namespaceServices
+	cfg.Routers.PreserveEntryPoints = &preserveEntryPoints
+
+	for i := range cfg.Upstreams {
+		upstream := &cfg.Upstreams[i]
+
+		if upstream.PollInterval == 0 {
+			upstream.PollInterval = cfg.Server.PollInterval
+		}
+
+		if upstream.BasicAuth != nil && upstream.BasicAuth.Password != "" {
+			auth := *upstream.BasicAuth
+			auth.Password = redacted
+			upstream.BasicAuth = &auth
+		}
+
+		if upstream.BearerToken != "" {
+			upstream.BearerToken = redacted
+		}
+	}
+
+	if cfg.Output.HTTP.ReloadToken != "" {
+		cfg.Output.HTTP.ReloadToken = redacted
+	}
+
+	if cfg.Output.Redis.Password != "" {
+		cfg.Output.Redis.Password = redacted
+	}
+
+	// Webhook headers commonly carry credentials
+	for name := range cfg.Output.Webhook.Headers {
+		cfg.Output.Webhook.Headers[name] = redacted
+	}
+
+	// So do the headers sent to the OTLP collector
+	for name := range cfg.Server.Tracing.Headers {
+		cfg.Server.Tracing.Headers[name] = redacted
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPrintConfig(t *testing.T) {
+	t.Setenv("HOST1_TOKEN", "s3cret")
+	t.Setenv("HOST1_ADDR", "192.168.1.10")
+
+	path := writeConfigFile(t, `upstreams:
+  - name: host1
+    admin_url: http://${HOST1_ADDR}:8080
+    server_url: http://${HOST1_ADDR}:80
+    bearer_token: ${HOST1_TOKEN}
+output:
+  http:
+    enabled: true
+    port: 8080
+  redis:
+    enabled: true
+    address: redis:6379
+    password: ${HOST1_TOKEN}
+server:
+  tracing:
+    enabled: true
+    endpoint: otel-collector:4318
+    headers:
+      Authorization: Bearer ${HOST1_TOKEN}
+`)
+
+	var out bytes.Buffer
+	require.NoError(t, runPrintConfig(path, config.LoadOptions{}, &out))
+
+	printed := out.String()
+	assert.Contains(t, printed, "poll_interval: 10s")
+	assert.Contains(t, printed, "path: /config")
+	assert.Contains(t, printed, "preserve_priority: true")
+	assert.Contains(t, printed, "admin_url: http://192.168.1.10:8080")
+	assert.Contains(t, printed, "bearer_token: REDACTED")
+	assert.Contains(t, printed, "password: REDACTED")
+	assert.Contains(t, printed, "Authorization: REDACTED")
+	assert.NotContains(t, printed, "s3cret")
+}
+
+func TestRunPrintConfigInvalid(t *testing.T) {
+	path := writeConfigFile(t, `upstreams: []
+`)
+
+	var out bytes.Buffer
+	err := runPrintConfig(path, config.LoadOptions{}, &out)
+	require.Error(t, err)
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/chickenzord/traefik-fed/internal/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// fakeSink records every configuration it receives
+type fakeSink struct {
+	name    string
+	err     error
+	updates []*dynamic.Configuration
+}
+
+func (s *fakeSink) Name() string {
+	return s.name
+}
+
+func (s *fakeSink) Update(_ context.Context, dynConfig *dynamic.Configuration) error {
+	s.updates = append(s.updates, dynConfig)
+	return s.err
+}
+
+func TestPublishUpdatesAllSinks(t *testing.T) {
+	upstream := mockUpstream(t, `[{"name":"webapp@docker","provider":"docker","status":"enabled","rule":"Host(`+"`app.example.com`"+`)"}]`)
+
+	agg := aggregator.New(dryRunConfig(upstream.URL), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	agg.Refresh(context.Background())
+
+	// A failing sink must not prevent the others from being updated
+	failing := &fakeSink{name: "failing", err: errors.New("unavailable")}
+	recording := &fakeSink{name: "recording"}
+
+	publish(context.Background(), agg, nil, nil, []output.Sink{failing, recording}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	require.Len(t, failing.updates, 1)
+	require.Len(t, recording.updates, 1)
+	assert.Contains(t, recording.updates[0].HTTP.Routers, "host1-webapp")
+	assert.Same(t, failing.updates[0], recording.updates[0])
+}
+
+func TestDelayUpdatesBatchesChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	updates := make(chan struct{})
+	delayed := delayUpdates(ctx, 50*time.Millisecond, updates)
+
+	// Rapid changes within the delay window
+	for range 5 {
+		updates <- struct{}{}
+	}
+
+	select {
+	case <-delayed:
+	case <-time.After(time.Second):
+		t.Fatal("no update applied")
+	}
+
+	select {
+	case <-delayed:
+		t.Fatal("rapid changes applied more than once")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDelayUpdatesWithoutDelay(t *testing.T) {
+	updates := make(chan struct{})
+
+	assert.Equal(t, (<-chan struct{})(updates), delayUpdates(context.Background(), 0, updates))
+}
+
+func TestDelayUpdatesReplaced(t *testing.T) {
+	updates := make(chan struct{}, 1)
+
+	// A reload with another apply_delay stops the old delay
+	oldCtx, stopOld := context.WithCancel(context.Background())
+	_ = delayUpdates(oldCtx, time.Hour, updates)
+	stopOld()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	delayed := delayUpdates(ctx, 10*time.Millisecond, updates)
+	deadline := time.After(time.Second)
+
+	// The old delay may still take an update while it stops, so keep polling
+	for {
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+
+		select {
+		case <-delayed:
+			return
+		case <-deadline:
+			t.Fatal("update not applied with the new delay")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestAwaitFlushWritesPublishedConfig(t *testing.T) {
+	upstream := mockUpstream(t, `[{"name":"webapp@docker","provider":"docker","status":"enabled","rule":"Host(`+"`app.example.com`"+`)"}]`)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	agg := aggregator.New(dryRunConfig(upstream.URL), logger)
+	agg.Refresh(context.Background())
+
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	fileWriter := output.NewFileWriter(config.FileOutput{Path: path, Interval: time.Hour, Debounce: time.Hour}, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var writers sync.WaitGroup
+
+	writers.Go(func() {
+		_ = fileWriter.Run(ctx)
+	})
+
+	// Published right before shutdown, well within the debounce
+	publish(ctx, agg, nil, nil, []output.Sink{fileWriter}, logger)
+	awaitFlush(cancel, &writers, time.Second, logger)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "host1-webapp")
+}
+
+func TestAwaitFlushTimesOut(t *testing.T) {
+	var writers sync.WaitGroup
+
+	writers.Add(1)
+	t.Cleanup(writers.Done)
+
+	start := time.Now()
+	awaitFlush(func() {}, &writers, 50*time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestReloadEndpointTriggersAggregation(t *testing.T) {
+	var polls atomic.Int32
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/http/routers" {
+			polls.Add(1)
+		}
+
+		_, _ = w.Write([]byte(`[
+			{"name":"webapp@docker","provider":"docker","status":"enabled","rule":"Host(` + "`app.example.com`" + `)"},
+			{"name":"blog@docker","provider":"docker","status":"enabled","rule":"Host(` + "`blog.example.com`" + `)"}
+		]`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	agg := aggregator.New(dryRunConfig(upstream.URL), logger)
+	httpServer := output.NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config", ReloadToken: "secret"}, logger)
+
+	// Stand in for the main loop
+	go func() {
+		reply := <-httpServer.Reloads()
+		reply <- reload(context.Background(), agg, httpServer, nil, []output.Sink{httpServer}, logger)
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/reload", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	rec := httptest.NewRecorder()
+	httpServer.Handler().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"routers": 2}`, rec.Body.String())
+	assert.Equal(t, int32(1), polls.Load())
+
+	// The served configuration was updated too
+	rec = httptest.NewRecorder()
+	httpServer.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	assert.Contains(t, rec.Body.String(), "host1-blog")
+}
+
+func TestStartPollingDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+
+		_, _ = w.Write([]byte(`[{"name":"webapp@docker","provider":"docker","status":"enabled","rule":"Host(` + "`app.example.com`" + `)"}]`))
+	}))
+	t.Cleanup(upstream.Close)
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	agg := aggregator.New(dryRunConfig(upstream.URL), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	updates := make(chan struct{}, 1)
+
+	// Returns while the upstream is still answering the initial poll
+	stop, initialized := startPolling(t.Context(), agg, updates)
+	t.Cleanup(stop)
+
+	select {
+	case <-initialized:
+		t.Fatal("initial aggregation finished before the upstream answered")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case <-initialized:
+	case <-time.After(5 * time.Second):
+		t.Fatal("initial aggregation did not finish")
+	}
+
+	select {
+	case <-updates:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update signalled after the initial aggregation")
+	}
+
+	assert.Contains(t, agg.Snapshot().HTTP.Routers, "host1-webapp")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+)
+
+// runStartupCheck checks every upstream once and logs its reachability.
+// It fails when the number of failing upstreams reaches the configured threshold.
+func runStartupCheck(ctx context.Context, agg *aggregator.Aggregator, check config.StartupCheck, logger *slog.Logger) error {
+	failed := 0
+
+	for _, result := range agg.CheckUpstreams(ctx) {
+		if result.Err != nil {
+			failed++
+
+			logger.Warn("upstream unreachable at startup",
+				"upstream", result.Upstream,
+				"error", result.Err)
+
+			continue
+		}
+
+		logger.Info("upstream reachable",
+			"upstream", result.Upstream,
+			"routers", result.Routers)
+	}
+
+	if check.FailThreshold > 0 && failed >= check.FailThreshold {
+		return fmt.Errorf("%d upstreams failed the startup check (threshold %d)", failed, check.FailThreshold)
+	}
+
+	return nil
+}
+
+// checkNotEmpty fails when the aggregated configuration has no routers.
+// It is only used on the initial aggregation: later polls coming up empty
+// keep serving, since upstreams may be restarting.
+func checkNotEmpty(agg *aggregator.Aggregator) error {
+	dynConfig := agg.Snapshot()
+
+	routers := len(dynConfig.HTTP.Routers)
+	if dynConfig.TCP != nil {
+		routers += len(dynConfig.TCP.Routers)
+	}
+
+	if dynConfig.UDP != nil {
+		routers += len(dynConfig.UDP.Routers)
+	}
+
+	if routers == 0 {
+		return fmt.Errorf("initial aggregation produced no routers from %d upstreams", len(agg.Stats().Upstreams))
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStartupCheck(t *testing.T) {
+	reachable := mockUpstream(t, `[{"name":"webapp@docker","provider":"docker","status":"enabled"}]`)
+
+	notJSON := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html>login</html>"))
+	}))
+	t.Cleanup(notJSON.Close)
+
+	upstreams := map[string]config.Upstream{
+		"host1": {Name: "host1", AdminURL: reachable.URL, ServerURL: "http://192.168.1.10:80"},
+		"host2": {Name: "host2", AdminURL: notJSON.URL, ServerURL: "http://192.168.1.11:80"},
+		"host3": {Name: "host3", AdminURL: "http://127.0.0.1:1", ServerURL: "http://192.168.1.12:80"},
+	}
+
+	tests := []struct {
+		name      string
+		upstreams []string
+		threshold int
+		wantErr   bool
+	}{
+		{name: "all reachable", upstreams: []string{"host1"}, threshold: 1},
+		{name: "some unreachable below threshold", upstreams: []string{"host1", "host2", "host3"}, threshold: 3},
+		{name: "some unreachable at threshold", upstreams: []string{"host1", "host2", "host3"}, threshold: 2, wantErr: true},
+		{name: "some unreachable warn only", upstreams: []string{"host1", "host3"}, threshold: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := dryRunConfig("")
+			cfg.Upstreams = nil
+
+			for _, name := range tt.upstreams {
+				cfg.Upstreams = append(cfg.Upstreams, upstreams[name])
+			}
+
+			var logs bytes.Buffer
+
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			check := config.StartupCheck{Enabled: true, FailThreshold: tt.threshold}
+
+			err := runStartupCheck(context.Background(), aggregator.New(cfg, logger), check, logger)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Contains(t, logs.String(), `msg="upstream reachable" upstream=host1 routers=1`)
+
+			if len(tt.upstreams) > 1 {
+				assert.Contains(t, logs.String(), "upstream unreachable at startup")
+			} else {
+				assert.NotContains(t, logs.String(), "upstream unreachable at startup")
+			}
+		})
+	}
+}
+
+func TestCheckNotEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		routers string
+		wantErr bool
+	}{
+		{name: "routers aggregated", routers: `[{"name":"webapp@docker","provider":"docker","status":"enabled"}]`},
+		{name: "no routers", routers: `[]`, wantErr: true},
+		{name: "only filtered routers", routers: `[{"name":"api@internal","provider":"internal","status":"enabled"}]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := mockUpstream(t, tt.routers)
+
+			agg := aggregator.New(dryRunConfig(upstream.URL), slog.New(slog.NewTextHandler(io.Discard, nil)))
+			agg.Refresh(context.Background())
+
+			err := checkNotEmpty(agg)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "initial aggregation produced no routers from 1 upstreams")
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckNotEmptyUnreachableUpstream(t *testing.T) {
+	agg := aggregator.New(dryRunConfig("http://127.0.0.1:1"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	agg.Refresh(context.Background())
+
+	require.Error(t, checkNotEmpty(agg))
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+)
+
+// runValidate loads and validates the configuration file without contacting
+// any upstream, writing a short summary to w when it is valid
+func runValidate(path string, opts config.LoadOptions, w io.Writer) error {
+	cfg, err := config.LoadWithOptions(path, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	for _, warning := range cfg.Warnings() {
+		if _, err := fmt.Fprintf(w, "%s: warning: %s\n", path, warning); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "%s: configuration is valid (%d upstreams)\n", path, len(cfg.Upstreams))
+
+	return err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestRunValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			content: `upstreams:
+  - name: host1
+    admin_url: http://192.168.1.10:8080
+    server_url: http://192.168.1.10:80
+output:
+  http:
+    enabled: true
+    port: 8080
+`,
+		},
+		{
+			name: "missing server url",
+			content: `upstreams:
+  - name: host1
+    admin_url: http://192.168.1.10:8080
+`,
+			wantErr: "server_url is required",
+		},
+		{
+			name: "invalid url",
+			content: `upstreams:
+  - name: host1
+    admin_url: 192.168.1.10:8080
+    server_url: http://192.168.1.10:80
+`,
+			wantErr: "admin_url",
+		},
+		{
+			name: "invalid regex",
+			content: `upstreams:
+  - name: host1
+    admin_url: http://192.168.1.10:8080
+    server_url: http://192.168.1.10:80
+routers:
+  selector:
+    rule_regex: "("
+`,
+			wantErr: "rule_regex",
+		},
+		{
+			name:    "malformed yaml",
+			content: "upstreams: [",
+			wantErr: "failed to parse config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.content)
+
+			var out bytes.Buffer
+
+			err := runValidate(path, config.LoadOptions{}, &out)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Empty(t, out.String())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Contains(t, out.String(), "configuration is valid")
+		})
+	}
+}
+
+func TestRunValidateMissingFile(t *testing.T) {
+	err := runValidate(filepath.Join(t.TempDir(), "missing.yaml"), config.LoadOptions{}, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read config file")
+}
+
+func TestRunValidatePrintsMigrationWarnings(t *testing.T) {
+	path := writeConfigFile(t, `upstreams:
+  - name: host1
+    admin_url: http://192.168.1.10:8080
+    server_url: http://192.168.1.10:80
+output:
+  http:
+    enabled: true
+    port: 8080
+`)
+
+	var out bytes.Buffer
+
+	require.NoError(t, runValidate(path, config.LoadOptions{}, &out))
+	assert.Contains(t, out.String(), "warning: configuration has no version field")
+	assert.Contains(t, out.String(), "configuration is valid")
+}
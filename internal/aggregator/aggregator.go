@@ -1,122 +1,718 @@
 package aggregator
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/chickenzord/traefik-fed/internal/config"
 	"github.com/chickenzord/traefik-fed/internal/traefik"
+	ptypes "github.com/traefik/paerser/types"
 	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	otypes "github.com/traefik/traefik/v3/pkg/observability/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName is the instrumentation scope of the aggregator spans
+const tracerName = "github.com/chickenzord/traefik-fed/internal/aggregator"
+
 // Aggregator aggregates configurations from multiple Traefik upstreams
 type Aggregator struct {
-	config  *config.Config
-	clients map[string]*traefik.Client
-	logger  *slog.Logger
+	config   *config.Config
+	clients  map[string]*traefik.Client
+	breakers map[string]*circuitBreaker // nil when the circuit breaker is disabled
+	probes   map[string]*healthProbe    // Upstreams with a server_url health check
+	dns      map[string]*dnsCheck       // Upstreams with validate_server_dns
+	logger   *slog.Logger
+	tracer   trace.Tracer
+	polls    atomic.Uint64 // Counter for poll IDs
+
+	lookupHost     func(ctx context.Context, host string) ([]string, error) // Resolves server_url hosts
+	ruleTransforms []ruleTransform                                          // routers.rule_transforms, in order
+
+	mu       sync.RWMutex
+	states   map[string]*upstreamState
+	versions map[string]string // Detected Traefik version by upstream name
+	mappings []UpstreamMapping
+	groups   []UpstreamConfiguration
+	stats    Stats
+
+	pathWarned     map[string]struct{}            // Source routers already warned about path middlewares
+	internalWarned map[string]map[string]struct{} // Source routers and services already warned about internal services, by upstream
+}
+
+// upstreamState holds the result of the last poll of a single upstream
+type upstreamState struct {
+	config   *dynamic.Configuration // nil when the last poll failed
+	mapping  UpstreamMapping
+	polledAt time.Time
+	duration time.Duration
+
+	lastSuccess time.Time // Completion time of the last successful poll, kept across failures
 }
 
 // New creates a new aggregator
 func New(cfg *config.Config, logger *slog.Logger) *Aggregator {
 	clients := make(map[string]*traefik.Client)
+	probes := make(map[string]*healthProbe)
+	dns := make(map[string]*dnsCheck)
+
+	var breakers map[string]*circuitBreaker
+	if b := cfg.Server.CircuitBreaker; b.Enabled {
+		breakers = make(map[string]*circuitBreaker)
+
+		for _, upstream := range cfg.Upstreams {
+			breakers[upstream.Name] = newCircuitBreaker(b.FailThreshold, b.CoolDown, b.MaxCoolDown)
+		}
+	}
 
 	for _, upstream := range cfg.Upstreams {
-		// Append /api to admin URL to get the API endpoint
-		apiURL := strings.TrimSuffix(upstream.AdminURL, "/") + "/api"
-		clients[upstream.Name] = traefik.NewClient(apiURL)
+		client := newClient(upstream, logger)
+		client.SetUserAgent(cfg.Server.UserAgent)
+		client.SetTransportOptions(traefik.TransportOptions{
+			MaxIdleConns:        cfg.Server.HTTPTransport.MaxIdleConns,
+			MaxIdleConnsPerHost: cfg.Server.HTTPTransport.MaxIdleConnsPerHost,
+			IdleConnTimeout:     cfg.Server.HTTPTransport.IdleConnTimeout,
+			DisableKeepAlives:   cfg.Server.HTTPTransport.DisableKeepAlives,
+		})
+
+		if cfg.Routers.SkipMalformed {
+			client.SkipMalformed(logger.With("upstream", upstream.Name))
+		}
+
+		// Served by the debug endpoints of the HTTP output
+		if cfg.Output.HTTP.Debug {
+			client.KeepRawResponses()
+		}
+
+		clients[upstream.Name] = client
+
+		if upstream.ValidateServerDNS {
+			dns[upstream.Name] = &dnsCheck{}
+		}
+
+		if upstream.HealthCheck != nil {
+			probe, err := newHealthProbe(upstream)
+			if err != nil {
+				logger.Error("failed to configure upstream health check", "upstream", upstream.Name, "error", err)
+				continue
+			}
+
+			probes[upstream.Name] = probe
+		}
 	}
 
 	return &Aggregator{
-		config:  cfg,
-		clients: clients,
-		logger:  logger,
+		config:   cfg,
+		clients:  clients,
+		breakers: breakers,
+		probes:   probes,
+		dns:      dns,
+		logger:   logger,
+		tracer:   otel.Tracer(tracerName),
+		states:   make(map[string]*upstreamState),
+		versions: make(map[string]string),
+
+		lookupHost:     net.DefaultResolver.LookupHost,
+		ruleTransforms: newRuleTransforms(cfg.Routers.RuleTransforms),
+		pathWarned:     make(map[string]struct{}),
+		internalWarned: make(map[string]map[string]struct{}),
 	}
 }
 
 // Aggregate fetches and aggregates configurations from all upstreams
-func (a *Aggregator) Aggregate() (*dynamic.HTTPConfiguration, error) {
-	httpConfig := &dynamic.HTTPConfiguration{
-		Routers:  make(map[string]*dynamic.Router),
-		Services: make(map[string]*dynamic.Service),
+func (a *Aggregator) Aggregate() (*dynamic.Configuration, error) {
+	return a.AggregateContext(context.Background())
+}
+
+// AggregateContext polls all upstreams once and returns the merged configuration.
+// Upstream requests are aborted when ctx is cancelled.
+func (a *Aggregator) AggregateContext(ctx context.Context) (*dynamic.Configuration, error) {
+	a.Refresh(ctx)
+
+	return a.Snapshot(), nil
+}
+
+// Refresh probes the servers of upstreams with a health check and polls all
+// upstreams once, storing their latest configurations
+func (a *Aggregator) Refresh(ctx context.Context) {
+	ctx, span := a.tracer.Start(ctx, "aggregator.refresh",
+		trace.WithAttributes(attribute.Int("upstreams", len(a.config.Upstreams))))
+	defer span.End()
+
+	a.probeUpstreams(ctx)
+
+	for _, upstream := range a.config.Upstreams {
+		a.refresh(ctx, upstream)
 	}
+}
+
+// Run polls every upstream on its own interval until ctx is cancelled,
+// calling notify after each poll. The first poll of each upstream happens
+// after one interval, so callers should run Refresh first.
+// Each interval is randomized by the configured poll jitter.
+// Upstream health checks run on their own interval, calling notify when the
+// health of an upstream changes.
+func (a *Aggregator) Run(ctx context.Context, notify func()) {
+	var wg sync.WaitGroup
 
 	for _, upstream := range a.config.Upstreams {
-		if err := a.aggregateUpstream(upstream, httpConfig); err != nil {
-			a.logger.Error("failed to aggregate upstream",
+		if probe := a.probes[upstream.Name]; probe != nil {
+			wg.Go(func() {
+				a.runHealthCheck(ctx, upstream, probe, notify)
+			})
+		}
+
+		wg.Go(func() {
+			interval := a.pollInterval(upstream)
+			jitter := a.config.Server.Jitter(interval)
+
+			timer := time.NewTimer(jitteredInterval(interval, jitter))
+			defer timer.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-timer.C:
+					a.refresh(ctx, upstream)
+					notify()
+					timer.Reset(jitteredInterval(interval, jitter))
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+}
+
+// jitteredInterval returns interval randomized by up to ±jitter.
+// The jitter is capped at half the interval so polls never run back to back.
+func jitteredInterval(interval, jitter time.Duration) time.Duration {
+	jitter = min(jitter, interval/2)
+	if jitter <= 0 {
+		return interval
+	}
+
+	return interval - jitter + rand.N(2*jitter+1)
+}
+
+// UpstreamCheck is the result of checking a single upstream
+type UpstreamCheck struct {
+	Upstream string
+	Routers  int
+	Err      error
+}
+
+// CheckUpstreams fetches the routers of every upstream once, without storing
+// anything, to verify each upstream is reachable and returns a valid response
+func (a *Aggregator) CheckUpstreams(ctx context.Context) []UpstreamCheck {
+	checks := make([]UpstreamCheck, len(a.config.Upstreams))
+
+	var wg sync.WaitGroup
+
+	for i, upstream := range a.config.Upstreams {
+		wg.Go(func() {
+			pollCtx := ctx
+
+			if interval := a.pollInterval(upstream); interval > 0 {
+				var cancel context.CancelFunc
+
+				pollCtx, cancel = context.WithTimeout(ctx, interval)
+				defer cancel()
+			}
+
+			routers, err := a.clients[upstream.Name].GetRoutersContext(pollCtx)
+			checks[i] = UpstreamCheck{Upstream: upstream.Name, Routers: len(routers), Err: err}
+		})
+	}
+
+	wg.Wait()
+
+	return checks
+}
+
+// newClient creates the API client of an upstream, reading routers from its
+// fixture file when one is configured
+func newClient(upstream config.Upstream, logger *slog.Logger) *traefik.Client {
+	if upstream.FixtureFile != "" {
+		logger.Info("reading upstream routers from fixture", "upstream", upstream.Name, "fixture_file", upstream.FixtureFile)
+		return traefik.NewFixtureClient(upstream.FixtureFile)
+	}
+
+	apiURLs := upstream.APIURLs()
+	if len(apiURLs) == 0 {
+		// Not validated; polls fail with a request error
+		apiURLs = []string{upstream.APIURL()}
+	}
+
+	apiURL := apiURLs[0]
+
+	if upstream.InsecureSkipVerify {
+		logger.Warn("TLS certificate verification disabled for upstream admin API",
+			"upstream", upstream.Name,
+			"admin_url", upstream.AdminURL)
+	}
+
+	client, err := traefik.NewClientWithTLS(apiURL, traefik.TLSOptions{
+		InsecureSkipVerify: upstream.InsecureSkipVerify,
+		CAFile:             upstream.CAFile,
+	})
+	if err != nil {
+		// Fall back to the default client; polls fail until the CA is fixed
+		logger.Error("failed to configure upstream TLS", "upstream", upstream.Name, "error", err)
+
+		client = traefik.NewClient(apiURL)
+	}
+
+	client.AddFailoverURLs(apiURLs[1:]...)
+	client.SetMinRequestInterval(upstream.MinRequestInterval)
+	client.SetMaxResponseBytes(upstream.MaxResponseBytes)
+
+	if upstream.BasicAuth != nil {
+		client.SetBasicAuth(upstream.BasicAuth.Username, upstream.BasicAuth.Password)
+	}
+
+	client.SetBearerToken(upstream.BearerToken)
+
+	return client
+}
+
+// pollInterval returns the poll interval of the upstream, falling back to the global one
+func (a *Aggregator) pollInterval(upstream config.Upstream) time.Duration {
+	if upstream.PollInterval > 0 {
+		return upstream.PollInterval
+	}
+
+	return a.config.Server.PollInterval
+}
+
+// refresh polls a single upstream and stores its partial configuration.
+// Requests are bounded by the upstream poll interval so a slow upstream
+// cannot delay its next poll.
+func (a *Aggregator) refresh(ctx context.Context, upstream config.Upstream) {
+	// Correlate all log lines and spans of this poll
+	pollID := a.polls.Add(1)
+	logger := a.logger.With("poll_id", pollID)
+
+	breaker := a.breakers[upstream.Name]
+	if breaker != nil && !breaker.allow() {
+		logger.Debug("skipping upstream poll, circuit open", "upstream", upstream.Name)
+		return
+	}
+
+	ctx, span := a.tracer.Start(ctx, "aggregator.poll", trace.WithAttributes(
+		attribute.String("upstream", upstream.Name),
+		attribute.Int64("poll_id", int64(pollID)),
+	))
+	defer span.End()
+
+	start := time.Now()
+	pollCtx := ctx
+
+	if interval := a.pollInterval(upstream); interval > 0 {
+		var cancel context.CancelFunc
+
+		pollCtx, cancel = context.WithTimeout(ctx, interval)
+		defer cancel()
+	}
+
+	pollCtx = traefik.WithTraceLogger(pollCtx, logger.With("upstream", upstream.Name))
+
+	// Fixtures only capture HTTP routers
+	if upstream.FixtureFile == "" {
+		a.detectVersion(pollCtx, logger, upstream)
+	}
+
+	partial := newConfiguration(a.config.Routers.TCP.Enabled, a.config.Routers.ShouldIncludeUDP())
+	state := &upstreamState{
+		config:  partial,
+		mapping: UpstreamMapping{Upstream: upstream.Name},
+	}
+
+	if err := a.aggregateUpstream(pollCtx, logger, upstream, partial.HTTP, &state.mapping); err != nil {
+		// Keep the previous state when polling was stopped by the caller
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Error("failed to aggregate upstream",
+			"upstream", upstream.Name,
+			"error", err)
+
+		state.config = nil
+		state.mapping.Error = err.Error()
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to aggregate upstream")
+
+		if breaker != nil && breaker.failure() {
+			stats := breaker.stats()
+			logger.Warn("circuit opened for failing upstream, skipping polls until cool-down ends",
 				"upstream", upstream.Name,
-				"error", err)
-			// Continue with other upstreams even if one fails
+				"failures", stats.Failures,
+				"retry_at", stats.RetryAt)
+		}
+	} else if upstream.FixtureFile == "" {
+		if partial.TCP != nil {
+			if err := a.aggregateUpstreamTCP(pollCtx, logger, upstream, partial.TCP); err != nil {
+				logger.Error("failed to aggregate TCP routers from upstream",
+					"upstream", upstream.Name,
+					"error", err)
+			}
+		}
+
+		if partial.UDP != nil {
+			if err := a.aggregateUpstreamUDP(pollCtx, logger, upstream, partial.UDP); err != nil {
+				logger.Error("failed to aggregate UDP routers from upstream",
+					"upstream", upstream.Name,
+					"error", err)
+			}
+		}
+	}
+
+	if check := a.dns[upstream.Name]; check != nil && state.config != nil {
+		a.checkServerDNS(pollCtx, logger, upstream, check)
+	}
+
+	if breaker != nil && state.config != nil {
+		breaker.success()
+	}
+
+	state.polledAt = time.Now()
+	state.duration = state.polledAt.Sub(start)
+
+	logger.Debug("polled upstream",
+		"upstream", upstream.Name,
+		"duration", state.duration)
+
+	a.mu.Lock()
+	if state.config != nil {
+		state.lastSuccess = state.polledAt
+	} else if previous, ok := a.states[upstream.Name]; ok {
+		state.lastSuccess = previous.lastSuccess
+	}
+
+	a.states[upstream.Name] = state
+	a.mu.Unlock()
+}
+
+// detectVersion fetches and logs the Traefik version of an upstream once,
+// warning when its major version differs from the supported one.
+// Failures are retried on the next poll.
+func (a *Aggregator) detectVersion(ctx context.Context, logger *slog.Logger, upstream config.Upstream) {
+	a.mu.RLock()
+	_, known := a.versions[upstream.Name]
+	a.mu.RUnlock()
+
+	if known {
+		return
+	}
+
+	version, err := a.clients[upstream.Name].GetVersionContext(ctx)
+	if err != nil {
+		logger.Debug("failed to detect upstream version",
+			"upstream", upstream.Name,
+			"error", err)
+
+		return
+	}
+
+	logger.Info("detected upstream version",
+		"upstream", upstream.Name,
+		"version", version.Version,
+		"codename", version.Codename)
+
+	if major, ok := version.MajorVersion(); ok && major != traefik.SupportedMajorVersion {
+		logger.Warn("upstream Traefik major version differs from the supported one; rules and API responses may not be compatible",
+			"upstream", upstream.Name,
+			"version", version.Version,
+			"supported_major", traefik.SupportedMajorVersion)
+	}
+
+	a.mu.Lock()
+	a.versions[upstream.Name] = version.Version
+	a.mu.Unlock()
+}
+
+// Snapshot merges the latest configuration of every upstream, in config order.
+// An upstream whose routers or services collide with a previous upstream is
+// left out entirely so that router to service references stay consistent.
+func (a *Aggregator) Snapshot() *dynamic.Configuration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	result := newConfiguration(a.config.Routers.TCP.Enabled, a.config.Routers.ShouldIncludeUDP())
+	mappings := make([]UpstreamMapping, 0, len(a.config.Upstreams))
+	stats := Stats{Upstreams: make([]UpstreamStats, 0, len(a.config.Upstreams))}
+	upstreamConfigs := make(map[string]*dynamic.Configuration)
+
+	for _, upstream := range a.config.Upstreams {
+		state, ok := a.states[upstream.Name]
+		if !ok {
 			continue
 		}
+
+		mapping := state.mapping
+
+		if probe := a.probes[upstream.Name]; probe != nil && mapping.Error == "" {
+			if healthy, err := probe.healthy(); !healthy {
+				mapping.Error = fmt.Sprintf("server_url health check failing: %v", err)
+			}
+		}
+
+		if check := a.dns[upstream.Name]; check != nil && mapping.Error == "" {
+			if resolves, err := check.resolves(); !resolves {
+				mapping.Error = fmt.Sprintf("server_url host does not resolve: %v", err)
+			}
+		}
+
+		if state.config != nil && mapping.Error == "" {
+			if err := mergeConfiguration(result, state.config); err != nil {
+				a.logger.Error("failed to merge upstream",
+					"upstream", upstream.Name,
+					"error", err)
+
+				mapping.Error = err.Error()
+			} else {
+				upstreamConfigs[upstream.Name] = state.config
+			}
+		}
+
+		mappings = append(mappings, mapping)
+		stats.Upstreams = append(stats.Upstreams, upstreamStats(upstream.Name, state, mapping, a.versions[upstream.Name]))
+
+		if breaker := a.breakers[upstream.Name]; breaker != nil {
+			stats.Upstreams[len(stats.Upstreams)-1].Circuit = breaker.stats()
+		}
+
+		if state.polledAt.After(stats.LastPoll) {
+			stats.LastPoll = state.polledAt
+			stats.DurationMs = durationMs(state.duration)
+		}
+	}
+
+	if a.config.Routers.MergeIdentical {
+		a.mergeIdenticalRouters(result, mappings)
+	}
+
+	if pruned := pruneOrphanedServices(result); pruned > 0 {
+		a.logger.Debug("pruned services without routers", "services", pruned)
+	}
+
+	stats.Routers, stats.Services = countConfiguration(result)
+
+	a.mappings = mappings
+	a.groups = groupByUpstream(result, mappings, upstreamConfigs)
+	a.stats = stats
+
+	return result
+}
+
+// newConfiguration creates an empty configuration with HTTP and optionally TCP and UDP sections
+func newConfiguration(includeTCP, includeUDP bool) *dynamic.Configuration {
+	result := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers:  make(map[string]*dynamic.Router),
+			Services: make(map[string]*dynamic.Service),
+		},
+	}
+
+	if includeTCP {
+		result.TCP = &dynamic.TCPConfiguration{
+			Routers:  make(map[string]*dynamic.TCPRouter),
+			Services: make(map[string]*dynamic.TCPService),
+		}
 	}
 
-	return httpConfig, nil
+	if includeUDP {
+		result.UDP = &dynamic.UDPConfiguration{
+			Routers:  make(map[string]*dynamic.UDPRouter),
+			Services: make(map[string]*dynamic.UDPService),
+		}
+	}
+
+	return result
 }
 
-// aggregateUpstream aggregates configuration from a single upstream
-func (a *Aggregator) aggregateUpstream(upstream config.Upstream, httpConfig *dynamic.HTTPConfiguration) error {
+// mergeConfiguration copies src into dst, failing without changes if any
+// router or service name is already defined
+func mergeConfiguration(dst, src *dynamic.Configuration) error {
+	for name := range src.HTTP.Services {
+		if _, exists := dst.HTTP.Services[name]; exists {
+			return fmt.Errorf("service %q already defined by another upstream, enable routers.namespace_services", name)
+		}
+	}
+
+	for name := range src.HTTP.Routers {
+		if _, exists := dst.HTTP.Routers[name]; exists {
+			return fmt.Errorf("router %q already defined by another upstream", name)
+		}
+	}
+
+	if src.TCP != nil && dst.TCP != nil {
+		for name := range src.TCP.Services {
+			if _, exists := dst.TCP.Services[name]; exists {
+				return fmt.Errorf("TCP service %q already defined by another upstream, enable routers.namespace_services", name)
+			}
+		}
+
+		for name := range src.TCP.Routers {
+			if _, exists := dst.TCP.Routers[name]; exists {
+				return fmt.Errorf("TCP router %q already defined by another upstream", name)
+			}
+		}
+	}
+
+	if src.UDP != nil && dst.UDP != nil {
+		for name := range src.UDP.Services {
+			if _, exists := dst.UDP.Services[name]; exists {
+				return fmt.Errorf("UDP service %q already defined by another upstream, enable routers.namespace_services", name)
+			}
+		}
+
+		for name := range src.UDP.Routers {
+			if _, exists := dst.UDP.Routers[name]; exists {
+				return fmt.Errorf("UDP router %q already defined by another upstream", name)
+			}
+		}
+
+		maps.Copy(dst.UDP.Services, src.UDP.Services)
+		maps.Copy(dst.UDP.Routers, src.UDP.Routers)
+	}
+
+	if src.TCP != nil && dst.TCP != nil {
+		maps.Copy(dst.TCP.Services, src.TCP.Services)
+		maps.Copy(dst.TCP.Routers, src.TCP.Routers)
+	}
+
+	maps.Copy(dst.HTTP.Services, src.HTTP.Services)
+	maps.Copy(dst.HTTP.Routers, src.HTTP.Routers)
+
+	return nil
+}
+
+// aggregateUpstream aggregates configuration from a single upstream.
+// The mapping is filled with every source router and, for included ones,
+// the generated router and service names.
+func (a *Aggregator) aggregateUpstream(
+	ctx context.Context,
+	logger *slog.Logger,
+	upstream config.Upstream,
+	httpConfig *dynamic.HTTPConfiguration,
+	mapping *UpstreamMapping,
+) error {
 	client := a.clients[upstream.Name]
 
 	// Fetch routers from upstream
-	routers, err := client.GetRouters()
+	routers, err := client.GetRoutersContext(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to fetch routers: %w", err)
 	}
 
+	// Record every source router, marking included ones as they are added
+	mapping.Routers = make([]RouterMapping, len(routers))
+	mappingIndex := make(map[string]int, len(routers))
+
+	for i, router := range routers {
+		mapping.Routers[i] = RouterMapping{
+			Name:     router.Name,
+			Provider: router.Provider,
+			Status:   router.Status,
+		}
+		mappingIndex[router.Name] = i
+	}
+
 	// Apply filters
-	filteredRouters := traefik.FilterRouters(routers, a.config.Routers.Selector.Provider, a.config.Routers.Selector.Status)
+	filteredRouters, reasons := traefik.FilterRoutersWithReasons(routers, a.routerFilter())
+	filteredRouters = a.filterInternalServices(logger, upstream, filteredRouters, reasons)
+	filteredRouters = a.filterRuleSyntax(logger, upstream, filteredRouters, reasons)
+	filteredRouters = a.filterNameCollisions(logger, upstream, filteredRouters, reasons)
+	filteredRouters = a.limitRouters(logger, upstream, filteredRouters, reasons)
+
+	for name, reason := range reasons {
+		mapping.Routers[mappingIndex[name]].Reason = string(reason)
+	}
 
-	a.logger.Info("fetched routers from upstream",
+	logger.Info("fetched routers from upstream",
 		"upstream", upstream.Name,
 		"total", len(routers),
 		"filtered", len(filteredRouters))
 
+	if len(reasons) > 0 {
+		logger.Debug("routers excluded by filters",
+			"upstream", upstream.Name,
+			"excluded", excludedCounts(*mapping))
+	}
+
 	// Debug: log filtered routers
 	for _, router := range filteredRouters {
-		a.logger.Debug("router will be aggregated",
+		logger.Debug("router will be aggregated",
 			"upstream", upstream.Name,
 			"name", router.Name,
 			"provider", router.Provider,
 			"status", router.Status,
 			"rule", router.Rule,
 			"entrypoints", router.EntryPoints,
+			"using", router.Using,
 			"service", router.Service)
 	}
 
 	// Create a service for this upstream if we have any routers
 	if len(filteredRouters) > 0 {
-		serviceName := fmt.Sprintf("%s-traefik", upstream.Name)
+		targetURL, err := upstream.TargetURL()
+		if err != nil {
+			return err
+		}
+
+		serviceName := a.serviceName(upstream, "traefik")
 		httpConfig.Services[serviceName] = &dynamic.Service{
 			LoadBalancer: &dynamic.ServersLoadBalancer{
 				Servers: []dynamic.Server{
 					{
-						URL: upstream.ServerURL,
+						URL: targetURL,
 					},
 				},
+				Sticky:         a.sticky(),
+				PassHostHeader: a.passHostHeader(),
+				HealthCheck:    a.healthCheck(),
 			},
 		}
 
 		// Add routers, using router name from API
 		for _, router := range filteredRouters {
-			// Trim provider suffix from router name (e.g., "memos@docker" -> "memos")
-			baseName := router.Name
-			if idx := strings.Index(baseName, "@"); idx != -1 {
-				baseName = baseName[:idx]
-			}
+			routerName := a.routerName(upstream, router.Name)
 
-			// Prepend upstream name
-			routerName := fmt.Sprintf("%s-%s", upstream.Name, baseName)
+			a.warnPathMiddlewares(logger, upstream, router)
 
 			// Create a new router pointing to our upstream service
 			newRouter := &dynamic.Router{
-				Rule:    router.Rule,
+				Rule:    a.transformRule(router.Rule),
 				Service: serviceName,
 			}
 
-			// Apply defaults (not copied from upstream)
+			// Priority 0 means unset; Traefik then derives it from the rule
+			// length, which yields the same value as on the upstream
+			if a.config.Routers.ShouldPreservePriority() && router.Priority != 0 {
+				newRouter.Priority = router.Priority
+			}
+
+			// Apply defaults, falling back to the upstream entrypoints which
+			// must then also exist on the federated Traefik
 			if len(a.config.Routers.Defaults.EntryPoints) > 0 {
 				newRouter.EntryPoints = a.config.Routers.Defaults.EntryPoints
+			} else if a.config.Routers.ShouldPreserveEntryPoints() {
+				newRouter.EntryPoints = a.upstreamEntryPoints(router)
 			}
 
 			if len(a.config.Routers.Defaults.Middlewares) > 0 {
@@ -130,9 +726,167 @@ func (a *Aggregator) aggregateUpstream(upstream config.Upstream, httpConfig *dyn
 				newRouter.TLS = router.TLS
 			}
 
+			newRouter.Observability = a.observability(router)
+
 			httpConfig.Routers[routerName] = newRouter
+
+			routerMapping := &mapping.Routers[mappingIndex[router.Name]]
+			routerMapping.Included = true
+			routerMapping.Router = routerName
+			routerMapping.Service = serviceName
 		}
 	}
 
 	return nil
 }
+
+// serviceName returns the name of a generated service, prefixed with the
+// upstream name when service namespacing is enabled. The same name must be
+// used for the service map key and the router service reference.
+func (a *Aggregator) serviceName(upstream config.Upstream, name string) string {
+	if a.config.Routers.ShouldNamespaceServices() {
+		name = fmt.Sprintf("%s-%s", upstream.Name, name)
+	}
+
+	return a.affixService(name)
+}
+
+// affixService adds the configured service_prefix and service_suffix to a
+// generated service name
+func (a *Aggregator) affixService(name string) string {
+	return a.config.Routers.ServicePrefix + name + a.config.Routers.ServiceSuffix
+}
+
+// routerFilter returns the router filter built from the configured selector
+func (a *Aggregator) routerFilter() traefik.RouterFilter {
+	return selectorFilter(&a.config.Routers.Selector)
+}
+
+// selectorFilter returns the router filter built from a selector
+func selectorFilter(selector *config.RouterSelector) traefik.RouterFilter {
+	return traefik.RouterFilter{
+		Provider:      selector.Provider,
+		Status:        selector.Status,
+		Exclude:       selector.Exclude,
+		RuleRegex:     selector.RuleRegexp(),
+		HasMiddleware: selector.HasMiddleware,
+		EntryPoints:   selector.EntryPoints,
+
+		IncludeInternal: selector.IncludeInternal,
+	}
+}
+
+// routerName returns the generated router name: the upstream name followed by
+// the router name without its provider suffix (e.g., "memos@docker" -> "host1-memos").
+// With normalize_names, the router name part is normalized (e.g., "My App@docker" -> "host1-my-app").
+func (a *Aggregator) routerName(upstream config.Upstream, name string) string {
+	baseName := name
+	if idx := strings.Index(baseName, "@"); idx != -1 {
+		baseName = baseName[:idx]
+	}
+
+	if a.config.Routers.NormalizeNames {
+		baseName = normalizeName(baseName)
+	}
+
+	return fmt.Sprintf("%s-%s", upstream.Name, baseName)
+}
+
+// upstreamEntryPoints returns the entrypoints of the upstream router copied by
+// preserve_entrypoints: its configured entrypoints, or with entrypoint_source
+// "using" the entrypoints it is actually served on
+func (a *Aggregator) upstreamEntryPoints(router *traefik.RouterInfo) []string {
+	if a.config.Routers.EntryPointSource == config.EntryPointSourceUsing {
+		return router.Using
+	}
+
+	return router.EntryPoints
+}
+
+// observability returns the observability settings of a generated router:
+// the configured defaults if present, otherwise the upstream router's settings
+// when preserve_observability is enabled, nil leaving it to Traefik's defaults.
+// With disable_access_logs, access logs are turned off on top of those.
+func (a *Aggregator) observability(router *traefik.RouterInfo) *dynamic.RouterObservabilityConfig {
+	observability := a.baseObservability(router)
+	if !a.config.Routers.Defaults.DisableAccessLogs {
+		return observability
+	}
+
+	// Copy so the shared defaults are not modified
+	result := dynamic.RouterObservabilityConfig{}
+	if observability != nil {
+		result = *observability
+	}
+
+	disabled := false
+	result.AccessLogs = &disabled
+
+	return &result
+}
+
+// baseObservability returns the observability settings of a generated router
+// before disable_access_logs is applied
+func (a *Aggregator) baseObservability(router *traefik.RouterInfo) *dynamic.RouterObservabilityConfig {
+	if defaults := a.config.Routers.Defaults.Observability; defaults != nil {
+		return defaults
+	}
+
+	if !a.config.Routers.PreserveObservability || router.Observability == nil {
+		return nil
+	}
+
+	accessLogs, metrics, tracing := router.Observability.AccessLogs, router.Observability.Metrics, router.Observability.Tracing
+
+	return &dynamic.RouterObservabilityConfig{
+		AccessLogs:     &accessLogs,
+		Metrics:        &metrics,
+		Tracing:        &tracing,
+		TraceVerbosity: otypes.TracingVerbosity(router.Observability.TraceVerbosity),
+	}
+}
+
+// passHostHeader returns the passHostHeader setting for generated services,
+// nil leaving it to Traefik's default
+func (a *Aggregator) passHostHeader() *bool {
+	if a.config.Routers.Defaults.PassHostHeader == nil {
+		return nil
+	}
+
+	passHostHeader := *a.config.Routers.Defaults.PassHostHeader
+
+	return &passHostHeader
+}
+
+// healthCheck returns the health check of generated services, or nil when
+// routers.defaults.healthcheck is unset. Unset durations keep Traefik's defaults.
+func (a *Aggregator) healthCheck() *dynamic.ServerHealthCheck {
+	defaults := a.config.Routers.Defaults.HealthCheck
+	if defaults == nil {
+		return nil
+	}
+
+	return &dynamic.ServerHealthCheck{
+		Path:     defaults.Path,
+		Interval: ptypes.Duration(defaults.Interval),
+		Timeout:  ptypes.Duration(defaults.Timeout),
+		Hostname: defaults.Hostname,
+	}
+}
+
+// sticky returns the sticky session configuration for generated services.
+// Stickiness is harmless for single-server services and keeps sessions
+// pinned once a service is backed by several servers.
+func (a *Aggregator) sticky() *dynamic.Sticky {
+	defaults := a.config.Routers.Defaults.Sticky
+	if defaults == nil {
+		return nil
+	}
+
+	// An empty sticky section enables a cookie with Traefik's default settings
+	if defaults.Cookie == nil {
+		return &dynamic.Sticky{Cookie: &dynamic.Cookie{}}
+	}
+
+	return defaults
+}
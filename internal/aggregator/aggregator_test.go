@@ -0,0 +1,951 @@
+package aggregator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ptypes "github.com/traefik/paerser/types"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	otypes "github.com/traefik/traefik/v3/pkg/observability/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// mockUpstream starts a fake Traefik API serving the given JSON per API path
+func mockUpstream(t *testing.T, responses map[string]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// writeFixture saves a captured /http/routers response and returns its path
+func writeFixture(t *testing.T, body string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "routers.json")
+	require.NoError(t, os.WriteFile(path, []byte(body), 0600))
+
+	return path
+}
+
+func testConfig(upstreams ...config.Upstream) *config.Config {
+	return &config.Config{
+		Upstreams: upstreams,
+		Routers: config.RouterConfig{
+			Selector: config.RouterSelector{Status: "enabled"},
+			TCP:      config.TCPRouterConfig{Selector: config.RouterSelector{Status: "enabled"}},
+			UDP:      config.UDPRouterConfig{Selector: config.RouterSelector{Status: "enabled"}},
+		},
+	}
+}
+
+const priorityRouters = `[
+	{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)", "priority": 100},
+	{"name": "fallback@docker", "provider": "docker", "status": "enabled", "rule": "PathPrefix(` + "`/`" + `)"}
+]`
+
+func TestAggregatePreservesPriority(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": priorityRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	httpConfig := result.HTTP
+
+	require.Contains(t, httpConfig.Routers, "host1-webapp")
+	assert.Equal(t, 100, httpConfig.Routers["host1-webapp"].Priority)
+
+	require.Contains(t, httpConfig.Routers, "host1-fallback")
+	assert.Equal(t, 0, httpConfig.Routers["host1-fallback"].Priority)
+}
+
+func TestAggregateWithoutPreservePriority(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": priorityRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+	preserve := false
+	cfg.Routers.PreservePriority = &preserve
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	httpConfig := result.HTTP
+
+	require.Contains(t, httpConfig.Routers, "host1-webapp")
+	assert.Equal(t, 0, httpConfig.Routers["host1-webapp"].Priority)
+}
+
+const entryPointRouters = `[
+	{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)", "entryPoints": ["web", "websecure"], "using": ["web", "websecure"]},
+	{"name": "catchall@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`other.example.com`" + `)", "using": ["web", "websecure", "traefik"]}
+]`
+
+func TestAggregateEntryPoints(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": entryPointRouters})
+
+	tests := []struct {
+		name     string
+		defaults []string
+		preserve *bool
+		source   string
+		expected []string
+		catchall []string
+	}{
+		{name: "defaults present", defaults: []string{"public"}, expected: []string{"public"}, catchall: []string{"public"}},
+		{name: "defaults empty", expected: []string{"web", "websecure"}, catchall: nil},
+		{name: "defaults empty without preserve", preserve: new(bool), expected: nil, catchall: nil},
+		{name: "entrypoints source", source: config.EntryPointSourceEntryPoints, expected: []string{"web", "websecure"}, catchall: nil},
+		{name: "using source", source: config.EntryPointSourceUsing, expected: []string{"web", "websecure"}, catchall: []string{"web", "websecure", "traefik"}},
+		{name: "using source with defaults", defaults: []string{"public"}, source: config.EntryPointSourceUsing, expected: []string{"public"}, catchall: []string{"public"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+			cfg.Routers.Defaults.EntryPoints = tt.defaults
+			cfg.Routers.PreserveEntryPoints = tt.preserve
+			cfg.Routers.EntryPointSource = tt.source
+
+			result, err := New(cfg, discardLogger()).Aggregate()
+			require.NoError(t, err)
+
+			require.Contains(t, result.HTTP.Routers, "host1-webapp")
+			assert.Equal(t, tt.expected, result.HTTP.Routers["host1-webapp"].EntryPoints)
+			assert.Equal(t, tt.catchall, result.HTTP.Routers["host1-catchall"].EntryPoints)
+		})
+	}
+}
+
+const webappRouters = `[
+	{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)"}
+]`
+
+func TestAggregateServiceAffixes(t *testing.T) {
+	upstream1 := mockUpstream(t, map[string]string{"/api/http/routers": mergeRouters})
+	upstream2 := mockUpstream(t, map[string]string{"/api/http/routers": mergeRouters})
+
+	tests := []struct {
+		name     string
+		merge    bool
+		services []string
+	}{
+		{name: "namespaced", services: []string{"fed-host1-traefik-svc", "fed-host2-traefik-svc"}},
+		{name: "merged", merge: true, services: []string{"fed-admin-svc", "fed-webapp-svc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(
+				config.Upstream{Name: "host1", AdminURL: upstream1.URL, ServerURL: "http://192.168.1.10:80"},
+				config.Upstream{Name: "host2", AdminURL: upstream2.URL, ServerURL: "http://192.168.1.11:80"},
+			)
+			cfg.Routers.ServicePrefix = "fed-"
+			cfg.Routers.ServiceSuffix = "-svc"
+			cfg.Routers.MergeIdentical = tt.merge
+
+			agg := New(cfg, discardLogger())
+
+			result, err := agg.Aggregate()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.services, slices.Sorted(maps.Keys(result.HTTP.Services)))
+
+			// Every router and mapping refers to a defined service
+			for name, router := range result.HTTP.Routers {
+				assert.Contains(t, result.HTTP.Services, router.Service, name)
+			}
+
+			for _, mapping := range agg.Mappings() {
+				for _, router := range mapping.Routers {
+					if router.Included {
+						assert.Equal(t, result.HTTP.Routers[router.Router].Service, router.Service, router.Name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateNamespacedServicesResolve(t *testing.T) {
+	upstream1 := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	upstream2 := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: upstream1.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "host2", AdminURL: upstream2.URL, ServerURL: "http://192.168.1.11:80"},
+	)
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	httpConfig := result.HTTP
+
+	require.Len(t, httpConfig.Routers, 2)
+	require.Len(t, httpConfig.Services, 2)
+
+	expectedURLs := map[string]string{
+		"host1-webapp": "http://192.168.1.10:80",
+		"host2-webapp": "http://192.168.1.11:80",
+	}
+
+	for routerName, url := range expectedURLs {
+		router := httpConfig.Routers[routerName]
+		require.NotNil(t, router, routerName)
+
+		service, ok := httpConfig.Services[router.Service]
+		require.True(t, ok, "router %s references missing service %s", routerName, router.Service)
+		assert.Equal(t, url, service.LoadBalancer.Servers[0].URL)
+	}
+}
+
+func TestAggregateWithoutNamespacedServicesSkipsCollision(t *testing.T) {
+	upstream1 := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	upstream2 := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: upstream1.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "host2", AdminURL: upstream2.URL, ServerURL: "http://192.168.1.11:80"},
+	)
+
+	namespace := false
+	cfg.Routers.NamespaceServices = &namespace
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	httpConfig := result.HTTP
+
+	// The colliding upstream is skipped so existing references stay consistent
+	require.Len(t, httpConfig.Routers, 1)
+	require.Contains(t, httpConfig.Routers, "host1-webapp")
+	assert.Equal(t, "traefik", httpConfig.Routers["host1-webapp"].Service)
+	require.Contains(t, httpConfig.Services, "traefik")
+	assert.Equal(t, "http://192.168.1.10:80", httpConfig.Services["traefik"].LoadBalancer.Servers[0].URL)
+}
+
+func TestAggregateStickyServices(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.Defaults.Sticky = &dynamic.Sticky{
+		Cookie: &dynamic.Cookie{Name: "fed_sticky", Secure: true, HTTPOnly: true},
+	}
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	service := result.HTTP.Services["host1-traefik"]
+	require.NotNil(t, service)
+	require.NotNil(t, service.LoadBalancer.Sticky)
+	require.NotNil(t, service.LoadBalancer.Sticky.Cookie)
+	assert.Equal(t, "fed_sticky", service.LoadBalancer.Sticky.Cookie.Name)
+	assert.True(t, service.LoadBalancer.Sticky.Cookie.Secure)
+	assert.True(t, service.LoadBalancer.Sticky.Cookie.HTTPOnly)
+}
+
+func TestAggregateStickyEmptyEnablesDefaultCookie(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.Defaults.Sticky = &dynamic.Sticky{}
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	sticky := result.HTTP.Services["host1-traefik"].LoadBalancer.Sticky
+	require.NotNil(t, sticky)
+	assert.NotNil(t, sticky.Cookie)
+}
+
+func TestAggregateWithoutSticky(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+	assert.Nil(t, result.HTTP.Services["host1-traefik"].LoadBalancer.Sticky)
+}
+
+func TestAggregateServiceHealthCheck(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.Defaults.HealthCheck = &config.ServiceHealthCheck{
+		Path:     "/ping",
+		Interval: 10 * time.Second,
+		Timeout:  3 * time.Second,
+		Hostname: "ping.example.com",
+	}
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	healthCheck := result.HTTP.Services["host1-traefik"].LoadBalancer.HealthCheck
+	require.NotNil(t, healthCheck)
+	assert.Equal(t, &dynamic.ServerHealthCheck{
+		Path:     "/ping",
+		Interval: ptypes.Duration(10 * time.Second),
+		Timeout:  ptypes.Duration(3 * time.Second),
+		Hostname: "ping.example.com",
+	}, healthCheck)
+}
+
+func TestAggregateWithoutServiceHealthCheck(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+	assert.Nil(t, result.HTTP.Services["host1-traefik"].LoadBalancer.HealthCheck)
+}
+
+func TestAggregateHTTPTransport(t *testing.T) {
+	var closed atomic.Bool
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		closed.Store(r.Close)
+		_, _ = w.Write([]byte(priorityRouters))
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Server.HTTPTransport.DisableKeepAlives = true
+
+	_, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	// Without keep-alives the client asks the server to close the connection
+	assert.True(t, closed.Load())
+}
+
+func TestAggregateTLSDefaults(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": `[
+		{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)", "tls": {"certResolver": "internal"}},
+		{"name": "plain@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`plain.example.com`" + `)"}
+	]`})
+
+	t.Run("defaults replace upstream TLS", func(t *testing.T) {
+		cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+		cfg.Routers.Defaults.TLS = &dynamic.RouterTLSConfig{CertResolver: "letsencrypt", Options: "modern@file"}
+
+		result, err := New(cfg, discardLogger()).Aggregate()
+		require.NoError(t, err)
+
+		for _, name := range []string{"host1-webapp", "host1-plain"} {
+			require.NotNil(t, result.HTTP.Routers[name].TLS, name)
+			assert.Equal(t, "letsencrypt", result.HTTP.Routers[name].TLS.CertResolver, name)
+			assert.Equal(t, "modern@file", result.HTTP.Routers[name].TLS.Options, name)
+		}
+	})
+
+	t.Run("upstream TLS without defaults", func(t *testing.T) {
+		cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+		result, err := New(cfg, discardLogger()).Aggregate()
+		require.NoError(t, err)
+
+		require.NotNil(t, result.HTTP.Routers["host1-webapp"].TLS)
+		assert.Equal(t, "internal", result.HTTP.Routers["host1-webapp"].TLS.CertResolver)
+		assert.Nil(t, result.HTTP.Routers["host1-plain"].TLS)
+	})
+}
+
+func TestAggregateObservability(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": `[
+		{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)",
+		 "observability": {"accessLogs": true, "metrics": false, "tracing": true, "traceVerbosity": "detailed"}},
+		{"name": "plain@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`plain.example.com`" + `)"}
+	]`})
+
+	t.Run("not preserved by default", func(t *testing.T) {
+		cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+		result, err := New(cfg, discardLogger()).Aggregate()
+		require.NoError(t, err)
+
+		assert.Nil(t, result.HTTP.Routers["host1-webapp"].Observability)
+	})
+
+	t.Run("preserved from upstream", func(t *testing.T) {
+		cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+		cfg.Routers.PreserveObservability = true
+
+		result, err := New(cfg, discardLogger()).Aggregate()
+		require.NoError(t, err)
+
+		observability := result.HTTP.Routers["host1-webapp"].Observability
+		require.NotNil(t, observability)
+		assert.True(t, *observability.AccessLogs)
+		assert.False(t, *observability.Metrics)
+		assert.True(t, *observability.Tracing)
+		assert.Equal(t, otypes.DetailedVerbosity, observability.TraceVerbosity)
+
+		// Routers without observability settings are left to Traefik's defaults
+		assert.Nil(t, result.HTTP.Routers["host1-plain"].Observability)
+	})
+
+	t.Run("defaults replace upstream settings", func(t *testing.T) {
+		disabled := false
+
+		cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+		cfg.Routers.PreserveObservability = true
+		cfg.Routers.Defaults.Observability = &dynamic.RouterObservabilityConfig{AccessLogs: &disabled}
+
+		result, err := New(cfg, discardLogger()).Aggregate()
+		require.NoError(t, err)
+
+		for _, name := range []string{"host1-webapp", "host1-plain"} {
+			observability := result.HTTP.Routers[name].Observability
+			require.NotNil(t, observability, name)
+			assert.False(t, *observability.AccessLogs, name)
+			assert.Nil(t, observability.Tracing, name)
+		}
+	})
+
+	t.Run("access logs disabled", func(t *testing.T) {
+		cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+		cfg.Routers.PreserveObservability = true
+		cfg.Routers.Defaults.DisableAccessLogs = true
+
+		result, err := New(cfg, discardLogger()).Aggregate()
+		require.NoError(t, err)
+
+		// Other upstream settings are kept
+		observability := result.HTTP.Routers["host1-webapp"].Observability
+		require.NotNil(t, observability)
+		assert.False(t, *observability.AccessLogs)
+		assert.True(t, *observability.Tracing)
+		assert.Equal(t, otypes.DetailedVerbosity, observability.TraceVerbosity)
+
+		// Routers without observability settings only get accessLogs set
+		observability = result.HTTP.Routers["host1-plain"].Observability
+		require.NotNil(t, observability)
+		assert.False(t, *observability.AccessLogs)
+		assert.Nil(t, observability.Metrics)
+	})
+
+	t.Run("access logs disabled on top of defaults", func(t *testing.T) {
+		enabled := true
+		defaults := &dynamic.RouterObservabilityConfig{AccessLogs: &enabled, Metrics: &enabled}
+
+		cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+		cfg.Routers.Defaults.Observability = defaults
+		cfg.Routers.Defaults.DisableAccessLogs = true
+
+		result, err := New(cfg, discardLogger()).Aggregate()
+		require.NoError(t, err)
+
+		observability := result.HTTP.Routers["host1-webapp"].Observability
+		require.NotNil(t, observability)
+		assert.False(t, *observability.AccessLogs)
+		assert.True(t, *observability.Metrics)
+		assert.True(t, *defaults.AccessLogs, "configured defaults are not modified")
+	})
+}
+
+func TestAggregateFromFixtures(t *testing.T) {
+	cfg := testConfig(
+		config.Upstream{Name: "host1", ServerURL: "http://192.168.1.10:80", FixtureFile: writeFixture(t, `[
+			{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(`+"`app.example.com`"+`)"},
+			{"name": "admin@docker", "provider": "docker", "status": "enabled", "rule": "Host(`+"`admin.example.com`"+`)"},
+			{"name": "broken@docker", "provider": "docker", "status": "disabled", "rule": "Host(`+"`broken.example.com`"+`)"}
+		]`)},
+		config.Upstream{Name: "host2", ServerURL: "http://192.168.1.11:80", FixtureFile: writeFixture(t, `[
+			{"name": "blog@file", "provider": "file", "status": "enabled", "rule": "Host(`+"`blog.example.com`"+`)"}
+		]`)},
+	)
+	cfg.Routers.IncludeUDP = true
+	cfg.Routers.Selector.Exclude = []string{"admin@*"}
+	cfg.Routers.RuleRewrite = map[string]string{".example.com": ".example.org"}
+
+	agg := New(cfg, discardLogger())
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"host1-webapp", "host2-blog"}, slices.Collect(maps.Keys(result.HTTP.Routers)))
+	assert.Equal(t, "Host(`app.example.org`)", result.HTTP.Routers["host1-webapp"].Rule)
+	assert.Equal(t, "http://192.168.1.11:80", result.HTTP.Services["host2-traefik"].LoadBalancer.Servers[0].URL)
+
+	// Fixture upstreams only provide HTTP routers and are not failing
+	assert.Empty(t, result.UDP.Routers)
+
+	for _, mapping := range agg.Mappings() {
+		assert.Empty(t, mapping.Error, mapping.Upstream)
+	}
+}
+
+func TestAggregatePassHostHeader(t *testing.T) {
+	enabled, disabled := true, false
+
+	tests := []struct {
+		name           string
+		passHostHeader *bool
+	}{
+		{name: "true", passHostHeader: &enabled},
+		{name: "false", passHostHeader: &disabled},
+		{name: "unset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+			cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+			cfg.Routers.Defaults.PassHostHeader = tt.passHostHeader
+
+			result, err := New(cfg, discardLogger()).Aggregate()
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.passHostHeader, result.HTTP.Services["host1-traefik"].LoadBalancer.PassHostHeader)
+		})
+	}
+}
+
+func TestAggregateMappings(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": `[
+		{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)"},
+		{"name": "admin@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`admin.lan`" + `)"},
+		{"name": "old@docker", "provider": "docker", "status": "disabled", "rule": "Host(` + "`old.example.com`" + `)"},
+		{"name": "api@internal", "provider": "internal", "status": "enabled", "rule": "PathPrefix(` + "`/api`" + `)"}
+	]`})
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "down", AdminURL: "http://127.0.0.1:1", ServerURL: "http://127.0.0.1:80"},
+	)
+	cfg.Routers.Selector.Exclude = []string{"admin@*"}
+
+	agg := New(cfg, discardLogger())
+
+	_, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	mappings := agg.Mappings()
+	require.Len(t, mappings, 2)
+	assert.NotEmpty(t, mappings[1].Error)
+	mappings[1].Error = "unreachable"
+
+	data, err := json.Marshal(mappings)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `[
+		{
+			"upstream": "host1",
+			"routers": [
+				{"name": "webapp@docker", "provider": "docker", "status": "enabled", "included": true, "router": "host1-webapp", "service": "host1-traefik"},
+				{"name": "admin@docker", "provider": "docker", "status": "enabled", "included": false, "reason": "exclude"},
+				{"name": "old@docker", "provider": "docker", "status": "disabled", "included": false, "reason": "status"},
+				{"name": "api@internal", "provider": "internal", "status": "enabled", "included": false, "reason": "internal"}
+			]
+		},
+		{
+			"upstream": "down",
+			"error": "unreachable",
+			"routers": null
+		}
+	]`, string(data))
+}
+
+func TestAggregateRawRouters(t *testing.T) {
+	body := `[{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)"}]`
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": body})
+
+	for _, debug := range []bool{true, false} {
+		cfg := testConfig(
+			config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"},
+			config.Upstream{Name: "down", AdminURL: "http://127.0.0.1:1", ServerURL: "http://127.0.0.1:80"},
+		)
+		cfg.Output.HTTP.Debug = debug
+
+		agg := New(cfg, discardLogger())
+
+		_, err := agg.Aggregate()
+		require.NoError(t, err)
+
+		if !debug {
+			assert.Empty(t, agg.RawRouters())
+			continue
+		}
+
+		assert.Equal(t, map[string][]byte{"host1": []byte(body)}, agg.RawRouters())
+	}
+}
+
+func TestAggregateCountsExclusionReasons(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": `[
+		{"name": "webapp@docker", "provider": "docker", "status": "enabled", "ruleSyntax": "v3"},
+		{"name": "old@docker", "provider": "docker", "status": "disabled"},
+		{"name": "broken@docker", "provider": "docker", "status": "warning"},
+		{"name": "legacy@docker", "provider": "docker", "status": "enabled", "ruleSyntax": "v2"},
+		{"name": "static@file", "provider": "file", "status": "enabled"},
+		{"name": "api@internal", "provider": "internal", "status": "enabled"},
+		{"name": "dashboard@internal", "provider": "internal", "status": "enabled"}
+	]`})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.Selector.Provider = "docker"
+	cfg.Routers.TargetSyntax = "v3"
+
+	agg := New(cfg, discardLogger())
+
+	_, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	stats := agg.Stats()
+	require.Len(t, stats.Upstreams, 1)
+	assert.Equal(t, map[string]int{
+		"status":      2,
+		"provider":    1,
+		"internal":    2,
+		"rule_syntax": 1,
+	}, stats.Upstreams[0].Excluded)
+}
+
+func TestRunPollsUpstreamsAtTheirOwnInterval(t *testing.T) {
+	var fastPolls, slowPolls atomic.Int32
+
+	countingUpstream := func(counter *atomic.Int32) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/http/routers" {
+				counter.Add(1)
+			}
+
+			_, _ = w.Write([]byte(webappRouters))
+		}))
+		t.Cleanup(server.Close)
+
+		return server
+	}
+
+	fast := countingUpstream(&fastPolls)
+	slow := countingUpstream(&slowPolls)
+
+	cfg := testConfig(
+		config.Upstream{Name: "fast", AdminURL: fast.URL, ServerURL: "http://192.168.1.10:80", PollInterval: 20 * time.Millisecond},
+		config.Upstream{Name: "slow", AdminURL: slow.URL, ServerURL: "http://192.168.1.11:80", PollInterval: 200 * time.Millisecond},
+	)
+	cfg.Server.PollInterval = time.Hour
+
+	agg := New(cfg, discardLogger())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	var notifications atomic.Int32
+
+	agg.Run(ctx, func() { notifications.Add(1) })
+
+	assert.GreaterOrEqual(t, fastPolls.Load(), int32(10))
+	assert.InDelta(t, 2, slowPolls.Load(), 1)
+	assert.GreaterOrEqual(t, notifications.Load(), fastPolls.Load()+slowPolls.Load())
+
+	// Both upstreams contribute to the merged snapshot
+	snapshot := agg.Snapshot()
+	assert.Contains(t, snapshot.HTTP.Routers, "fast-webapp")
+	assert.Contains(t, snapshot.HTTP.Routers, "slow-webapp")
+}
+
+func TestSnapshotKeepsOtherUpstreamsWhenOneFails(t *testing.T) {
+	healthy := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: healthy.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "down", AdminURL: "http://127.0.0.1:1", ServerURL: "http://127.0.0.1:80"},
+	)
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	assert.Len(t, result.HTTP.Routers, 1)
+	assert.Contains(t, result.HTTP.Routers, "host1-webapp")
+}
+
+func TestJitteredIntervalBounds(t *testing.T) {
+	interval := 10 * time.Second
+	jitter := 2 * time.Second
+
+	seen := make(map[time.Duration]bool)
+
+	for range 1000 {
+		d := jitteredInterval(interval, jitter)
+		assert.GreaterOrEqual(t, d, interval-jitter)
+		assert.LessOrEqual(t, d, interval+jitter)
+
+		seen[d] = true
+	}
+
+	assert.Greater(t, len(seen), 1, "intervals should be randomized")
+
+	// Without jitter the interval is fixed
+	assert.Equal(t, interval, jitteredInterval(interval, 0))
+
+	// Jitter is capped at half the interval
+	for range 1000 {
+		d := jitteredInterval(interval, time.Hour)
+		assert.GreaterOrEqual(t, d, interval/2)
+		assert.LessOrEqual(t, d, interval+interval/2)
+	}
+}
+
+func TestRunAppliesJitter(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/http/routers" {
+			http.NotFound(w, r)
+			return
+		}
+
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+
+		_, _ = w.Write([]byte(webappRouters))
+	}))
+	t.Cleanup(server.Close)
+
+	interval := 60 * time.Millisecond
+	jitter := 20 * time.Millisecond
+
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: server.URL, ServerURL: "http://192.168.1.10:80", PollInterval: interval})
+	cfg.Server.PollJitter = jitter.String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 700*time.Millisecond)
+	defer cancel()
+
+	New(cfg, discardLogger()).Run(ctx, func() {})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.GreaterOrEqual(t, len(times), 5)
+
+	// Allow scheduling slack on top of the jittered bounds
+	slack := 15 * time.Millisecond
+
+	for i := 1; i < len(times); i++ {
+		d := times[i].Sub(times[i-1])
+		assert.GreaterOrEqual(t, d, interval-jitter-slack, "interval %d", i)
+		assert.LessOrEqual(t, d, interval+jitter+slack, "interval %d", i)
+	}
+}
+
+func TestStatsReflectAggregation(t *testing.T) {
+	server := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: server.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "host2", AdminURL: "http://127.0.0.1:1", ServerURL: "http://192.168.1.11:80"},
+	)
+
+	agg := New(cfg, discardLogger())
+
+	before := time.Now()
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	stats := agg.Stats()
+	assert.False(t, stats.LastPoll.Before(before))
+	assert.Positive(t, stats.DurationMs)
+	assert.Equal(t, len(result.HTTP.Routers), stats.Routers)
+	assert.Equal(t, len(result.HTTP.Services), stats.Services)
+
+	require.Len(t, stats.Upstreams, 2)
+	assert.Equal(t, "host1", stats.Upstreams[0].Upstream)
+	assert.Equal(t, len(result.HTTP.Routers), stats.Upstreams[0].Routers)
+	assert.Equal(t, 1, stats.Upstreams[0].Services)
+	assert.Empty(t, stats.Upstreams[0].Error)
+
+	assert.Equal(t, "host2", stats.Upstreams[1].Upstream)
+	assert.Zero(t, stats.Upstreams[1].Routers)
+	assert.NotEmpty(t, stats.Upstreams[1].Error)
+}
+
+func TestStatsIncludeUpstreamVersion(t *testing.T) {
+	server := mockUpstream(t, map[string]string{
+		"/api/http/routers": webappRouters,
+		"/api/version":      `{"Version":"2.11.0","Codename":"mimolette","startDate":"2026-01-02T03:04:05Z"}`,
+	})
+	noVersion := mockUpstream(t, map[string]string{"/api/http/routers": "[]"})
+
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: server.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "host2", AdminURL: noVersion.URL, ServerURL: "http://192.168.1.11:80"},
+	)
+
+	agg := New(cfg, discardLogger())
+
+	_, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	stats := agg.Stats()
+	require.Len(t, stats.Upstreams, 2)
+	assert.Equal(t, "2.11.0", stats.Upstreams[0].Version)
+	assert.Empty(t, stats.Upstreams[1].Version)
+	assert.Empty(t, stats.Upstreams[1].Error, "a missing version endpoint must not fail the poll")
+}
+
+func TestRefreshLogsCarryPollID(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	agg := New(cfg, logger)
+
+	agg.Refresh(t.Context())
+	agg.Refresh(t.Context())
+
+	pollIDs := make(map[float64]int)
+
+	for line := range strings.Lines(buf.String()) {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+
+		pollID, ok := record["poll_id"].(float64)
+		require.True(t, ok, "log line without poll_id: %s", line)
+
+		pollIDs[pollID]++
+	}
+
+	// Each poll logs several lines under its own ID
+	require.Len(t, pollIDs, 2)
+
+	for id, lines := range pollIDs {
+		assert.Greater(t, lines, 1, "poll %v", id)
+	}
+}
+
+func TestRefreshRecordsSpans(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "host2", AdminURL: "http://127.0.0.1:1", ServerURL: "http://192.168.1.11:80"},
+	)
+
+	recorder := tracetest.NewSpanRecorder()
+	agg := New(cfg, discardLogger())
+	agg.tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer(tracerName)
+
+	agg.Refresh(t.Context())
+
+	spans := recorder.Ended()
+	byID := make(map[string]sdktrace.ReadOnlySpan)
+
+	var refresh sdktrace.ReadOnlySpan
+
+	polls := make(map[string]sdktrace.ReadOnlySpan)
+
+	for _, span := range spans {
+		byID[span.SpanContext().SpanID().String()] = span
+
+		switch span.Name() {
+		case "aggregator.refresh":
+			refresh = span
+		case "aggregator.poll":
+			for _, attr := range span.Attributes() {
+				if attr.Key == "upstream" {
+					polls[attr.Value.AsString()] = span
+				}
+			}
+		}
+	}
+
+	require.NotNil(t, refresh)
+	require.Len(t, polls, 2)
+
+	for name, poll := range polls {
+		assert.Equal(t, refresh.SpanContext().SpanID(), poll.Parent().SpanID(), name)
+	}
+
+	// Upstreams are polled in order, matching the poll_id of their log lines
+	assert.Contains(t, polls["host1"].Attributes(), attribute.Int64("poll_id", 1))
+	assert.Contains(t, polls["host2"].Attributes(), attribute.Int64("poll_id", 2))
+
+	assert.Equal(t, codes.Unset, polls["host1"].Status().Code)
+	assert.Equal(t, codes.Error, polls["host2"].Status().Code)
+
+	// Upstream requests are recorded as children of the poll of their upstream
+	requests := make(map[string][]string)
+
+	for _, span := range spans {
+		parent, ok := byID[span.Parent().SpanID().String()]
+		if !ok || parent.Name() != "aggregator.poll" {
+			continue
+		}
+
+		for name, poll := range polls {
+			if poll == parent {
+				requests[name] = append(requests[name], span.Name())
+			}
+		}
+	}
+
+	assert.Contains(t, requests["host1"], "GET /http/routers")
+	assert.Contains(t, requests["host2"], "GET /http/routers")
+}
+
+func TestStatsKeepLastSuccessAcrossFailures(t *testing.T) {
+	var failing atomic.Bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() || r.URL.Path != "/api/http/routers" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte(webappRouters))
+	}))
+	t.Cleanup(server.Close)
+
+	agg := New(testConfig(config.Upstream{Name: "host1", AdminURL: server.URL, ServerURL: "http://192.168.1.10:80"}), discardLogger())
+
+	_, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	succeeded := agg.Stats().Upstreams[0].LastSuccess
+	require.False(t, succeeded.IsZero())
+
+	failing.Store(true)
+
+	_, err = agg.Aggregate()
+	require.NoError(t, err)
+
+	stats := agg.Stats().Upstreams[0]
+	assert.NotEmpty(t, stats.Error)
+	assert.True(t, stats.LastPoll.After(succeeded))
+	assert.True(t, succeeded.Equal(stats.LastSuccess))
+}
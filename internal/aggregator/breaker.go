@@ -0,0 +1,108 @@
+package aggregator
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	circuitClosed   = "closed"    // Upstream is polled normally
+	circuitOpen     = "open"      // Polls are skipped until the cool-down ends
+	circuitHalfOpen = "half-open" // The next poll probes whether the upstream recovered
+)
+
+// CircuitStats describes the circuit breaker of an upstream
+type CircuitStats struct {
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`           // Consecutive failed polls
+	RetryAt  *time.Time `json:"retry_at,omitempty"` // End of the cool-down while open
+}
+
+// circuitBreaker skips polls of an upstream after consecutive failures.
+// Each time a half-open probe fails the cool-down doubles, up to maxCoolDown.
+type circuitBreaker struct {
+	threshold   int
+	coolDown    time.Duration
+	maxCoolDown time.Duration
+	now         func() time.Time
+
+	mu        sync.Mutex
+	state     string
+	failures  int
+	trips     int // Consecutive times the circuit opened without recovering
+	openUntil time.Time
+}
+
+// newCircuitBreaker creates a closed circuit breaker
+func newCircuitBreaker(threshold int, coolDown, maxCoolDown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold:   threshold,
+		coolDown:    coolDown,
+		maxCoolDown: maxCoolDown,
+		now:         time.Now,
+		state:       circuitClosed,
+	}
+}
+
+// allow reports whether the upstream should be polled now, moving an open
+// circuit to half-open once its cool-down has elapsed
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == circuitOpen && !b.now().Before(b.openUntil) {
+		b.state = circuitHalfOpen
+	}
+
+	return b.state != circuitOpen
+}
+
+// success records a successful poll and closes the circuit
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = circuitClosed
+	b.failures = 0
+	b.trips = 0
+}
+
+// failure records a failed poll, opening the circuit when the threshold is
+// reached or a half-open probe failed. It reports whether the circuit opened.
+func (b *circuitBreaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+
+	if b.state != circuitHalfOpen && b.failures < b.threshold {
+		return false
+	}
+
+	coolDown := b.coolDown
+	for i := 0; i < b.trips && coolDown < b.maxCoolDown; i++ {
+		coolDown *= 2
+	}
+
+	b.state = circuitOpen
+	b.openUntil = b.now().Add(min(coolDown, b.maxCoolDown))
+	b.trips++
+
+	return true
+}
+
+// stats returns the current state of the circuit
+func (b *circuitBreaker) stats() *CircuitStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := &CircuitStats{State: b.state, Failures: b.failures}
+
+	if b.state == circuitOpen {
+		retryAt := b.openUntil
+		stats.RetryAt = &retryAt
+	}
+
+	return stats
+}
@@ -0,0 +1,121 @@
+package aggregator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	b := newCircuitBreaker(2, 10*time.Second, 25*time.Second)
+	b.now = func() time.Time { return now }
+
+	// Closed: failures below the threshold keep polling
+	assert.True(t, b.allow())
+	assert.False(t, b.failure())
+	assert.Equal(t, circuitClosed, b.stats().State)
+	assert.True(t, b.allow())
+
+	// Reaching the threshold opens the circuit
+	assert.True(t, b.failure())
+	assert.Equal(t, circuitOpen, b.stats().State)
+	assert.Equal(t, now.Add(10*time.Second), *b.stats().RetryAt)
+	assert.False(t, b.allow())
+
+	// After the cool-down a single probe is allowed
+	now = now.Add(10 * time.Second)
+	assert.True(t, b.allow())
+	assert.Equal(t, circuitHalfOpen, b.stats().State)
+	assert.Nil(t, b.stats().RetryAt)
+
+	// A failed probe reopens with a doubled cool-down
+	assert.True(t, b.failure())
+	assert.Equal(t, circuitOpen, b.stats().State)
+	assert.Equal(t, now.Add(20*time.Second), *b.stats().RetryAt)
+
+	// The cool-down is capped
+	now = now.Add(20 * time.Second)
+	require.True(t, b.allow())
+	assert.True(t, b.failure())
+	assert.Equal(t, now.Add(25*time.Second), *b.stats().RetryAt)
+
+	// A successful probe closes the circuit and resets the cool-down
+	now = now.Add(25 * time.Second)
+	require.True(t, b.allow())
+	assert.Equal(t, circuitHalfOpen, b.stats().State)
+
+	b.success()
+	assert.Equal(t, circuitClosed, b.stats().State)
+	assert.Zero(t, b.stats().Failures)
+	assert.True(t, b.allow())
+
+	assert.False(t, b.failure())
+	assert.True(t, b.failure())
+	assert.Equal(t, now.Add(10*time.Second), *b.stats().RetryAt)
+}
+
+func TestRefreshSkipsUpstreamWithOpenCircuit(t *testing.T) {
+	var (
+		requests atomic.Int32
+		healthy  atomic.Bool
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/http/routers" {
+			http.NotFound(w, r)
+			return
+		}
+
+		requests.Add(1)
+
+		if !healthy.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte(webappRouters))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: server.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Server.CircuitBreaker = config.CircuitBreaker{Enabled: true, FailThreshold: 2, CoolDown: time.Minute, MaxCoolDown: time.Hour}
+
+	agg := New(cfg, discardLogger())
+
+	now := time.Now()
+	agg.breakers["host1"].now = func() time.Time { return now }
+
+	circuit := func() *CircuitStats {
+		agg.Snapshot()
+		return agg.Stats().Upstreams[0].Circuit
+	}
+
+	agg.Refresh(t.Context())
+	assert.Equal(t, circuitClosed, circuit().State)
+
+	agg.Refresh(t.Context())
+	assert.Equal(t, circuitOpen, circuit().State)
+	assert.Equal(t, 2, circuit().Failures)
+	assert.Equal(t, int32(2), requests.Load())
+
+	// Open: polls are skipped
+	agg.Refresh(t.Context())
+	assert.Equal(t, int32(2), requests.Load())
+
+	// Half-open probe succeeds and closes the circuit
+	healthy.Store(true)
+	now = now.Add(time.Minute)
+
+	agg.Refresh(t.Context())
+	assert.Equal(t, int32(3), requests.Load())
+	assert.Equal(t, circuitClosed, circuit().State)
+	assert.Empty(t, agg.Stats().Upstreams[0].Error)
+}
@@ -0,0 +1,95 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+)
+
+const (
+	// dnsFailThreshold is the number of consecutive polls the server_url host
+	// must be reported as nonexistent before the upstream is skipped
+	dnsFailThreshold = 3
+
+	// dnsTimeout bounds a single lookup of the server_url host
+	dnsTimeout = 2 * time.Second
+)
+
+// dnsCheck tracks whether the server_url host of an upstream resolves
+type dnsCheck struct {
+	mu       sync.Mutex
+	failures int   // Consecutive polls the host was not found
+	lastErr  error // Last lookup error, nil after a successful lookup
+}
+
+// resolves reports whether the host resolved recently enough, along with the
+// last lookup error
+func (c *dnsCheck) resolves() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.failures < dnsFailThreshold, c.lastErr
+}
+
+// checkServerDNS resolves the server_url host of the upstream. Only a host
+// reported as nonexistent counts as a failure: timeouts and resolver errors
+// are transient and neither skip nor restore the upstream. Hosts that are IP
+// addresses are not looked up.
+func (a *Aggregator) checkServerDNS(ctx context.Context, logger *slog.Logger, upstream config.Upstream, check *dnsCheck) {
+	host, err := serverHost(upstream)
+	if err != nil || host == "" || net.ParseIP(host) != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dnsTimeout)
+	defer cancel()
+
+	_, err = a.lookupHost(ctx, host)
+
+	var dnsErr *net.DNSError
+	if err != nil && (!errors.As(err, &dnsErr) || !dnsErr.IsNotFound) {
+		logger.Debug("failed to resolve server_url host, keeping the upstream",
+			"upstream", upstream.Name,
+			"host", host,
+			"error", err)
+
+		return
+	}
+
+	check.mu.Lock()
+	defer check.mu.Unlock()
+
+	wasResolving := check.failures < dnsFailThreshold
+
+	if err != nil {
+		check.failures++
+	} else {
+		check.failures = 0
+	}
+
+	check.lastErr = err
+
+	switch resolving := check.failures < dnsFailThreshold; {
+	case wasResolving && !resolving:
+		logger.Warn("server_url host does not resolve, skipping the routers of the upstream",
+			"upstream", upstream.Name,
+			"host", host,
+			"failures", check.failures,
+			"error", err)
+	case !wasResolving && resolving:
+		logger.Info("server_url host resolves again, including the routers of the upstream",
+			"upstream", upstream.Name,
+			"host", host)
+	case err != nil && resolving:
+		logger.Warn("server_url host does not resolve",
+			"upstream", upstream.Name,
+			"host", host,
+			"failures", check.failures,
+			"skip_after", dnsFailThreshold)
+	}
+}
@@ -0,0 +1,92 @@
+package aggregator
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateSkipsUnresolvableServerHost(t *testing.T) {
+	admin := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: admin.URL, ServerURL: "http://192.168.1.10:80", ValidateServerDNS: true},
+		config.Upstream{Name: "host2", AdminURL: admin.URL, ServerURL: "http://gone.invalid:80", ValidateServerDNS: true},
+	)
+
+	var (
+		logs      bytes.Buffer
+		lookups   int
+		lookupErr error = &net.DNSError{Err: "no such host", Name: "gone.invalid", IsNotFound: true}
+	)
+
+	// Polls run sequentially, so lookups need no synchronization
+	agg := New(cfg, slog.New(slog.NewTextHandler(&logs, nil)))
+	agg.lookupHost = func(_ context.Context, host string) ([]string, error) {
+		assert.Equal(t, "gone.invalid", host, "IP addresses are not looked up")
+		lookups++
+
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+
+		return []string{"192.168.1.11"}, nil
+	}
+
+	// A host missing for a few polls is still served
+	for range dnsFailThreshold - 1 {
+		result, err := agg.Aggregate()
+		require.NoError(t, err)
+		assert.Contains(t, result.HTTP.Routers, "host2-webapp")
+	}
+
+	assert.Contains(t, logs.String(), "server_url host does not resolve")
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	assert.Contains(t, result.HTTP.Routers, "host1-webapp")
+	assert.NotContains(t, result.HTTP.Routers, "host2-webapp")
+	assert.NotContains(t, result.HTTP.Services, "host2-traefik")
+	assert.Contains(t, logs.String(), "skipping the routers of the upstream")
+	assert.Contains(t, agg.Stats().Upstreams[1].Error, "server_url host does not resolve")
+
+	// Transient resolver failures do not bring the upstream back
+	lookupErr = &net.DNSError{Err: "i/o timeout", Name: "gone.invalid", IsTimeout: true}
+
+	result, err = agg.Aggregate()
+	require.NoError(t, err)
+	assert.NotContains(t, result.HTTP.Routers, "host2-webapp")
+
+	lookupErr = nil
+
+	result, err = agg.Aggregate()
+	require.NoError(t, err)
+	assert.Contains(t, result.HTTP.Routers, "host2-webapp")
+	assert.Contains(t, logs.String(), "server_url host resolves again")
+
+	assert.Equal(t, dnsFailThreshold+2, lookups)
+}
+
+func TestDNSCheckIgnoresTransientFailures(t *testing.T) {
+	agg := New(testConfig(), discardLogger())
+	agg.lookupHost = func(context.Context, string) ([]string, error) {
+		return nil, &net.DNSError{Err: "server misbehaving", Name: "app.example.com", IsTemporary: true}
+	}
+
+	upstream := config.Upstream{Name: "host1", ServerURL: "http://app.example.com:80"}
+	check := &dnsCheck{}
+
+	for range dnsFailThreshold + 1 {
+		agg.checkServerDNS(t.Context(), discardLogger(), upstream, check)
+	}
+
+	resolves, err := check.resolves()
+	assert.True(t, resolves)
+	assert.NoError(t, err)
+}
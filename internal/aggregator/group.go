@@ -0,0 +1,74 @@
+package aggregator
+
+import (
+	"maps"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// UpstreamConfiguration is the part of an aggregated configuration generated
+// from a single upstream
+type UpstreamConfiguration struct {
+	Upstream string
+	Config   *dynamic.Configuration
+}
+
+// Groups returns the configuration of the last snapshot grouped by upstream,
+// in config order. Upstreams left out of the snapshot have no group.
+func (a *Aggregator) Groups() []UpstreamConfiguration {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.groups
+}
+
+// groupByUpstream splits the aggregated configuration by the upstream its
+// routers were generated from, using the router mappings. A router merged from
+// several upstreams belongs to the first of them, together with its service.
+// TCP and UDP routers are taken from the upstream's own configuration.
+func groupByUpstream(result *dynamic.Configuration, mappings []UpstreamMapping, upstreamConfigs map[string]*dynamic.Configuration) []UpstreamConfiguration {
+	groups := make([]UpstreamConfiguration, 0, len(mappings))
+	assigned := make(map[string]struct{})
+
+	for _, mapping := range mappings {
+		if mapping.Error != "" {
+			continue
+		}
+
+		group := newConfiguration(result.TCP != nil, result.UDP != nil)
+
+		for _, routerMapping := range mapping.Routers {
+			router, ok := result.HTTP.Routers[routerMapping.Router]
+			if !routerMapping.Included || !ok {
+				continue
+			}
+
+			if _, done := assigned[routerMapping.Router]; done {
+				continue
+			}
+
+			assigned[routerMapping.Router] = struct{}{}
+			group.HTTP.Routers[routerMapping.Router] = router
+
+			if service, ok := result.HTTP.Services[router.Service]; ok {
+				group.HTTP.Services[router.Service] = service
+			}
+		}
+
+		if upstreamConfig := upstreamConfigs[mapping.Upstream]; upstreamConfig != nil {
+			if group.TCP != nil && upstreamConfig.TCP != nil {
+				maps.Copy(group.TCP.Routers, upstreamConfig.TCP.Routers)
+				maps.Copy(group.TCP.Services, upstreamConfig.TCP.Services)
+			}
+
+			if group.UDP != nil && upstreamConfig.UDP != nil {
+				maps.Copy(group.UDP.Routers, upstreamConfig.UDP.Routers)
+				maps.Copy(group.UDP.Services, upstreamConfig.UDP.Services)
+			}
+		}
+
+		groups = append(groups, UpstreamConfiguration{Upstream: mapping.Upstream, Config: group})
+	}
+
+	return groups
+}
@@ -0,0 +1,64 @@
+package aggregator
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateGroupsByUpstream(t *testing.T) {
+	host1 := mockUpstream(t, map[string]string{"/api/http/routers": mergeRouters})
+	host2 := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	unreachable := mockUpstream(t, map[string]string{})
+
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: host1.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "host2", AdminURL: host2.URL, ServerURL: "http://192.168.1.11:80"},
+		config.Upstream{Name: "host3", AdminURL: unreachable.URL, ServerURL: "http://192.168.1.12:80"},
+	)
+
+	agg := New(cfg, discardLogger())
+
+	_, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	// The failing upstream has no group
+	groups := agg.Groups()
+	require.Len(t, groups, 2)
+
+	assert.Equal(t, "host1", groups[0].Upstream)
+	assert.ElementsMatch(t, []string{"host1-webapp", "host1-admin"}, slices.Collect(maps.Keys(groups[0].Config.HTTP.Routers)))
+	assert.ElementsMatch(t, []string{"host1-traefik"}, slices.Collect(maps.Keys(groups[0].Config.HTTP.Services)))
+
+	assert.Equal(t, "host2", groups[1].Upstream)
+	assert.ElementsMatch(t, []string{"host2-webapp"}, slices.Collect(maps.Keys(groups[1].Config.HTTP.Routers)))
+	assert.ElementsMatch(t, []string{"host2-traefik"}, slices.Collect(maps.Keys(groups[1].Config.HTTP.Services)))
+}
+
+func TestAggregateGroupsMergedRouters(t *testing.T) {
+	host1 := mockUpstream(t, map[string]string{"/api/http/routers": mergeRouters})
+	host2 := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: host1.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "host2", AdminURL: host2.URL, ServerURL: "http://192.168.1.11:80"},
+	)
+	cfg.Routers.MergeIdentical = true
+
+	agg := New(cfg, discardLogger())
+
+	_, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	// A merged router is only part of the first upstream it comes from
+	groups := agg.Groups()
+	require.Len(t, groups, 2)
+	assert.ElementsMatch(t, []string{"webapp", "host1-admin"}, slices.Collect(maps.Keys(groups[0].Config.HTTP.Routers)))
+	assert.ElementsMatch(t, []string{"webapp", "host1-traefik"}, slices.Collect(maps.Keys(groups[0].Config.HTTP.Services)))
+	assert.Empty(t, groups[1].Config.HTTP.Routers)
+	assert.Empty(t, groups[1].Config.HTTP.Services)
+}
@@ -0,0 +1,198 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+)
+
+// healthProbe actively checks that the server_url of an upstream is reachable
+type healthProbe struct {
+	target    *url.URL // Rewritten server_url
+	path      string   // Probed with HTTP HEAD when set, otherwise a TCP connect is used
+	timeout   time.Duration
+	threshold int
+	client    *http.Client
+
+	mu       sync.Mutex
+	failures int
+	lastErr  error
+}
+
+// newHealthProbe creates a probe for the upstream health check
+func newHealthProbe(upstream config.Upstream) (*healthProbe, error) {
+	targetURL, err := upstream.TargetURL()
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server_url: %w", err)
+	}
+
+	check := upstream.HealthCheck
+
+	return &healthProbe{
+		target:    target,
+		path:      check.Path,
+		timeout:   check.Timeout,
+		threshold: max(check.FailThreshold, 1),
+		client: &http.Client{
+			// Any answer proves reachability, redirects included
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+// probe checks the server once and records the result.
+// It reports whether the healthy state changed.
+func (p *healthProbe) probe(ctx context.Context) bool {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	var err error
+	if p.path != "" {
+		err = p.probeHTTP(ctx)
+	} else {
+		err = p.probeTCP(ctx)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	wasHealthy := p.failures < p.threshold
+
+	if err != nil {
+		p.failures++
+	} else {
+		p.failures = 0
+	}
+
+	p.lastErr = err
+
+	return wasHealthy != (p.failures < p.threshold)
+}
+
+// probeHTTP sends a HEAD request to the health check path. Any status below
+// 500 counts as reachable.
+func (p *healthProbe) probeHTTP(ctx context.Context) error {
+	target := *p.target
+	target.Path = p.path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// probeTCP opens and closes a TCP connection to the server
+func (p *healthProbe) probeTCP(ctx context.Context) error {
+	port := p.target.Port()
+	if port == "" {
+		port = "80"
+		if p.target.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(p.target.Hostname(), port))
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// healthy reports whether the server passed the probe recently enough, along
+// with the last probe error
+func (p *healthProbe) healthy() (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.failures < p.threshold, p.lastErr
+}
+
+// probeUpstreams probes the server_url of every upstream with a health check
+func (a *Aggregator) probeUpstreams(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, upstream := range a.config.Upstreams {
+		if probe := a.probes[upstream.Name]; probe != nil {
+			wg.Go(func() {
+				a.probeUpstream(ctx, upstream, probe)
+			})
+		}
+	}
+
+	wg.Wait()
+}
+
+// probeUpstream probes a single upstream, logging changes of its health.
+// It reports whether the health changed.
+func (a *Aggregator) probeUpstream(ctx context.Context, upstream config.Upstream, probe *healthProbe) bool {
+	if !probe.probe(ctx) {
+		return false
+	}
+
+	healthy, err := probe.healthy()
+	if healthy {
+		a.logger.Info("upstream server reachable again, including its routers", "upstream", upstream.Name)
+	} else {
+		a.logger.Warn("upstream server unreachable, skipping its routers",
+			"upstream", upstream.Name,
+			"error", err)
+	}
+
+	return true
+}
+
+// runHealthCheck probes the upstream on its health check interval until ctx
+// is cancelled, calling notify whenever its health changes
+func (a *Aggregator) runHealthCheck(ctx context.Context, upstream config.Upstream, probe *healthProbe, notify func()) {
+	interval := upstream.HealthCheck.Interval
+	if interval <= 0 {
+		interval = a.pollInterval(upstream)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if a.probeUpstream(ctx, upstream, probe) {
+				notify()
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package aggregator
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableURL returns the URL of a local port nothing listens on
+func unreachableURL(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	return "http://" + addr
+}
+
+func TestAggregateSkipsUnreachableServer(t *testing.T) {
+	admin := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	check := &config.HealthCheck{Timeout: time.Second, FailThreshold: 1}
+
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: admin.URL, ServerURL: server.URL, HealthCheck: check},
+		config.Upstream{Name: "host2", AdminURL: admin.URL, ServerURL: unreachableURL(t), HealthCheck: check},
+	)
+
+	agg := New(cfg, discardLogger())
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	assert.Contains(t, result.HTTP.Routers, "host1-webapp")
+	assert.NotContains(t, result.HTTP.Routers, "host2-webapp")
+	assert.NotContains(t, result.HTTP.Services, "host2-traefik")
+
+	stats := agg.Stats()
+	require.Len(t, stats.Upstreams, 2)
+	assert.Empty(t, stats.Upstreams[0].Error)
+	assert.Contains(t, stats.Upstreams[1].Error, "server_url health check failing")
+}
+
+func TestHealthProbeThreshold(t *testing.T) {
+	var healthy atomic.Bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		assert.Equal(t, "/ping", r.URL.Path)
+
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	probe, err := newHealthProbe(config.Upstream{
+		Name:        "host1",
+		ServerURL:   server.URL,
+		HealthCheck: &config.HealthCheck{Path: "/ping", Timeout: time.Second, FailThreshold: 2},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// A single failure is tolerated
+	assert.False(t, probe.probe(ctx))
+
+	ok, probeErr := probe.healthy()
+	assert.True(t, ok)
+	require.Error(t, probeErr)
+	assert.Contains(t, probeErr.Error(), "status 502")
+
+	// Consistent failures mark the server unhealthy
+	assert.True(t, probe.probe(ctx))
+
+	ok, _ = probe.healthy()
+	assert.False(t, ok)
+
+	// A single success recovers
+	healthy.Store(true)
+
+	assert.True(t, probe.probe(ctx))
+
+	ok, probeErr = probe.healthy()
+	assert.True(t, ok)
+	assert.NoError(t, probeErr)
+}
+
+func TestHealthProbeTCP(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	reachable, err := newHealthProbe(config.Upstream{ServerURL: server.URL, HealthCheck: &config.HealthCheck{Timeout: time.Second}})
+	require.NoError(t, err)
+
+	reachable.probe(context.Background())
+
+	ok, _ := reachable.healthy()
+	assert.True(t, ok)
+
+	unreachable, err := newHealthProbe(config.Upstream{ServerURL: unreachableURL(t), HealthCheck: &config.HealthCheck{Timeout: time.Second}})
+	require.NoError(t, err)
+
+	unreachable.probe(context.Background())
+
+	ok, probeErr := unreachable.healthy()
+	assert.False(t, ok)
+	assert.Error(t, probeErr)
+}
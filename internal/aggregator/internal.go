@@ -0,0 +1,64 @@
+package aggregator
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/chickenzord/traefik-fed/internal/traefik"
+)
+
+// excludedInternalService is the exclusion reason of routers whose upstream
+// service is one of Traefik's internal services
+const excludedInternalService traefik.ExclusionReason = "internal_service"
+
+// filterInternalServices drops routers of other providers that point to an
+// internal service such as api@internal or dashboard@internal. Federating them
+// would expose the upstream Traefik API or dashboard through the federated
+// Traefik, like the routers of the internal provider dropped by default.
+// include_internal keeps both. Dropped routers are recorded in reasons and
+// warned about once, until they point to another service. The warned routers
+// are replaced on every poll, so those gone from the upstream are forgotten.
+func (a *Aggregator) filterInternalServices(
+	logger *slog.Logger,
+	upstream config.Upstream,
+	routers []*traefik.RouterInfo,
+	reasons map[string]traefik.ExclusionReason,
+) []*traefik.RouterInfo {
+	if a.config.Routers.Selector.IncludeInternal {
+		return routers
+	}
+
+	kept := make([]*traefik.RouterInfo, 0, len(routers))
+	dropped := make(map[string]struct{})
+
+	a.mu.Lock()
+	warned := a.internalWarned[upstream.Name]
+	a.mu.Unlock()
+
+	for _, router := range routers {
+		if strings.HasSuffix(router.Service, "@internal") {
+			key := router.Name + "/" + router.Service
+			dropped[key] = struct{}{}
+
+			if _, ok := warned[key]; !ok {
+				logger.Warn("skipping router with internal service",
+					"upstream", upstream.Name,
+					"name", router.Name,
+					"service", router.Service)
+			}
+
+			reasons[router.Name] = excludedInternalService
+
+			continue
+		}
+
+		kept = append(kept, router)
+	}
+
+	a.mu.Lock()
+	a.internalWarned[upstream.Name] = dropped
+	a.mu.Unlock()
+
+	return kept
+}
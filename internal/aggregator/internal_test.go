@@ -0,0 +1,92 @@
+package aggregator
+
+import (
+	"bytes"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const internalServiceRouters = `[
+	{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)", "service": "webapp@docker"},
+	{"name": "dashboard@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`traefik.example.com`" + `)", "service": "api@internal"},
+	{"name": "panel@file", "provider": "file", "status": "enabled", "rule": "Host(` + "`panel.example.com`" + `)", "service": "dashboard@internal"}
+]`
+
+func TestAggregateSkipsInternalServices(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": internalServiceRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+	var logs bytes.Buffer
+
+	agg := New(cfg, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"host1-webapp"}, slices.Collect(maps.Keys(result.HTTP.Routers)))
+	assert.Contains(t, logs.String(), "skipping router with internal service")
+	assert.Contains(t, logs.String(), "service=api@internal")
+
+	for _, router := range agg.Mappings()[0].Routers {
+		if router.Name == "webapp@docker" {
+			assert.True(t, router.Included)
+			continue
+		}
+
+		assert.False(t, router.Included, router.Name)
+		assert.Equal(t, string(excludedInternalService), router.Reason, router.Name)
+	}
+
+	assert.Equal(t, map[string]int{"internal_service": 2}, agg.Stats().Upstreams[0].Excluded)
+
+	// Warned once per router, not on every poll
+	_, err = agg.Aggregate()
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(logs.String(), "skipping router with internal service"))
+}
+
+func TestAggregateIncludeInternalKeepsInternalServices(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": internalServiceRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.Selector.IncludeInternal = true
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	assert.Len(t, result.HTTP.Routers, 3)
+}
+
+func TestAggregateForgetsRemovedInternalServiceRouters(t *testing.T) {
+	var routers atomic.Value
+
+	routers.Store(internalServiceRouters)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(routers.Load().(string)))
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	agg := New(cfg, discardLogger())
+
+	_, err := agg.Aggregate()
+	require.NoError(t, err)
+	assert.Len(t, agg.internalWarned["host1"], 2)
+
+	// Routers gone from the upstream are no longer remembered
+	routers.Store(`[{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)", "service": "webapp@docker"}]`)
+
+	_, err = agg.Aggregate()
+	require.NoError(t, err)
+	assert.Empty(t, agg.internalWarned["host1"])
+}
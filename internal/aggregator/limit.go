@@ -0,0 +1,67 @@
+package aggregator
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/chickenzord/traefik-fed/internal/traefik"
+)
+
+// excludedMaxRouters is the exclusion reason of routers dropped by an
+// upstream's max_routers
+const excludedMaxRouters traefik.ExclusionReason = "max_routers"
+
+// limitRouters warns when an upstream has more routers than the configured
+// warn threshold, and keeps only the first max_routers of them by name so that
+// the same routers are kept on every poll. Dropped routers are recorded in
+// reasons.
+func (a *Aggregator) limitRouters(
+	logger *slog.Logger,
+	upstream config.Upstream,
+	routers []*traefik.RouterInfo,
+	reasons map[string]traefik.ExclusionReason,
+) []*traefik.RouterInfo {
+	if threshold := a.config.Routers.WarnThreshold; threshold > 0 && len(routers) > threshold {
+		logger.Warn("upstream has more routers than the warn threshold",
+			"upstream", upstream.Name,
+			"routers", len(routers),
+			"warn_threshold", threshold)
+	}
+
+	limit := upstream.MaxRouters
+	if limit == 0 || len(routers) <= limit {
+		return routers
+	}
+
+	names := make([]string, len(routers))
+	for i, router := range routers {
+		names[i] = router.Name
+	}
+
+	slices.Sort(names)
+
+	dropped := make(map[string]bool, len(names)-limit)
+	for _, name := range names[limit:] {
+		dropped[name] = true
+	}
+
+	logger.Error("upstream exceeds max_routers, dropping routers beyond the limit",
+		"upstream", upstream.Name,
+		"routers", len(routers),
+		"max_routers", limit,
+		"dropped", len(dropped))
+
+	kept := make([]*traefik.RouterInfo, 0, limit)
+
+	for _, router := range routers {
+		if dropped[router.Name] {
+			reasons[router.Name] = excludedMaxRouters
+			continue
+		}
+
+		kept = append(kept, router)
+	}
+
+	return kept
+}
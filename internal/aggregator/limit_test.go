@@ -0,0 +1,87 @@
+package aggregator
+
+import (
+	"bytes"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routersJSON builds an /http/routers response with routers of the given names
+func routersJSON(names ...string) string {
+	body := "["
+
+	for i, name := range names {
+		if i > 0 {
+			body += ","
+		}
+
+		body += `{"name": "` + name + `@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`" + name + ".example.com`" + `)"}`
+	}
+
+	return body + "]"
+}
+
+func TestAggregateMaxRouters(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": routersJSON("zeta", "alpha", "mid", "beta")})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80", MaxRouters: 2})
+
+	var logs bytes.Buffer
+
+	agg := New(cfg, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"host1-alpha", "host1-beta"}, slices.Sorted(maps.Keys(result.HTTP.Routers)))
+	assert.Contains(t, logs.String(), "level=ERROR msg=\"upstream exceeds max_routers")
+	assert.Contains(t, logs.String(), "dropped=2")
+
+	for _, router := range agg.Mappings()[0].Routers {
+		if router.Included {
+			continue
+		}
+
+		assert.Contains(t, []string{"zeta@docker", "mid@docker"}, router.Name)
+		assert.Equal(t, string(excludedMaxRouters), router.Reason)
+	}
+}
+
+func TestAggregateMaxRoutersStableOrder(t *testing.T) {
+	orders := [][]string{
+		{"zeta", "alpha", "mid", "beta"},
+		{"beta", "mid", "alpha", "zeta"},
+		{"mid", "zeta", "beta", "alpha"},
+	}
+
+	for _, order := range orders {
+		upstream := mockUpstream(t, map[string]string{"/api/http/routers": routersJSON(order...)})
+		cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80", MaxRouters: 3})
+
+		result, err := New(cfg, discardLogger()).Aggregate()
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"host1-alpha", "host1-beta", "host1-mid"}, slices.Sorted(maps.Keys(result.HTTP.Routers)), order)
+	}
+}
+
+func TestAggregateRouterWarnThreshold(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": routersJSON("alpha", "beta", "mid")})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.WarnThreshold = 2
+
+	var logs bytes.Buffer
+
+	result, err := New(cfg, slog.New(slog.NewTextHandler(&logs, nil))).Aggregate()
+	require.NoError(t, err)
+
+	// Only warned, nothing is dropped
+	assert.Len(t, result.HTTP.Routers, 3)
+	assert.Contains(t, logs.String(), "upstream has more routers than the warn threshold")
+	assert.Contains(t, logs.String(), "warn_threshold=2")
+}
@@ -0,0 +1,43 @@
+package aggregator
+
+// UpstreamMapping describes how the routers of one upstream were federated
+// during the last aggregation
+type UpstreamMapping struct {
+	Upstream string          `json:"upstream"`
+	Error    string          `json:"error,omitempty"`
+	Routers  []RouterMapping `json:"routers"`
+}
+
+// RouterMapping maps a source router to its generated router and service
+type RouterMapping struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	Status   string `json:"status"`
+	Included bool   `json:"included"`
+	Reason   string `json:"reason,omitempty"` // Filter that left the router out (e.g. status, provider, internal)
+	Router   string `json:"router,omitempty"`
+	Service  string `json:"service,omitempty"`
+}
+
+// RawRouters returns the last /http/routers response body received from each
+// upstream, by upstream name. Bodies are only kept when output.http.debug is
+// enabled; upstreams not polled successfully yet are left out.
+func (a *Aggregator) RawRouters() map[string][]byte {
+	raw := make(map[string][]byte, len(a.clients))
+
+	for name, client := range a.clients {
+		if body := client.RawResponse("/http/routers"); body != nil {
+			raw[name] = body
+		}
+	}
+
+	return raw
+}
+
+// Mappings returns the router mappings from the last snapshot
+func (a *Aggregator) Mappings() []UpstreamMapping {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.mappings
+}
@@ -0,0 +1,158 @@
+package aggregator
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// mergeCandidate is a generated router that may be merged with the routers
+// of other upstreams having the same source name and rule
+type mergeCandidate struct {
+	upstream config.Upstream
+	name     string // Generated router name
+	router   *dynamic.Router
+}
+
+// mergeIdenticalRouters replaces routers generated from several upstreams with
+// the same source router name and rule by a single router named after the
+// source router. It is backed by a service of the same name balancing between
+// the upstreams by their weight; the router settings of the first upstream
+// in config order are kept. Mappings are updated to the merged names. The
+// upstream services left unused are removed by pruneOrphanedServices.
+func (a *Aggregator) mergeIdenticalRouters(result *dynamic.Configuration, mappings []UpstreamMapping) {
+	groups := make(map[string][]mergeCandidate)
+
+	var keys []string
+
+	for _, upstream := range a.config.Upstreams {
+		prefix := upstream.Name + "-"
+
+		for _, name := range slices.Sorted(maps.Keys(result.HTTP.Routers)) {
+			baseName, ok := strings.CutPrefix(name, prefix)
+			if !ok || !a.ownsRouter(upstream, name, mappings) {
+				continue
+			}
+
+			router := result.HTTP.Routers[name]
+			key := baseName + "\x00" + router.Rule
+
+			if _, exists := groups[key]; !exists {
+				keys = append(keys, key)
+			}
+
+			groups[key] = append(groups[key], mergeCandidate{upstream: upstream, name: name, router: router})
+		}
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+
+		mergedName, _, _ := strings.Cut(key, "\x00")
+		serviceName := a.affixService(mergedName)
+
+		_, routerExists := result.HTTP.Routers[mergedName]
+		_, serviceExists := result.HTTP.Services[serviceName]
+
+		if routerExists || serviceExists {
+			a.logger.Warn("not merging identical routers, name already in use",
+				"router", mergedName)
+
+			continue
+		}
+
+		service, ok := a.mergedService(group)
+		if !ok {
+			continue
+		}
+
+		merged := *group[0].router
+		merged.Service = serviceName
+
+		for _, candidate := range group {
+			delete(result.HTTP.Routers, candidate.name)
+			renameMapping(mappings, candidate.upstream.Name, candidate.name, mergedName, serviceName)
+		}
+
+		result.HTTP.Routers[mergedName] = &merged
+		result.HTTP.Services[serviceName] = service
+
+		a.logger.Debug("merged identical routers",
+			"router", mergedName,
+			"upstreams", len(group))
+	}
+}
+
+// ownsRouter reports whether the generated router comes from the upstream,
+// guarding against upstream names that prefix each other (e.g. "a" and "a-b")
+func (a *Aggregator) ownsRouter(upstream config.Upstream, name string, mappings []UpstreamMapping) bool {
+	for _, mapping := range mappings {
+		if mapping.Upstream != upstream.Name {
+			continue
+		}
+
+		for _, router := range mapping.Routers {
+			if router.Router == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// mergedService builds the load balancer of a merged router, with one server
+// per upstream. Weights are only set when any upstream of the group has one;
+// upstreams without a weight then count as 1.
+func (a *Aggregator) mergedService(group []mergeCandidate) (*dynamic.Service, bool) {
+	weighted := slices.ContainsFunc(group, func(c mergeCandidate) bool { return c.upstream.Weight > 0 })
+
+	servers := make([]dynamic.Server, 0, len(group))
+
+	for _, candidate := range group {
+		targetURL, err := candidate.upstream.TargetURL()
+		if err != nil {
+			return nil, false
+		}
+
+		server := dynamic.Server{URL: targetURL}
+
+		if weighted {
+			weight := max(candidate.upstream.Weight, 1)
+			server.Weight = &weight
+		}
+
+		servers = append(servers, server)
+	}
+
+	return &dynamic.Service{
+		LoadBalancer: &dynamic.ServersLoadBalancer{
+			Servers:        servers,
+			Sticky:         a.sticky(),
+			PassHostHeader: a.passHostHeader(),
+			HealthCheck:    a.healthCheck(),
+		},
+	}, true
+}
+
+// renameMapping points the mapping of a generated router to its merged router and service
+func renameMapping(mappings []UpstreamMapping, upstream, from, router, service string) {
+	for i := range mappings {
+		if mappings[i].Upstream != upstream {
+			continue
+		}
+
+		for j := range mappings[i].Routers {
+			if mappings[i].Routers[j].Router == from {
+				mappings[i].Routers[j].Router = router
+				mappings[i].Routers[j].Service = service
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+const mergeRouters = `[
+	{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)"},
+	{"name": "admin@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`admin.example.com`" + `)"}
+]`
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func weights(servers []dynamic.Server) map[string]*int {
+	result := make(map[string]*int, len(servers))
+	for _, server := range servers {
+		result[server.URL] = server.Weight
+	}
+
+	return result
+}
+
+func TestAggregateMergeIdenticalWeighted(t *testing.T) {
+	host1 := mockUpstream(t, map[string]string{"/api/http/routers": mergeRouters})
+	host2 := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: host1.URL, ServerURL: "http://192.168.1.10:80", Weight: 80},
+		config.Upstream{Name: "host2", AdminURL: host2.URL, ServerURL: "http://192.168.1.11:80", Weight: 20},
+	)
+	cfg.Routers.MergeIdentical = true
+
+	agg := New(cfg, discardLogger())
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	// The webapp routers are merged, admin only exists on host1
+	assert.NotContains(t, result.HTTP.Routers, "host1-webapp")
+	assert.NotContains(t, result.HTTP.Routers, "host2-webapp")
+	require.Contains(t, result.HTTP.Routers, "webapp")
+	require.Contains(t, result.HTTP.Routers, "host1-admin")
+	assert.Equal(t, "webapp", result.HTTP.Routers["webapp"].Service)
+	assert.Equal(t, "host1-traefik", result.HTTP.Routers["host1-admin"].Service)
+
+	// host2 has no router of its own left
+	assert.NotContains(t, result.HTTP.Services, "host2-traefik")
+
+	service := result.HTTP.Services["webapp"]
+	require.NotNil(t, service)
+	require.NotNil(t, service.LoadBalancer)
+	assert.Equal(t, map[string]*int{
+		"http://192.168.1.10:80": intPtr(80),
+		"http://192.168.1.11:80": intPtr(20),
+	}, weights(service.LoadBalancer.Servers))
+
+	mappings := agg.Mappings()
+	require.Len(t, mappings, 2)
+	assert.Equal(t, "webapp", mappings[1].Routers[0].Router)
+	assert.Equal(t, "webapp", mappings[1].Routers[0].Service)
+}
+
+func TestAggregateMergeIdenticalPartialWeights(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80", Weight: 3},
+		config.Upstream{Name: "host2", AdminURL: upstream.URL, ServerURL: "http://192.168.1.11:80"},
+	)
+	cfg.Routers.MergeIdentical = true
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	service := result.HTTP.Services["webapp"]
+	require.NotNil(t, service)
+	assert.Equal(t, map[string]*int{
+		"http://192.168.1.10:80": intPtr(3),
+		"http://192.168.1.11:80": intPtr(1),
+	}, weights(service.LoadBalancer.Servers))
+}
+
+func TestAggregateMergeIdenticalUnweighted(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "host2", AdminURL: upstream.URL, ServerURL: "http://192.168.1.11:80"},
+	)
+	cfg.Routers.MergeIdentical = true
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	service := result.HTTP.Services["webapp"]
+	require.NotNil(t, service)
+	assert.Equal(t, map[string]*int{
+		"http://192.168.1.10:80": nil,
+		"http://192.168.1.11:80": nil,
+	}, weights(service.LoadBalancer.Servers))
+}
+
+func TestAggregateMergeIdenticalHealthCheck(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "host2", AdminURL: upstream.URL, ServerURL: "http://192.168.1.11:80"},
+	)
+	cfg.Routers.MergeIdentical = true
+	cfg.Routers.Defaults.HealthCheck = &config.ServiceHealthCheck{Path: "/ping"}
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	// Checks every upstream server of the merged service
+	service := result.HTTP.Services["webapp"]
+	require.NotNil(t, service)
+	require.NotNil(t, service.LoadBalancer.HealthCheck)
+	assert.Equal(t, "/ping", service.LoadBalancer.HealthCheck.Path)
+	assert.Zero(t, service.LoadBalancer.HealthCheck.Interval, "Traefik's default interval")
+}
+
+func TestAggregateWithoutMergeKeepsRoutersApart(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80", Weight: 80},
+		config.Upstream{Name: "host2", AdminURL: upstream.URL, ServerURL: "http://192.168.1.11:80", Weight: 20},
+	)
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	assert.Contains(t, result.HTTP.Routers, "host1-webapp")
+	assert.Contains(t, result.HTTP.Routers, "host2-webapp")
+	assert.NotContains(t, result.HTTP.Services, "webapp")
+}
@@ -0,0 +1,78 @@
+package aggregator
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/chickenzord/traefik-fed/internal/traefik"
+)
+
+// excludedNameCollision is the exclusion reason of routers whose generated
+// name is already taken by another router of the same upstream
+const excludedNameCollision traefik.ExclusionReason = "name_collision"
+
+// normalizeName lowercases name and replaces runs of characters other than
+// letters, digits, "-" and "_" by a single "-"
+func normalizeName(name string) string {
+	var b strings.Builder
+
+	dash := false
+
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			b.WriteRune(r)
+
+			dash = false
+
+			continue
+		}
+
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+
+		dash = true
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// filterNameCollisions drops routers whose generated name is already used by
+// an earlier router of the upstream, which happens when normalize_names maps
+// different source names to the same name. Dropped routers are recorded in
+// reasons.
+func (a *Aggregator) filterNameCollisions(
+	logger *slog.Logger,
+	upstream config.Upstream,
+	routers []*traefik.RouterInfo,
+	reasons map[string]traefik.ExclusionReason,
+) []*traefik.RouterInfo {
+	if !a.config.Routers.NormalizeNames {
+		return routers
+	}
+
+	kept := make([]*traefik.RouterInfo, 0, len(routers))
+	owners := make(map[string]string, len(routers))
+
+	for _, router := range routers {
+		name := a.routerName(upstream, router.Name)
+
+		if owner, taken := owners[name]; taken {
+			logger.Warn("skipping router with colliding normalized name",
+				"upstream", upstream.Name,
+				"name", router.Name,
+				"router", name,
+				"kept", owner)
+
+			reasons[router.Name] = excludedNameCollision
+
+			continue
+		}
+
+		owners[name] = router.Name
+		kept = append(kept, router)
+	}
+
+	return kept
+}
@@ -0,0 +1,63 @@
+package aggregator
+
+import (
+	"bytes"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"webapp", "webapp"},
+		{"WebApp", "webapp"},
+		{"My App.v2", "my-app-v2"},
+		{"api__internal", "api__internal"},
+		{"--Admin//Panel--", "admin-panel"},
+		{"café", "caf"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, normalizeName(tt.name), tt.name)
+	}
+}
+
+const mixedCaseRouters = `[
+	{"name": "My.App@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)"},
+	{"name": "WebApp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`web.example.com`" + `)"},
+	{"name": "webapp@file", "provider": "file", "status": "enabled", "rule": "Host(` + "`web2.example.com`" + `)"}
+]`
+
+func TestAggregateNormalizeNames(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": mixedCaseRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.NormalizeNames = true
+
+	var logs bytes.Buffer
+
+	agg := New(cfg, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"host1-my-app", "host1-webapp"}, slices.Sorted(maps.Keys(result.HTTP.Routers)))
+
+	// The first router keeps the colliding name
+	assert.Equal(t, "Host(`web.example.com`)", result.HTTP.Routers["host1-webapp"].Rule)
+	assert.Contains(t, logs.String(), "skipping router with colliding normalized name")
+
+	mapping := agg.Mappings()[0]
+	require.Len(t, mapping.Routers, 3)
+	assert.Equal(t, "host1-my-app", mapping.Routers[0].Router)
+	assert.True(t, mapping.Routers[1].Included)
+	assert.False(t, mapping.Routers[2].Included)
+	assert.Equal(t, string(excludedNameCollision), mapping.Routers[2].Reason)
+}
@@ -0,0 +1,68 @@
+package aggregator
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/chickenzord/traefik-fed/internal/traefik"
+)
+
+// pathMiddlewareMarkers are name fragments of middlewares commonly changing
+// the request path, such as stripprefix, addprefix or replacepath instances
+var pathMiddlewareMarkers = []string{"strip", "addprefix", "add-prefix", "replacepath", "replace-path"}
+
+// pathMiddlewares returns the middlewares whose name suggests they modify the
+// request path. The provider suffix is ignored.
+func pathMiddlewares(middlewares []string) []string {
+	var matched []string
+
+	for _, middleware := range middlewares {
+		name, _, _ := strings.Cut(strings.ToLower(middleware), "@")
+
+		for _, marker := range pathMiddlewareMarkers {
+			if strings.Contains(name, marker) {
+				matched = append(matched, middleware)
+				break
+			}
+		}
+	}
+
+	return matched
+}
+
+// warnPathMiddlewares warns once per source router relying on path-modifying
+// middlewares. Upstream middlewares are not copied; they keep being applied by
+// the upstream Traefik, which only works while requests reach it with their
+// original path so the upstream rule still matches.
+func (a *Aggregator) warnPathMiddlewares(logger *slog.Logger, upstream config.Upstream, router *traefik.RouterInfo) {
+	middlewares := pathMiddlewares(router.Middlewares)
+	if len(middlewares) == 0 {
+		return
+	}
+
+	key := upstream.Name + "/" + router.Name
+
+	a.mu.Lock()
+	_, warned := a.pathWarned[key]
+	a.pathWarned[key] = struct{}{}
+	a.mu.Unlock()
+
+	if warned {
+		return
+	}
+
+	args := []any{
+		"upstream", upstream.Name,
+		"name", router.Name,
+		"rule", router.Rule,
+		"middlewares", middlewares,
+	}
+
+	if defaults := pathMiddlewares(a.config.Routers.Defaults.Middlewares); len(defaults) > 0 {
+		args = append(args, "default_middlewares", defaults)
+	}
+
+	logger.Warn("router relies on path-modifying upstream middlewares; they only apply while the federated router forwards the original path",
+		args...)
+}
@@ -0,0 +1,62 @@
+package aggregator
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathMiddlewares(t *testing.T) {
+	tests := []struct {
+		middlewares []string
+		expected    []string
+	}{
+		{middlewares: nil, expected: nil},
+		{middlewares: []string{"compress@file", "auth@docker"}, expected: nil},
+		{middlewares: []string{"api-stripprefix@docker", "compress@file"}, expected: []string{"api-stripprefix@docker"}},
+		{middlewares: []string{"Strip-API@kubernetescrd"}, expected: []string{"Strip-API@kubernetescrd"}},
+		{middlewares: []string{"add-prefix-v1@file", "legacy-replacepath"}, expected: []string{"add-prefix-v1@file", "legacy-replacepath"}},
+		// Only the name is matched, not the provider
+		{middlewares: []string{"auth@stripped-provider"}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.middlewares, ","), func(t *testing.T) {
+			assert.Equal(t, tt.expected, pathMiddlewares(tt.middlewares))
+		})
+	}
+}
+
+func TestAggregateWarnsAboutPathMiddlewares(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": `[
+		{"name": "api@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `) && PathPrefix(` + "`/api`" + `)", "middlewares": ["api-stripprefix@docker"]},
+		{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)", "middlewares": ["compress@docker"]}
+	]`})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+	var logs bytes.Buffer
+
+	agg := New(cfg, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	// The router is still federated; the upstream applies the middleware
+	assert.Contains(t, result.HTTP.Routers, "host1-api")
+	assert.Empty(t, result.HTTP.Routers["host1-api"].Middlewares)
+
+	assert.Contains(t, logs.String(), "router relies on path-modifying upstream middlewares")
+	assert.Contains(t, logs.String(), "name=api@docker")
+	assert.Contains(t, logs.String(), "middlewares=[api-stripprefix@docker]")
+	assert.NotContains(t, logs.String(), "name=webapp@docker")
+
+	// Warned once per router, not on every poll
+	_, err = agg.Aggregate()
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(logs.String(), "router relies on path-modifying upstream middlewares"))
+}
@@ -0,0 +1,55 @@
+package aggregator
+
+import (
+	"maps"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// pruneOrphanedServices removes the HTTP, TCP and UDP services no router of
+// the same protocol refers to, e.g. upstream services whose routers were all
+// merged, and returns how many were removed
+func pruneOrphanedServices(cfg *dynamic.Configuration) int {
+	pruned := 0
+
+	if cfg.HTTP != nil {
+		used := make(map[string]bool, len(cfg.HTTP.Routers))
+		for _, router := range cfg.HTTP.Routers {
+			used[router.Service] = true
+		}
+
+		pruned += deleteUnused(cfg.HTTP.Services, used)
+	}
+
+	if cfg.TCP != nil {
+		used := make(map[string]bool, len(cfg.TCP.Routers))
+		for _, router := range cfg.TCP.Routers {
+			used[router.Service] = true
+		}
+
+		pruned += deleteUnused(cfg.TCP.Services, used)
+	}
+
+	if cfg.UDP != nil {
+		used := make(map[string]bool, len(cfg.UDP.Routers))
+		for _, router := range cfg.UDP.Routers {
+			used[router.Service] = true
+		}
+
+		pruned += deleteUnused(cfg.UDP.Services, used)
+	}
+
+	return pruned
+}
+
+// deleteUnused deletes the services missing from used and returns how many
+// were deleted
+func deleteUnused[S any](services map[string]S, used map[string]bool) int {
+	before := len(services)
+
+	maps.DeleteFunc(services, func(name string, _ S) bool {
+		return !used[name]
+	})
+
+	return before - len(services)
+}
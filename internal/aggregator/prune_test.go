@@ -0,0 +1,60 @@
+package aggregator
+
+import (
+	"bytes"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestPruneOrphanedServices(t *testing.T) {
+	cfg := newConfiguration(true, true)
+
+	cfg.HTTP.Routers["host1-webapp"] = &dynamic.Router{Service: "host1-traefik"}
+	cfg.HTTP.Services["host1-traefik"] = &dynamic.Service{}
+	cfg.HTTP.Services["host2-traefik"] = &dynamic.Service{}
+
+	cfg.TCP.Routers["host1-postgres"] = &dynamic.TCPRouter{Service: "host1-tcp-postgres"}
+	cfg.TCP.Services["host1-tcp-postgres"] = &dynamic.TCPService{}
+	cfg.TCP.Services["host1-tcp-redis"] = &dynamic.TCPService{}
+
+	// Services are matched per protocol
+	cfg.UDP.Services["host1-traefik"] = &dynamic.UDPService{}
+
+	assert.Equal(t, 3, pruneOrphanedServices(cfg))
+
+	assert.Equal(t, []string{"host1-traefik"}, slices.Sorted(maps.Keys(cfg.HTTP.Services)))
+	assert.Equal(t, []string{"host1-tcp-postgres"}, slices.Sorted(maps.Keys(cfg.TCP.Services)))
+	assert.Empty(t, cfg.UDP.Services)
+
+	assert.Zero(t, pruneOrphanedServices(cfg))
+}
+
+func TestAggregatePrunesMergedUpstreamServices(t *testing.T) {
+	upstream1 := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	upstream2 := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(
+		config.Upstream{Name: "host1", AdminURL: upstream1.URL, ServerURL: "http://192.168.1.10:80"},
+		config.Upstream{Name: "host2", AdminURL: upstream2.URL, ServerURL: "http://192.168.1.11:80"},
+	)
+	cfg.Routers.MergeIdentical = true
+
+	var logs bytes.Buffer
+
+	agg := New(cfg, slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	// The upstream services of the merged routers are orphans
+	assert.Equal(t, []string{"webapp"}, slices.Sorted(maps.Keys(result.HTTP.Services)))
+	assert.Equal(t, 1, agg.Stats().Services)
+	assert.Contains(t, logs.String(), "pruned services without routers")
+	assert.Contains(t, logs.String(), "services=2")
+}
@@ -0,0 +1,53 @@
+package aggregator
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// hostMatcherPattern matches Host and HostSNI matchers with their arguments
+	hostMatcherPattern = regexp.MustCompile("\\b(Host|HostSNI)\\(([^)]*)\\)")
+	// quotedPattern matches a backtick or double-quoted matcher argument
+	quotedPattern = regexp.MustCompile("`[^`]*`|\"[^\"]*\"")
+)
+
+// rewriteRuleHosts substitutes hosts in the Host and HostSNI matchers of a rule.
+// Keys match a host exactly, or, when starting with ".", replace a domain suffix
+// (".internal.lan" -> ".example.com" turns app.internal.lan into app.example.com).
+// Other matchers, such as HostRegexp or Path, are left untouched.
+func rewriteRuleHosts(rule string, rewrites map[string]string) string {
+	if len(rewrites) == 0 {
+		return rule
+	}
+
+	return hostMatcherPattern.ReplaceAllStringFunc(rule, func(matcher string) string {
+		return quotedPattern.ReplaceAllStringFunc(matcher, func(quoted string) string {
+			quote := quoted[:1]
+			host := quoted[1 : len(quoted)-1]
+
+			return quote + rewriteHost(host, rewrites) + quote
+		})
+	})
+}
+
+// rewriteHost applies the exact or longest matching suffix rewrite to host
+func rewriteHost(host string, rewrites map[string]string) string {
+	if replacement, ok := rewrites[host]; ok {
+		return replacement
+	}
+
+	longest := ""
+
+	for from := range rewrites {
+		if strings.HasPrefix(from, ".") && strings.HasSuffix(host, from) && len(from) > len(longest) {
+			longest = from
+		}
+	}
+
+	if longest == "" {
+		return host
+	}
+
+	return strings.TrimSuffix(host, longest) + rewrites[longest]
+}
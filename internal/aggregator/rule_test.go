@@ -0,0 +1,77 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteRuleHosts(t *testing.T) {
+	rewrites := map[string]string{
+		"app.internal.lan": "app.example.com",
+		".internal.lan":    ".example.com",
+		".corp.internal":   ".corp.example.com",
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		expected string
+	}{
+		{
+			name:     "single host",
+			rule:     "Host(`app.internal.lan`)",
+			expected: "Host(`app.example.com`)",
+		},
+		{
+			name:     "domain suffix",
+			rule:     "Host(`grafana.internal.lan`) && PathPrefix(`/internal.lan`)",
+			expected: "Host(`grafana.example.com`) && PathPrefix(`/internal.lan`)",
+		},
+		{
+			name:     "multiple hosts",
+			rule:     "Host(`app.internal.lan`) || Host(`api.corp.internal`) || Host(`static.cdn.net`)",
+			expected: "Host(`app.example.com`) || Host(`api.corp.example.com`) || Host(`static.cdn.net`)",
+		},
+		{
+			name:     "v2 multi-host matcher",
+			rule:     "Host(`a.internal.lan`, `b.internal.lan`)",
+			expected: "Host(`a.example.com`, `b.example.com`)",
+		},
+		{
+			name:     "double quotes",
+			rule:     `Host("app.internal.lan")`,
+			expected: `Host("app.example.com")`,
+		},
+		{
+			name:     "host regexp is untouched",
+			rule:     "HostRegexp(`.+\\.internal\\.lan`)",
+			expected: "HostRegexp(`.+\\.internal\\.lan`)",
+		},
+		{
+			name:     "bare suffix does not match",
+			rule:     "Host(`internal.lan`)",
+			expected: "Host(`internal.lan`)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, rewriteRuleHosts(tt.rule, rewrites))
+		})
+	}
+}
+
+func TestAggregateRewritesRuleHosts(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.RuleRewrite = map[string]string{"app.example.com": "app.public.example.org"}
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	require.Contains(t, result.HTTP.Routers, "host1-webapp")
+	assert.Equal(t, "Host(`app.public.example.org`)", result.HTTP.Routers["host1-webapp"].Rule)
+}
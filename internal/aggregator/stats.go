@@ -0,0 +1,105 @@
+package aggregator
+
+import (
+	"time"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// Stats describes the last completed aggregation
+type Stats struct {
+	LastPoll   time.Time       `json:"last_poll"`   // Completion time of the most recent upstream poll
+	DurationMs float64         `json:"duration_ms"` // Duration of the most recent upstream poll
+	Routers    int             `json:"routers"`
+	Services   int             `json:"services"`
+	Upstreams  []UpstreamStats `json:"upstreams"`
+}
+
+// UpstreamStats describes the last poll of a single upstream
+type UpstreamStats struct {
+	Upstream    string    `json:"upstream"`
+	Version     string    `json:"version,omitempty"` // Detected Traefik version, empty until known
+	LastPoll    time.Time `json:"last_poll"`
+	LastSuccess time.Time `json:"last_success"` // Completion time of the last successful poll (zero: never)
+	DurationMs  float64   `json:"duration_ms"`
+	Routers     int       `json:"routers"`
+	Services    int       `json:"services"`
+	Error       string    `json:"error,omitempty"`
+
+	Excluded map[string]int `json:"excluded,omitempty"` // Source routers left out per filter reason
+
+	Circuit *CircuitStats `json:"circuit,omitempty"` // Circuit breaker state, when enabled
+}
+
+// Stats returns the statistics from the last snapshot
+func (a *Aggregator) Stats() Stats {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.stats
+}
+
+// upstreamStats builds the statistics of a single upstream state
+func upstreamStats(name string, state *upstreamState, mapping UpstreamMapping, version string) UpstreamStats {
+	stats := UpstreamStats{
+		Upstream:    name,
+		Version:     version,
+		LastPoll:    state.polledAt,
+		DurationMs:  durationMs(state.duration),
+		Error:       mapping.Error,
+		LastSuccess: state.lastSuccess,
+		Excluded:    excludedCounts(mapping),
+	}
+
+	// Upstreams left out of the snapshot contribute nothing
+	if state.config != nil && mapping.Error == "" {
+		stats.Routers, stats.Services = countConfiguration(state.config)
+	}
+
+	return stats
+}
+
+// excludedCounts counts the source routers of a mapping left out per reason,
+// nil when none were
+func excludedCounts(mapping UpstreamMapping) map[string]int {
+	var counts map[string]int
+
+	for _, router := range mapping.Routers {
+		if router.Reason == "" {
+			continue
+		}
+
+		if counts == nil {
+			counts = make(map[string]int)
+		}
+
+		counts[router.Reason]++
+	}
+
+	return counts
+}
+
+// countConfiguration counts the HTTP and UDP routers and services of a configuration
+func countConfiguration(cfg *dynamic.Configuration) (routers, services int) {
+	if cfg.HTTP != nil {
+		routers += len(cfg.HTTP.Routers)
+		services += len(cfg.HTTP.Services)
+	}
+
+	if cfg.TCP != nil {
+		routers += len(cfg.TCP.Routers)
+		services += len(cfg.TCP.Services)
+	}
+
+	if cfg.UDP != nil {
+		routers += len(cfg.UDP.Routers)
+		services += len(cfg.UDP.Services)
+	}
+
+	return routers, services
+}
+
+// durationMs converts a duration to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,48 @@
+package aggregator
+
+import (
+	"log/slog"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/chickenzord/traefik-fed/internal/traefik"
+)
+
+// excludedRuleSyntax is the exclusion reason of routers dropped by target_syntax
+const excludedRuleSyntax traefik.ExclusionReason = "rule_syntax"
+
+// filterRuleSyntax drops routers whose rule syntax differs from the configured
+// target syntax, since their rules would not parse the same way on the
+// federated Traefik. Routers without a reported syntax are kept. Dropped
+// routers are recorded in reasons.
+func (a *Aggregator) filterRuleSyntax(
+	logger *slog.Logger,
+	upstream config.Upstream,
+	routers []*traefik.RouterInfo,
+	reasons map[string]traefik.ExclusionReason,
+) []*traefik.RouterInfo {
+	target := a.config.Routers.TargetSyntax
+	if target == "" {
+		return routers
+	}
+
+	kept := make([]*traefik.RouterInfo, 0, len(routers))
+
+	for _, router := range routers {
+		if router.RuleSyntax != "" && router.RuleSyntax != target {
+			logger.Warn("skipping router with unsupported rule syntax",
+				"upstream", upstream.Name,
+				"name", router.Name,
+				"rule", router.Rule,
+				"rule_syntax", router.RuleSyntax,
+				"target_syntax", target)
+
+			reasons[router.Name] = excludedRuleSyntax
+
+			continue
+		}
+
+		kept = append(kept, router)
+	}
+
+	return kept
+}
@@ -0,0 +1,52 @@
+package aggregator
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mixedSyntaxRouters = `[
+	{"name": "legacy@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`legacy.example.com`" + `) && HeadersRegexp(` + "`X-Id`, `[0-9]+`" + `)", "ruleSyntax": "v2"},
+	{"name": "modern@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)", "ruleSyntax": "v3"},
+	{"name": "unknown@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`other.example.com`" + `)"}
+]`
+
+func TestAggregateSkipsUnsupportedRuleSyntax(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": mixedSyntaxRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.TargetSyntax = "v3"
+
+	var logs bytes.Buffer
+
+	agg := New(cfg, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	result, err := agg.Aggregate()
+	require.NoError(t, err)
+
+	assert.NotContains(t, result.HTTP.Routers, "host1-legacy")
+	assert.Contains(t, result.HTTP.Routers, "host1-modern")
+	assert.Contains(t, result.HTTP.Routers, "host1-unknown")
+
+	assert.Contains(t, logs.String(), "skipping router with unsupported rule syntax")
+	assert.Contains(t, logs.String(), "name=legacy@docker")
+
+	mapping := agg.Mappings()[0]
+	for _, router := range mapping.Routers {
+		assert.Equal(t, router.Name != "legacy@docker", router.Included, router.Name)
+	}
+}
+
+func TestAggregateWithoutTargetSyntaxKeepsAll(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": mixedSyntaxRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	assert.Len(t, result.HTTP.Routers, 3)
+}
@@ -0,0 +1,118 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/chickenzord/traefik-fed/internal/traefik"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// aggregateUpstreamTCP aggregates TCP routers from a single upstream.
+//
+// Like UDP routers, generated routers keep the upstream entrypoint names unless
+// defaults are set, and each upstream entrypoint gets its own service pointing
+// to the (rewritten) ServerURL host on that entrypoint's port. Rules are
+// copied with their hosts rewritten; the upstream Traefik keeps applying the
+// router middlewares.
+func (a *Aggregator) aggregateUpstreamTCP(ctx context.Context, logger *slog.Logger, upstream config.Upstream, tcpConfig *dynamic.TCPConfiguration) error {
+	client := a.clients[upstream.Name]
+
+	routers, err := client.GetTCPRoutersContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch TCP routers: %w", err)
+	}
+
+	filteredRouters := traefik.FilterTCPRouters(routers, selectorFilter(&a.config.Routers.TCP.Selector))
+
+	logger.Info("fetched TCP routers from upstream",
+		"upstream", upstream.Name,
+		"total", len(routers),
+		"filtered", len(filteredRouters))
+
+	if len(filteredRouters) == 0 {
+		return nil
+	}
+
+	ports, err := entryPointPorts(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	host, err := serverHost(upstream)
+	if err != nil {
+		return err
+	}
+
+	defaults := a.config.Routers.TCP.Defaults
+
+	for _, router := range filteredRouters {
+		if len(router.EntryPoints) == 0 {
+			logger.Warn("skipping TCP router without entrypoints",
+				"upstream", upstream.Name,
+				"name", router.Name)
+
+			continue
+		}
+
+		entryPoint := router.EntryPoints[0]
+
+		port := ports[entryPoint]
+		if port == "" {
+			logger.Warn("skipping TCP router with unknown entrypoint port",
+				"upstream", upstream.Name,
+				"name", router.Name,
+				"entrypoint", entryPoint)
+
+			continue
+		}
+
+		// The service points at a single port, so only the first entrypoint is federated
+		if len(router.EntryPoints) > 1 {
+			logger.Warn("TCP router has multiple entrypoints, only the first is federated",
+				"upstream", upstream.Name,
+				"name", router.Name,
+				"entrypoint", entryPoint,
+				"dropped", router.EntryPoints[1:])
+		}
+
+		serviceName := a.serviceName(upstream, "tcp-"+entryPoint)
+		if _, exists := tcpConfig.Services[serviceName]; !exists {
+			tcpConfig.Services[serviceName] = &dynamic.TCPService{
+				LoadBalancer: &dynamic.TCPServersLoadBalancer{
+					Servers: []dynamic.TCPServer{
+						{
+							Address: net.JoinHostPort(host, port),
+						},
+					},
+				},
+			}
+		}
+
+		newRouter := &dynamic.TCPRouter{
+			EntryPoints: []string{entryPoint},
+			Rule:        rewriteRuleHosts(router.Rule, a.config.Routers.RuleRewrite),
+			Service:     serviceName,
+			TLS:         router.TLS,
+		}
+
+		if len(defaults.EntryPoints) > 0 {
+			newRouter.EntryPoints = defaults.EntryPoints
+		}
+
+		if defaults.TLS != nil {
+			newRouter.TLS = defaults.TLS
+		}
+
+		if a.config.Routers.ShouldPreservePriority() {
+			newRouter.Priority = router.Priority
+		}
+
+		tcpConfig.Routers[a.routerName(upstream, router.Name)] = newRouter
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package aggregator
+
+import (
+	"bytes"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// tcpUpstream serves HTTP routers from docker and TCP routers from docker and file
+func tcpUpstream(t *testing.T) string {
+	t.Helper()
+
+	upstream := mockUpstream(t, map[string]string{
+		"/api/http/routers": webappRouters,
+		"/api/tcp/routers": `[
+			{"name": "postgres@file", "provider": "file", "status": "enabled", "entryPoints": ["postgres"], "rule": "HostSNI(` + "`db.internal.lan`" + `)", "priority": 10, "tls": {"passthrough": true}},
+			{"name": "redis@docker", "provider": "docker", "status": "enabled", "entryPoints": ["redis"], "rule": "HostSNI(` + "`*`" + `)"},
+			{"name": "mqtt@file", "provider": "file", "status": "disabled", "entryPoints": ["mqtt"], "rule": "HostSNI(` + "`*`" + `)"}
+		]`,
+		"/api/entrypoints": `[
+			{"name": "web", "address": ":80"},
+			{"name": "postgres", "address": ":5432"},
+			{"name": "redis", "address": ":6379/tcp"},
+			{"name": "mqtt", "address": ":1883"}
+		]`,
+	})
+
+	return upstream.URL
+}
+
+func TestAggregateTCPRouters(t *testing.T) {
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: tcpUpstream(t), ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.TCP.Enabled = true
+	cfg.Routers.RuleRewrite = map[string]string{".internal.lan": ".example.com"}
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+	require.NotNil(t, result.TCP)
+
+	assert.Equal(t, []string{"host1-postgres", "host1-redis"}, slices.Sorted(maps.Keys(result.TCP.Routers)))
+
+	router := result.TCP.Routers["host1-postgres"]
+	assert.Equal(t, []string{"postgres"}, router.EntryPoints)
+	assert.Equal(t, "HostSNI(`db.example.com`)", router.Rule)
+	assert.Equal(t, 10, router.Priority)
+	assert.Equal(t, &dynamic.RouterTCPTLSConfig{Passthrough: true}, router.TLS)
+	assert.Equal(t, "host1-tcp-postgres", router.Service)
+	assert.Equal(t, "192.168.1.10:5432", result.TCP.Services["host1-tcp-postgres"].LoadBalancer.Servers[0].Address)
+	assert.Equal(t, "192.168.1.10:6379", result.TCP.Services["host1-tcp-redis"].LoadBalancer.Servers[0].Address)
+
+	// HTTP aggregation is unaffected
+	assert.Contains(t, result.HTTP.Routers, "host1-webapp")
+	assert.Nil(t, result.UDP)
+}
+
+func TestAggregateTCPSelector(t *testing.T) {
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: tcpUpstream(t), ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.TCP.Enabled = true
+
+	// The HTTP selector keeps docker routers only, the TCP selector file ones
+	cfg.Routers.Selector.Provider = "docker"
+	cfg.Routers.TCP.Selector.Provider = "file"
+	cfg.Routers.TCP.Selector.Status = ""
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	assert.Contains(t, result.HTTP.Routers, "host1-webapp")
+	assert.Equal(t, []string{"host1-mqtt", "host1-postgres"}, slices.Sorted(maps.Keys(result.TCP.Routers)))
+}
+
+func TestAggregateTCPDefaults(t *testing.T) {
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: tcpUpstream(t), ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.TCP.Enabled = true
+	cfg.Routers.TCP.Defaults = config.TCPRouterDefaults{
+		EntryPoints: []string{"tcp-edge"},
+		TLS:         &dynamic.RouterTCPTLSConfig{CertResolver: "letsencrypt"},
+	}
+
+	// HTTP defaults do not apply to TCP routers
+	cfg.Routers.Defaults.EntryPoints = []string{"websecure"}
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	for name, router := range result.TCP.Routers {
+		assert.Equal(t, []string{"tcp-edge"}, router.EntryPoints, name)
+		assert.Equal(t, "letsencrypt", router.TLS.CertResolver, name)
+	}
+
+	// Services still target the upstream entrypoint ports
+	assert.Equal(t, "192.168.1.10:5432", result.TCP.Services["host1-tcp-postgres"].LoadBalancer.Servers[0].Address)
+}
+
+func TestAggregateTCPMultipleEntryPoints(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{
+		"/api/http/routers": webappRouters,
+		"/api/tcp/routers": `[
+			{"name": "postgres@file", "provider": "file", "status": "enabled", "entryPoints": ["postgres", "postgres-replica"], "rule": "HostSNI(` + "`*`" + `)"}
+		]`,
+		"/api/entrypoints": `[
+			{"name": "postgres", "address": ":5432"},
+			{"name": "postgres-replica", "address": ":5433"}
+		]`,
+	})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.TCP.Enabled = true
+
+	var logs bytes.Buffer
+
+	result, err := New(cfg, slog.New(slog.NewTextHandler(&logs, nil))).Aggregate()
+	require.NoError(t, err)
+
+	// Only the first entrypoint is federated, the others are reported
+	router := result.TCP.Routers["host1-postgres"]
+	require.NotNil(t, router)
+	assert.Equal(t, []string{"postgres"}, router.EntryPoints)
+	assert.Equal(t, []string{"host1-tcp-postgres"}, slices.Collect(maps.Keys(result.TCP.Services)))
+	assert.Contains(t, logs.String(), "TCP router has multiple entrypoints")
+	assert.Contains(t, logs.String(), "dropped=[postgres-replica]")
+}
@@ -0,0 +1,62 @@
+package aggregator
+
+import (
+	"github.com/chickenzord/traefik-fed/internal/config"
+)
+
+// ruleTransform changes the rule of a generated HTTP router
+type ruleTransform interface {
+	apply(rule string) string
+}
+
+// ruleTransformTypes creates the transform of each routers.rule_transforms
+// type. New types are added here and to the validation of config.RuleTransform.
+var ruleTransformTypes = map[string]func(config.RuleTransform) ruleTransform{
+	config.RuleTransformHostRewrite: func(c config.RuleTransform) ruleTransform { return hostRewrite(c.Hosts) },
+	config.RuleTransformPathPrefix:  func(c config.RuleTransform) ruleTransform { return pathPrefix(c.Prefix) },
+}
+
+// newRuleTransforms creates the configured rule transforms, in order.
+// Unknown types are rejected by the config validation and skipped here.
+func newRuleTransforms(configs []config.RuleTransform) []ruleTransform {
+	transforms := make([]ruleTransform, 0, len(configs))
+
+	for _, c := range configs {
+		if create, ok := ruleTransformTypes[c.Type]; ok {
+			transforms = append(transforms, create(c))
+		}
+	}
+
+	return transforms
+}
+
+// transformRule returns the rule of a generated HTTP router: the upstream rule
+// with rule_rewrite and then every rule transform applied
+func (a *Aggregator) transformRule(rule string) string {
+	rule = rewriteRuleHosts(rule, a.config.Routers.RuleRewrite)
+
+	for _, transform := range a.ruleTransforms {
+		rule = transform.apply(rule)
+	}
+
+	return rule
+}
+
+// hostRewrite substitutes hosts in Host and HostSNI matchers like rule_rewrite
+type hostRewrite map[string]string
+
+func (h hostRewrite) apply(rule string) string {
+	return rewriteRuleHosts(rule, h)
+}
+
+// pathPrefix restricts a rule to requests under a path prefix
+type pathPrefix string
+
+func (p pathPrefix) apply(rule string) string {
+	matcher := "PathPrefix(`" + string(p) + "`)"
+	if rule == "" {
+		return matcher
+	}
+
+	return "(" + rule + ") && " + matcher
+}
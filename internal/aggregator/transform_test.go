@@ -0,0 +1,72 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleTransforms(t *testing.T) {
+	tests := []struct {
+		name       string
+		transforms []config.RuleTransform
+		rule       string
+		expected   string
+	}{
+		{
+			name:       "host rewrite",
+			transforms: []config.RuleTransform{{Type: config.RuleTransformHostRewrite, Hosts: map[string]string{"backend.local": "app.example.com"}}},
+			rule:       "Host(`backend.local`) && Path(`/backend.local`)",
+			expected:   "Host(`app.example.com`) && Path(`/backend.local`)",
+		},
+		{
+			name:       "path prefix",
+			transforms: []config.RuleTransform{{Type: config.RuleTransformPathPrefix, Prefix: "/app"}},
+			rule:       "Host(`a.example.com`) || Host(`b.example.com`)",
+			expected:   "(Host(`a.example.com`) || Host(`b.example.com`)) && PathPrefix(`/app`)",
+		},
+		{
+			name:       "path prefix of empty rule",
+			transforms: []config.RuleTransform{{Type: config.RuleTransformPathPrefix, Prefix: "/app"}},
+			rule:       "",
+			expected:   "PathPrefix(`/app`)",
+		},
+		{
+			name: "applied in order",
+			transforms: []config.RuleTransform{
+				{Type: config.RuleTransformHostRewrite, Hosts: map[string]string{".local": ".example.com"}},
+				{Type: config.RuleTransformPathPrefix, Prefix: "/v1"},
+				{Type: config.RuleTransformHostRewrite, Hosts: map[string]string{"backend.example.com": "api.example.com"}},
+			},
+			rule:     "Host(`backend.local`)",
+			expected: "(Host(`api.example.com`)) && PathPrefix(`/v1`)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Routers.RuleTransforms = tt.transforms
+
+			assert.Equal(t, tt.expected, New(cfg, discardLogger()).transformRule(tt.rule))
+		})
+	}
+}
+
+func TestAggregateAppliesRuleTransformsAfterRuleRewrite(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.RuleRewrite = map[string]string{"app.example.com": "app.internal.lan"}
+	cfg.Routers.RuleTransforms = []config.RuleTransform{
+		{Type: config.RuleTransformHostRewrite, Hosts: map[string]string{".internal.lan": ".example.org"}},
+		{Type: config.RuleTransformPathPrefix, Prefix: "/host1"},
+	}
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	require.Contains(t, result.HTTP.Routers, "host1-webapp")
+	assert.Equal(t, "(Host(`app.example.org`)) && PathPrefix(`/host1`)", result.HTTP.Routers["host1-webapp"].Rule)
+}
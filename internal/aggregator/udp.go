@@ -0,0 +1,150 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/chickenzord/traefik-fed/internal/traefik"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// aggregateUpstreamUDP aggregates UDP routers from a single upstream.
+//
+// Generated routers keep the upstream entrypoint names unless defaults are set,
+// so the central Traefik must define UDP entrypoints with the same names. Each
+// upstream entrypoint gets its own service pointing to the (rewritten)
+// ServerURL host on that entrypoint's port.
+func (a *Aggregator) aggregateUpstreamUDP(ctx context.Context, logger *slog.Logger, upstream config.Upstream, udpConfig *dynamic.UDPConfiguration) error {
+	client := a.clients[upstream.Name]
+
+	routers, err := client.GetUDPRoutersContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch UDP routers: %w", err)
+	}
+
+	filteredRouters := traefik.FilterUDPRouters(routers, selectorFilter(&a.config.Routers.UDP.Selector))
+
+	logger.Info("fetched UDP routers from upstream",
+		"upstream", upstream.Name,
+		"total", len(routers),
+		"filtered", len(filteredRouters))
+
+	if len(filteredRouters) == 0 {
+		return nil
+	}
+
+	ports, err := entryPointPorts(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	host, err := serverHost(upstream)
+	if err != nil {
+		return err
+	}
+
+	for _, router := range filteredRouters {
+		if len(router.EntryPoints) == 0 {
+			logger.Warn("skipping UDP router without entrypoints",
+				"upstream", upstream.Name,
+				"name", router.Name)
+
+			continue
+		}
+
+		entryPoint := router.EntryPoints[0]
+
+		port := ports[entryPoint]
+		if port == "" {
+			logger.Warn("skipping UDP router with unknown entrypoint port",
+				"upstream", upstream.Name,
+				"name", router.Name,
+				"entrypoint", entryPoint)
+
+			continue
+		}
+
+		// The service points at a single port, so only the first entrypoint is federated
+		if len(router.EntryPoints) > 1 {
+			logger.Warn("UDP router has multiple entrypoints, only the first is federated",
+				"upstream", upstream.Name,
+				"name", router.Name,
+				"entrypoint", entryPoint,
+				"dropped", router.EntryPoints[1:])
+		}
+
+		serviceName := a.serviceName(upstream, "udp-"+entryPoint)
+		if _, exists := udpConfig.Services[serviceName]; !exists {
+			udpConfig.Services[serviceName] = &dynamic.UDPService{
+				LoadBalancer: &dynamic.UDPServersLoadBalancer{
+					Servers: []dynamic.UDPServer{
+						{
+							Address: net.JoinHostPort(host, port),
+						},
+					},
+				},
+			}
+		}
+
+		entryPoints := []string{entryPoint}
+		if defaults := a.config.Routers.UDP.Defaults.EntryPoints; len(defaults) > 0 {
+			entryPoints = defaults
+		}
+
+		udpConfig.Routers[a.routerName(upstream, router.Name)] = &dynamic.UDPRouter{
+			EntryPoints: entryPoints,
+			Service:     serviceName,
+		}
+	}
+
+	return nil
+}
+
+// entryPointPorts fetches the entrypoints of the upstream and returns their
+// ports by entrypoint name
+func entryPointPorts(ctx context.Context, client *traefik.Client) (map[string]string, error) {
+	entryPoints, err := client.GetEntryPointsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch entrypoints: %w", err)
+	}
+
+	ports := make(map[string]string, len(entryPoints))
+	for _, ep := range entryPoints {
+		ports[ep.Name] = entryPointPort(ep.Address)
+	}
+
+	return ports, nil
+}
+
+// serverHost returns the host of the (rewritten) ServerURL of the upstream,
+// which TCP and UDP services connect to
+func serverHost(upstream config.Upstream) (string, error) {
+	targetURL, err := upstream.TargetURL()
+	if err != nil {
+		return "", err
+	}
+
+	serverURL, err := url.Parse(targetURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server_url: %w", err)
+	}
+
+	return serverURL.Hostname(), nil
+}
+
+// entryPointPort extracts the port from an entrypoint address (e.g., ":53/udp" -> "53")
+func entryPointPort(address string) string {
+	address, _, _ = strings.Cut(address, "/")
+
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return ""
+	}
+
+	return port
+}
@@ -0,0 +1,118 @@
+package aggregator
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateUDPRouters(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{
+		"/api/http/routers": webappRouters,
+		"/api/udp/routers": `[
+			{"name": "dns@docker", "provider": "docker", "entryPoints": ["dns"], "service": "dns"},
+			{"name": "syslog@file", "provider": "file", "status": "disabled", "entryPoints": ["syslog"], "service": "syslog"}
+		]`,
+		"/api/entrypoints": `[
+			{"name": "web", "address": ":80"},
+			{"name": "dns", "address": ":53/udp"},
+			{"name": "syslog", "address": ":514/udp"}
+		]`,
+	})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.UDP.Enabled = true
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+	require.NotNil(t, result.UDP)
+
+	require.Len(t, result.UDP.Routers, 1)
+	router := result.UDP.Routers["host1-dns"]
+	require.NotNil(t, router)
+	assert.Equal(t, []string{"dns"}, router.EntryPoints)
+	assert.Equal(t, "host1-udp-dns", router.Service)
+
+	service := result.UDP.Services[router.Service]
+	require.NotNil(t, service)
+	assert.Equal(t, "192.168.1.10:53", service.LoadBalancer.Servers[0].Address)
+
+	// HTTP aggregation is unaffected
+	assert.Contains(t, result.HTTP.Routers, "host1-webapp")
+}
+
+func TestAggregateUDPSelector(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{
+		"/api/http/routers": webappRouters,
+		"/api/udp/routers": `[
+			{"name": "dns@docker", "provider": "docker", "entryPoints": ["dns"], "service": "dns"},
+			{"name": "syslog@file", "provider": "file", "entryPoints": ["syslog"], "service": "syslog"}
+		]`,
+		"/api/entrypoints": `[
+			{"name": "dns", "address": ":53/udp"},
+			{"name": "syslog", "address": ":514/udp"}
+		]`,
+	})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.UDP.Enabled = true
+	cfg.Routers.UDP.Selector.EntryPoints = []string{"syslog"}
+	cfg.Routers.UDP.Defaults.EntryPoints = []string{"logs"}
+
+	// The HTTP selector does not apply to UDP routers
+	cfg.Routers.Selector.Provider = "docker"
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+
+	require.Len(t, result.UDP.Routers, 1)
+	require.Contains(t, result.UDP.Routers, "host1-syslog")
+	assert.Equal(t, []string{"logs"}, result.UDP.Routers["host1-syslog"].EntryPoints)
+	assert.Equal(t, "192.168.1.10:514", result.UDP.Services["host1-udp-syslog"].LoadBalancer.Servers[0].Address)
+}
+
+func TestAggregateUDPDisabled(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{"/api/http/routers": webappRouters})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+
+	result, err := New(cfg, discardLogger()).Aggregate()
+	require.NoError(t, err)
+	assert.Nil(t, result.UDP)
+}
+
+func TestEntryPointPort(t *testing.T) {
+	assert.Equal(t, "53", entryPointPort(":53/udp"))
+	assert.Equal(t, "8080", entryPointPort("0.0.0.0:8080"))
+	assert.Equal(t, "53", entryPointPort("[::]:53/udp"))
+	assert.Empty(t, entryPointPort("invalid"))
+}
+
+func TestAggregateUDPMultipleEntryPoints(t *testing.T) {
+	upstream := mockUpstream(t, map[string]string{
+		"/api/http/routers": webappRouters,
+		"/api/udp/routers": `[
+			{"name": "dns@docker", "provider": "docker", "entryPoints": ["dns", "dns-alt"], "service": "dns"}
+		]`,
+		"/api/entrypoints": `[
+			{"name": "dns", "address": ":53/udp"},
+			{"name": "dns-alt", "address": ":5353/udp"}
+		]`,
+	})
+	cfg := testConfig(config.Upstream{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"})
+	cfg.Routers.UDP.Enabled = true
+
+	var logs bytes.Buffer
+
+	result, err := New(cfg, slog.New(slog.NewTextHandler(&logs, nil))).Aggregate()
+	require.NoError(t, err)
+
+	// Only the first entrypoint is federated, the others are reported
+	router := result.UDP.Routers["host1-dns"]
+	require.NotNil(t, router)
+	assert.Equal(t, []string{"dns"}, router.EntryPoints)
+	assert.Len(t, result.UDP.Services, 1)
+	assert.Contains(t, logs.String(), "UDP router has multiple entrypoints")
+	assert.Contains(t, logs.String(), "dropped=[dns-alt]")
+}
@@ -1,40 +1,295 @@
 package config
 
 import (
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/chickenzord/traefik-fed/internal/traefik"
 	"github.com/traefik/traefik/v3/pkg/config/dynamic"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the application configuration
 type Config struct {
+	Version   int          `yaml:"version"` // Schema version, see SchemaVersion (default: unversioned, migrated with a warning)
 	Upstreams []Upstream   `yaml:"upstreams"`
 	Routers   RouterConfig `yaml:"routers"`
 	Output    OutputConfig `yaml:"output"`
 	Server    ServerConfig `yaml:"server"`
 	Log       LogConfig    `yaml:"log"`
+
+	warnings []string // Migration warnings collected by Load
 }
 
 // Upstream represents a Traefik instance to poll
 type Upstream struct {
-	Name      string `yaml:"name"`       // Identifier for this upstream
-	AdminURL  string `yaml:"admin_url"`  // Traefik admin/dashboard URL (e.g., http://100.64.1.2:8080)
-	ServerURL string `yaml:"server_url"` // Full URL to route traffic to (e.g., http://100.64.1.2:80)
+	Name         string        `yaml:"name"`          // Identifier for this upstream
+	AdminURL     string        `yaml:"admin_url"`     // Traefik admin/dashboard URL (e.g., http://100.64.1.2:8080)
+	AdminURLs    []string      `yaml:"admin_urls"`    // Further admin URLs of the same Traefik, tried in order when one fails (optional)
+	ServerURL    string        `yaml:"server_url"`    // Full URL to route traffic to (e.g., http://100.64.1.2:80)
+	PollInterval time.Duration `yaml:"poll_interval"` // Overrides server.poll_interval for this upstream (optional)
+
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"` // Skip TLS verification of admin_url (not recommended)
+	CAFile             string `yaml:"ca_file"`              // PEM CA bundle to trust for admin_url (optional)
+
+	ServerURLRewrite *URLRewrite `yaml:"server_url_rewrite"` // Rewrite server_url before generating services (optional)
+	APIPath          string      `yaml:"api_path"`           // Path of the Traefik API under admin_url (default: /api)
+
+	MinRequestInterval time.Duration `yaml:"min_request_interval"` // Minimum spacing between API requests to this upstream (optional)
+	MaxResponseBytes   int64         `yaml:"max_response_bytes"`   // Largest API response body accepted (default: 32MiB)
+
+	Weight int `yaml:"weight"` // Server weight in services merged by routers.merge_identical (default: 1)
+
+	MaxRouters int `yaml:"max_routers"` // Keep at most this many routers, the first ones by name (optional)
+
+	HealthCheck *HealthCheck `yaml:"healthcheck"` // Probe server_url and skip the upstream while it is unreachable (optional)
+
+	ValidateServerDNS bool `yaml:"validate_server_dns"` // Resolve the server_url host on every poll and skip the upstream while it does not exist
+
+	FixtureFile string `yaml:"fixture_file"` // Read routers from a captured /http/routers response instead of admin_url (optional)
+
+	BasicAuth       *BasicAuth `yaml:"basic_auth"`        // Basic auth for admin_url (optional)
+	BearerToken     string     `yaml:"bearer_token"`      // Bearer token for admin_url (optional)
+	BearerTokenFile string     `yaml:"bearer_token_file"` // Read the bearer token from this file (optional)
+}
+
+// DefaultPollInterval is the default interval between polls of an upstream
+const DefaultPollInterval = 10 * time.Second
+
+// APIURL returns the Traefik API base URL of the upstream.
+// The API path is not appended again when admin_url already ends with it.
+func (u Upstream) APIURL() string {
+	return u.apiURL(u.AdminURL)
+}
+
+// APIURLs returns the Traefik API base URLs of admin_url followed by admin_urls
+func (u Upstream) APIURLs() []string {
+	urls := make([]string, 0, 1+len(u.AdminURLs))
+
+	for _, adminURL := range append([]string{u.AdminURL}, u.AdminURLs...) {
+		if adminURL != "" {
+			urls = append(urls, u.apiURL(adminURL))
+		}
+	}
+
+	return urls
+}
+
+// apiURL appends the API path to an admin URL
+func (u Upstream) apiURL(adminURL string) string {
+	apiPath := strings.TrimSuffix(u.APIPath, "/")
+	if u.APIPath == "" {
+		apiPath = "/api"
+	}
+
+	if apiPath != "" && !strings.HasPrefix(apiPath, "/") {
+		apiPath = "/" + apiPath
+	}
+
+	base := strings.TrimSuffix(adminURL, "/")
+	if strings.HasSuffix(base, apiPath) {
+		return base
+	}
+
+	return base + apiPath
+}
+
+// URLRewrite rewrites the scheme and host of a URL
+type URLRewrite struct {
+	From   string `yaml:"from"`   // Host to replace (e.g., 100.64.1.2)
+	To     string `yaml:"to"`     // Replacement host, optionally with a port (e.g., gateway.internal)
+	Scheme string `yaml:"scheme"` // Replacement scheme (optional)
+}
+
+// TargetURL returns the server_url with server_url_rewrite applied.
+// The original port and path are kept unless the replacement host sets its own port.
+func (u Upstream) TargetURL() (string, error) {
+	if u.ServerURLRewrite == nil {
+		return u.ServerURL, nil
+	}
+
+	target, err := url.Parse(u.ServerURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server_url: %w", err)
+	}
+
+	rewrite := u.ServerURLRewrite
+	if target.Hostname() != rewrite.From {
+		return u.ServerURL, nil
+	}
+
+	// Keep the original port unless the replacement has its own
+	host := rewrite.To
+	if _, _, err := net.SplitHostPort(host); err != nil && target.Port() != "" {
+		host = net.JoinHostPort(rewrite.To, target.Port())
+	}
+
+	target.Host = host
+
+	if rewrite.Scheme != "" {
+		target.Scheme = rewrite.Scheme
+	}
+
+	return target.String(), nil
 }
 
 // RouterConfig defines how to filter and configure routers
 type RouterConfig struct {
-	Selector RouterSelector `yaml:"selector"`
-	Defaults RouterDefaults `yaml:"defaults"`
+	Selector            RouterSelector `yaml:"selector"`
+	Defaults            RouterDefaults `yaml:"defaults"`
+	PreservePriority    *bool          `yaml:"preserve_priority"`    // Copy upstream router priority (default: true)
+	NamespaceServices   *bool          `yaml:"namespace_services"`   // Prefix service names with the upstream name (default: true)
+	ServicePrefix       string         `yaml:"service_prefix"`       // Prepended to every generated service name (optional)
+	ServiceSuffix       string         `yaml:"service_suffix"`       // Appended to every generated service name (optional)
+	PreserveEntryPoints *bool          `yaml:"preserve_entrypoints"` // Copy upstream router entrypoints when no default is set (default: true)
+	EntryPointSource    string         `yaml:"entrypoint_source"`    // Upstream router field copied by preserve_entrypoints: entrypoints or using (default: entrypoints)
+	IncludeUDP          bool           `yaml:"include_udp"`          // Deprecated: use udp.enabled
+	SkipMalformed       bool           `yaml:"skip_malformed"`       // Skip upstream routers that fail to decode instead of failing the poll
+	TargetSyntax        string         `yaml:"target_syntax"`        // Skip routers whose rule syntax differs: v2 or v3 (optional)
+
+	PreserveObservability bool `yaml:"preserve_observability"` // Copy upstream router observability settings when no default is set
+
+	RuleRewrite    map[string]string `yaml:"rule_rewrite"`    // Host substitutions in Host/HostSNI matchers; ".domain" keys replace suffixes
+	RuleTransforms []RuleTransform   `yaml:"rule_transforms"` // Transformations applied in order to HTTP router rules after rule_rewrite (optional)
+
+	MergeIdentical bool `yaml:"merge_identical"` // Merge same-named routers with identical rules across upstreams into one load-balanced router
+	NormalizeNames bool `yaml:"normalize_names"` // Lowercase generated router names and replace characters other than letters, digits, - and _
+
+	WarnThreshold int `yaml:"warn_threshold"` // Warn when an upstream has more routers than this after filtering (optional)
+
+	TCP TCPRouterConfig `yaml:"tcp"` // TCP router aggregation, with its own selector and defaults
+	UDP UDPRouterConfig `yaml:"udp"` // UDP router aggregation, with its own selector and defaults
+}
+
+// TCPRouterConfig configures the aggregation of TCP routers
+type TCPRouterConfig struct {
+	Enabled  bool              `yaml:"enabled"`
+	Selector RouterSelector    `yaml:"selector"`
+	Defaults TCPRouterDefaults `yaml:"defaults"`
+}
+
+// TCPRouterDefaults defines default values applied to generated TCP routers
+type TCPRouterDefaults struct {
+	EntryPoints []string                    `yaml:"entrypoints"`
+	TLS         *dynamic.RouterTCPTLSConfig `yaml:"tls"`
+}
+
+// UDPRouterConfig configures the aggregation of UDP routers. UDP routers have
+// no rule or middlewares, so rule_regex and has_middleware are ignored.
+type UDPRouterConfig struct {
+	Enabled  bool              `yaml:"enabled"`
+	Selector RouterSelector    `yaml:"selector"`
+	Defaults UDPRouterDefaults `yaml:"defaults"`
+}
+
+// UDPRouterDefaults defines default values applied to generated UDP routers
+type UDPRouterDefaults struct {
+	EntryPoints []string `yaml:"entrypoints"`
+}
+
+// ShouldIncludeUDP reports whether UDP routers are aggregated, enabled by
+// udp.enabled or the deprecated include_udp
+func (r RouterConfig) ShouldIncludeUDP() bool {
+	return r.UDP.Enabled || r.IncludeUDP
+}
+
+// serviceAffixPattern matches valid service_prefix and service_suffix values.
+// "@" would be read as a provider suffix by Traefik.
+var serviceAffixPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]*$`)
+
+// ShouldPreserveEntryPoints reports whether upstream router entrypoints are
+// copied when no default entrypoints are configured
+func (r RouterConfig) ShouldPreserveEntryPoints() bool {
+	return r.PreserveEntryPoints == nil || *r.PreserveEntryPoints
+}
+
+// Entrypoint sources of preserve_entrypoints
+const (
+	EntryPointSourceEntryPoints = "entrypoints" // Entrypoints configured on the upstream router
+	EntryPointSourceUsing       = "using"       // Entrypoints the upstream router is actually served on
+)
+
+// ShouldNamespaceServices reports whether service names are prefixed with the upstream name
+func (r RouterConfig) ShouldNamespaceServices() bool {
+	return r.NamespaceServices == nil || *r.NamespaceServices
+}
+
+// ShouldPreservePriority reports whether upstream router priorities are copied
+func (r RouterConfig) ShouldPreservePriority() bool {
+	return r.PreservePriority == nil || *r.PreservePriority
 }
 
 // RouterSelector defines filtering criteria for routers
 type RouterSelector struct {
-	Provider string `yaml:"provider"`
-	Status   string `yaml:"status"`
+	Provider  string   `yaml:"provider"`
+	Status    string   `yaml:"status"`
+	Exclude   []string `yaml:"exclude"`    // Glob patterns matched against the full router name (e.g., admin-*@docker)
+	RuleRegex string   `yaml:"rule_regex"` // Regex matched against the raw router rule (e.g., `\.example\.com`)
+
+	HasMiddleware string   `yaml:"has_middleware"` // Glob pattern one of the router middlewares must match (e.g., auth@file)
+	EntryPoints   []string `yaml:"entrypoints"`    // Keep only routers on any of these upstream entrypoints (optional)
+
+	IncludeInternal bool `yaml:"include_internal"` // Also federate routers of the internal provider (e.g., api@internal)
+
+	ruleRegexp *regexp.Regexp
+}
+
+// matchNothing is the rule regex of a selector whose rule_regex is invalid
+var matchNothing = regexp.MustCompile(`[^\s\S]`)
+
+// RuleRegexp returns the compiled rule_regex, or nil when unset. Selectors
+// that were not validated compile it on every call, and an invalid regex
+// matches no rule instead of disabling the filter.
+func (s *RouterSelector) RuleRegexp() *regexp.Regexp {
+	if s.ruleRegexp != nil || s.RuleRegex == "" {
+		return s.ruleRegexp
+	}
+
+	re, err := regexp.Compile(s.RuleRegex)
+	if err != nil {
+		return matchNothing
+	}
+
+	return re
+}
+
+// validate checks the patterns of the selector and compiles its rule regex.
+// Errors are prefixed with the selector path, e.g. routers.selector.
+func (s *RouterSelector) validate(prefix string) error {
+	var errs []error
+
+	for _, pattern := range s.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s.exclude: invalid pattern %q: %w", prefix, pattern, err))
+		}
+	}
+
+	if _, err := path.Match(s.HasMiddleware, ""); err != nil {
+		errs = append(errs, fmt.Errorf("%s.has_middleware: invalid pattern %q: %w", prefix, s.HasMiddleware, err))
+	}
+
+	if s.RuleRegex != "" {
+		re, err := regexp.Compile(s.RuleRegex)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s.rule_regex: %w", prefix, err))
+		}
+
+		s.ruleRegexp = re
+	}
+
+	return errors.Join(errs...)
 }
 
 // RouterDefaults defines default values applied to all generated routers
@@ -42,66 +297,464 @@ type RouterDefaults struct {
 	EntryPoints []string                 `yaml:"entrypoints"`
 	Middlewares []string                 `yaml:"middlewares"`
 	TLS         *dynamic.RouterTLSConfig `yaml:"tls"`
+	Sticky      *dynamic.Sticky          `yaml:"sticky"` // Sticky sessions on generated services
+
+	PassHostHeader *bool `yaml:"pass_host_header"` // Forward the client Host header to upstreams (default: unset, Traefik's default)
+
+	Observability *dynamic.RouterObservabilityConfig `yaml:"observability"` // Access logs, metrics and tracing of generated routers
+
+	DisableAccessLogs bool `yaml:"disable_access_logs"` // Set observability.accessLogs=false on generated routers, leaving access logs to the upstreams
+
+	HealthCheck *ServiceHealthCheck `yaml:"healthcheck"` // Health check of the servers of generated services (optional)
 }
 
 // OutputConfig defines where to output the aggregated configuration
 type OutputConfig struct {
-	HTTP HTTPOutput `yaml:"http"`
-	File FileOutput `yaml:"file"`
+	HTTP    HTTPOutput    `yaml:"http"`
+	File    FileOutput    `yaml:"file"`
+	Files   []FileOutput  `yaml:"files"` // Additional file outputs, always enabled when listed
+	Webhook WebhookOutput `yaml:"webhook"`
+	Redis   RedisOutput   `yaml:"redis"`
+
+	Metadata MetadataOutput `yaml:"metadata"`
 }
 
-// HTTPOutput configuration for HTTP server
-type HTTPOutput struct {
+// MetadataOutput configures a JSON file mapping generated routers to their sources
+type MetadataOutput struct {
 	Enabled bool   `yaml:"enabled"`
-	Port    int    `yaml:"port"`
 	Path    string `yaml:"path"`
+	Mode    string `yaml:"mode"`     // Octal permissions of the written file (default: 0644)
+	DirMode string `yaml:"dir_mode"` // Octal permissions of the directory (default: 0755 when created)
+}
+
+// FileMode returns the permissions of the metadata file, like file outputs
+func (m MetadataOutput) FileMode() fs.FileMode {
+	return FileOutput{Mode: m.Mode}.FileMode()
+}
+
+// DirFileMode returns the permissions of the metadata directory, like file outputs
+func (m MetadataOutput) DirFileMode() (fs.FileMode, bool) {
+	return FileOutput{DirMode: m.DirMode}.DirFileMode()
+}
+
+// FileOutputs returns all active file outputs: the single file output if
+// enabled, followed by every entry of the files list
+func (o OutputConfig) FileOutputs() []FileOutput {
+	outputs := make([]FileOutput, 0, len(o.Files)+1)
+	if o.File.Enabled {
+		outputs = append(outputs, o.File)
+	}
+
+	return append(outputs, o.Files...)
+}
+
+// HTTPOutput configuration for HTTP server
+type HTTPOutput struct {
+	Enabled   bool   `yaml:"enabled"`
+	Address   string `yaml:"address"` // Interface address to bind, e.g. 10.0.0.5 (default: all interfaces)
+	Port      int    `yaml:"port"`
+	Path      string `yaml:"path"`
+	AccessLog bool   `yaml:"access_log"` // Log every request at debug level
+	Debug     bool   `yaml:"debug"`      // Expose debug endpoints such as /routers
+
+	ContentDisposition bool `yaml:"content_disposition"` // Name the served config traefik-fed.yaml/.json for downloads
+
+	H2C         bool          `yaml:"h2c"`          // Also accept cleartext HTTP/2 connections
+	IdleTimeout time.Duration `yaml:"idle_timeout"` // How long idle keep-alive connections are kept open (default: no limit)
+
+	HealthPath    string        `yaml:"health_path"`    // Liveness endpoint kept for compatibility (default: /health)
+	LivenessPath  string        `yaml:"liveness_path"`  // Answers 200 while the process is up (default: /livez)
+	ReadinessPath string        `yaml:"readiness_path"` // Answers 200 once an upstream was polled successfully recently (default: /readyz)
+	ReadyMaxAge   time.Duration `yaml:"ready_max_age"`  // How recent that poll must be (default: 3x the longest poll interval)
+
+	ReloadToken     string `yaml:"reload_token"`      // Bearer token enabling POST /reload (optional)
+	ReloadTokenFile string `yaml:"reload_token_file"` // Read the reload token from this file (optional)
+
+	Paths []HTTPPath `yaml:"paths"` // Additional endpoints serving a subset of the routers (optional)
+}
+
+// HTTPPath is an endpoint of the HTTP output serving only the aggregated
+// routers matching its selector, e.g. for Traefik instances polling
+// different slices of the configuration
+type HTTPPath struct {
+	Path     string       `yaml:"path"`
+	Selector FileSelector `yaml:"selector"`
+}
+
+// validate checks if the HTTP output is valid
+func (h HTTPOutput) validate() error {
+	var errs []error
+
+	if h.Port <= 0 {
+		errs = append(errs, fmt.Errorf("HTTP output port must be specified"))
+	}
+
+	if h.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("HTTP output idle_timeout must not be negative"))
+	}
+
+	// Endpoints registered by the HTTP output itself
+	paths := map[string]string{"the stats endpoint": "/stats"}
+	if h.Debug {
+		paths["the routers endpoint"] = "/routers"
+	}
+
+	if h.ReloadToken != "" || h.ReloadTokenFile != "" {
+		paths["the reload endpoint"] = "/reload"
+	}
+
+	// checkPath reports a path used by another endpoint
+	checkPath := func(key, p string) {
+		for used, path := range paths {
+			if path == p {
+				errs = append(errs, fmt.Errorf("output.http.%s: %q is already used by %s", key, p, used))
+			}
+		}
+
+		if h.Debug && strings.HasPrefix(p, "/debug/upstream/") {
+			errs = append(errs, fmt.Errorf("output.http.%s: %q is already used by the debug upstream endpoint", key, p))
+		}
+
+		paths[key] = p
+	}
+
+	checkPath("path", h.Path)
+
+	for _, endpoint := range []struct{ key, path string }{
+		{"health_path", h.HealthPath},
+		{"liveness_path", h.LivenessPath},
+		{"readiness_path", h.ReadinessPath},
+	} {
+		if endpoint.path == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(endpoint.path, "/") {
+			errs = append(errs, fmt.Errorf("output.http.%s: %q must start with /", endpoint.key, endpoint.path))
+			continue
+		}
+
+		checkPath(endpoint.key, endpoint.path)
+	}
+
+	for i, extra := range h.Paths {
+		key := fmt.Sprintf("paths[%d]", i)
+
+		if !strings.HasPrefix(extra.Path, "/") {
+			errs = append(errs, fmt.Errorf("output.http.%s: %q must start with /", key, extra.Path))
+			continue
+		}
+
+		checkPath(key, extra.Path)
+
+		for _, pattern := range extra.Selector.Names {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("output.http.%s: invalid selector name pattern %q: %w", key, pattern, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 // FileOutput configuration for file-based output
 type FileOutput struct {
 	Enabled  bool          `yaml:"enabled"`
 	Path     string        `yaml:"path"`
-	Interval time.Duration `yaml:"interval"`
+	Paths    []string      `yaml:"paths"`    // Further files written with the same content, each atomically (optional)
+	Format   string        `yaml:"format"`   // Format: yaml, json (default: yaml)
+	Interval time.Duration `yaml:"interval"` // Fallback interval to flush pending changes and recreate a missing file
+	Debounce time.Duration `yaml:"debounce"` // Minimum delay to coalesce rapid updates before writing
+	Mode     string        `yaml:"mode"`     // Octal permissions of the written file (default: 0644)
+	DirMode  string        `yaml:"dir_mode"` // Octal permissions of the output directory (default: 0755 when created)
+	Layout   string        `yaml:"layout"`   // Layout: single, per_upstream_documents (default: single)
+	Fsync    bool          `yaml:"fsync"`    // Flush the file and its directory to disk on every write
+	Selector FileSelector  `yaml:"selector"`
+
+	StartupGrace time.Duration `yaml:"startup_grace"` // How long after starting configs without routers are held back (optional)
+}
+
+// File output layouts
+const (
+	LayoutSingle               = "single"                 // One document with the whole configuration
+	LayoutPerUpstreamDocuments = "per_upstream_documents" // One YAML document per upstream
+)
+
+// AllPaths returns path followed by paths
+func (f FileOutput) AllPaths() []string {
+	if f.Path == "" {
+		return f.Paths
+	}
+
+	return append([]string{f.Path}, f.Paths...)
+}
+
+// name identifies the file output in validation errors
+func (f FileOutput) name() string {
+	return strings.Join(f.AllPaths(), ",")
+}
+
+// FileMode returns the permissions of the written file
+func (f FileOutput) FileMode() fs.FileMode {
+	mode, err := parseFileMode(f.Mode)
+	if err != nil || f.Mode == "" {
+		return 0644
+	}
+
+	return mode
+}
+
+// DirFileMode returns the permissions of the output directory, and whether
+// they were set explicitly and should also be applied to an existing directory
+func (f FileOutput) DirFileMode() (fs.FileMode, bool) {
+	mode, err := parseFileMode(f.DirMode)
+	if err != nil || f.DirMode == "" {
+		return 0755, false
+	}
+
+	return mode, true
+}
+
+// parseFileMode parses octal permission bits such as "0640" or "640"
+func parseFileMode(s string) (fs.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	mode, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid octal mode %q", s)
+	}
+
+	if mode > 0777 {
+		return 0, fmt.Errorf("mode %q has bits outside of 0777", s)
+	}
+
+	return fs.FileMode(mode), nil
+}
+
+// WebhookOutput configuration for pushing the configuration to an HTTP endpoint on change
+type WebhookOutput struct {
+	Enabled      bool              `yaml:"enabled"`
+	URL          string            `yaml:"url"`
+	Method       string            `yaml:"method"`        // HTTP method (default: POST)
+	Headers      map[string]string `yaml:"headers"`       // Extra request headers, e.g. Authorization (optional)
+	Format       string            `yaml:"format"`        // Format: json, yaml (default: json)
+	Debounce     time.Duration     `yaml:"debounce"`      // Minimum delay to coalesce rapid updates before sending
+	Timeout      time.Duration     `yaml:"timeout"`       // Timeout of a single request
+	MaxRetries   *int              `yaml:"max_retries"`   // Retries after a failed request (default: 3, 0 disables retries)
+	RetryBackoff time.Duration     `yaml:"retry_backoff"` // Delay before the first retry, doubled on each retry
+}
+
+// DefaultWebhookMaxRetries is the default number of retries of a webhook request
+const DefaultWebhookMaxRetries = 3
+
+// Retries returns the number of retries after a failed request
+func (w WebhookOutput) Retries() int {
+	if w.MaxRetries == nil {
+		return DefaultWebhookMaxRetries
+	}
+
+	return *w.MaxRetries
+}
+
+// RedisOutput configures writing the configuration to Redis in the key layout
+// read by Traefik's Redis provider
+type RedisOutput struct {
+	Enabled      bool          `yaml:"enabled"`
+	Address      string        `yaml:"address"`       // Redis server as host:port
+	Username     string        `yaml:"username"`      // ACL username (optional)
+	Password     string        `yaml:"password"`      // Password (optional)
+	PasswordFile string        `yaml:"password_file"` // Read the password from this file (optional)
+	DB           int           `yaml:"db"`            // Database number (default: 0)
+	RootKey      string        `yaml:"root_key"`      // Prefix of all written keys, Traefik's rootKey (default: traefik)
+	Debounce     time.Duration `yaml:"debounce"`      // Minimum delay to coalesce rapid updates before writing
+}
+
+// FileSelector further filters the aggregated routers written to a file or
+// served on an HTTP path.
+// Empty fields match all routers.
+type FileSelector struct {
+	EntryPoints []string `yaml:"entrypoints"` // Keep routers having any of these entrypoints
+	Names       []string `yaml:"names"`       // Glob patterns matched against the generated router name (e.g., host1-*)
 }
 
 // ServerConfig defines server behavior
 type ServerConfig struct {
 	PollInterval time.Duration `yaml:"poll_interval"`
+	PollJitter   string        `yaml:"poll_jitter"` // Random ±jitter per poll: a duration (2s) or a percentage of the interval (10%)
+	StartupCheck StartupCheck  `yaml:"startup_check"`
+	UserAgent    string        `yaml:"user_agent"`    // User-Agent of upstream API requests (default: traefik-fed/<version>)
+	FailOnEmpty  bool          `yaml:"fail_on_empty"` // Exit when the initial aggregation produces no routers
+	ApplyDelay   time.Duration `yaml:"apply_delay"`   // Batch aggregation changes for this long before publishing them (optional)
+
+	CircuitBreaker CircuitBreaker `yaml:"circuit_breaker"`
+	HTTPTransport  HTTPTransport  `yaml:"http_transport"`
+	Tracing        Tracing        `yaml:"tracing"`
+}
+
+// HTTPTransport tunes the connection pooling of upstream API requests.
+// Zero values keep the defaults of the Go HTTP client.
+type HTTPTransport struct {
+	MaxIdleConns        int           `yaml:"max_idle_conns"`          // Idle connections kept per upstream, across its admin URLs (default: 100)
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"` // Idle connections kept per upstream host (default: 2)
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout"`       // How long an idle connection is kept (default: 90s)
+	DisableKeepAlives   bool          `yaml:"disable_keep_alives"`     // Open a new connection for every request
+}
+
+// CircuitBreaker configures skipping upstreams that keep failing
+type CircuitBreaker struct {
+	Enabled       bool          `yaml:"enabled"`
+	FailThreshold int           `yaml:"fail_threshold"` // Consecutive failed polls that open the circuit (default: 3)
+	CoolDown      time.Duration `yaml:"cool_down"`      // Initial time the circuit stays open (default: 30s)
+	MaxCoolDown   time.Duration `yaml:"max_cool_down"`  // Cap of the doubling cool-down (default: 5m)
+}
+
+// StartupCheck configures the upstream reachability check run before polling starts
+type StartupCheck struct {
+	Enabled       bool `yaml:"enabled"`
+	FailThreshold int  `yaml:"fail_threshold"` // Exit when at least this many upstreams fail (0: only warn)
+}
+
+// Jitter returns the maximum poll jitter for the given interval.
+// It returns zero when poll_jitter is unset or invalid.
+func (s ServerConfig) Jitter(interval time.Duration) time.Duration {
+	jitter, _ := parseJitter(s.PollJitter, interval)
+	return jitter
+}
+
+// validateURL checks that value is an absolute URL with a scheme and host
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q must be an absolute URL like http://host:port", value)
+	}
+
+	return nil
+}
+
+// validateBindAddress checks that address is empty, an IP address or a host name
+func validateBindAddress(address string) error {
+	if address == "" || net.ParseIP(address) != nil {
+		return nil
+	}
+
+	for _, r := range address {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-') {
+			return fmt.Errorf("%q is not an IP address or host name", address)
+		}
+	}
+
+	return nil
+}
+
+// parseJitter parses a jitter given as a duration or a percentage of interval
+func parseJitter(value string, interval time.Duration) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || p < 0 || p > 100 {
+			return 0, fmt.Errorf("invalid percentage %q", value)
+		}
+
+		return time.Duration(float64(interval) * p / 100), nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	return d, nil
 }
 
 // LogConfig defines logging behavior
 type LogConfig struct {
-	Format string `yaml:"format"` // Format: plain, json (default: plain)
+	Format string `yaml:"format"` // Format: plain, json, console (default: plain)
 	Level  string `yaml:"level"`  // Level: debug, info, warn, error (default: info)
 }
 
 // Load reads and parses the configuration file
 func Load(path string) (*Config, error) {
+	return LoadWithOptions(path, LoadOptions{})
+}
+
+// LoadOptions controls how the configuration file is loaded
+type LoadOptions struct {
+	StrictEnv bool // Fail on ${VAR} references to undefined variables without a default
+}
+
+// LoadWithOptions reads and parses the configuration file, expanding
+// ${VAR} and ${VAR:-default} environment variable references first
+func LoadWithOptions(path string, opts LoadOptions) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	data, err = expandEnv(data, opts.StrictEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand config file: %w", err)
+	}
+
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	cfg.warnings, err = migrate(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveSecrets(&cfg); err != nil {
+		return nil, err
+	}
+
 	// Set defaults
 	if cfg.Server.PollInterval == 0 {
-		cfg.Server.PollInterval = 10 * time.Second
+		cfg.Server.PollInterval = DefaultPollInterval
 	}
 
-	if cfg.Output.HTTP.Path == "" {
-		cfg.Output.HTTP.Path = "/config"
+	setHTTPOutputDefaults(&cfg.Output.HTTP, cfg.maxPollInterval())
+
+	setFileOutputDefaults(&cfg.Output.File)
+	setWebhookOutputDefaults(&cfg.Output.Webhook)
+	setRedisOutputDefaults(&cfg.Output.Redis)
+	setCircuitBreakerDefaults(&cfg.Server.CircuitBreaker)
+	setTracingDefaults(&cfg.Server.Tracing)
+
+	for i := range cfg.Output.Files {
+		setFileOutputDefaults(&cfg.Output.Files[i])
 	}
 
-	if cfg.Output.File.Interval == 0 {
-		cfg.Output.File.Interval = 30 * time.Second
+	for i := range cfg.Upstreams {
+		upstream := &cfg.Upstreams[i]
+
+		if upstream.HealthCheck != nil {
+			setHealthCheckDefaults(upstream.HealthCheck)
+		}
+
+		if upstream.MaxResponseBytes == 0 {
+			upstream.MaxResponseBytes = traefik.DefaultMaxResponseBytes
+		}
+	}
+
+	if cfg.Routers.EntryPointSource == "" {
+		cfg.Routers.EntryPointSource = EntryPointSourceEntryPoints
 	}
 
-	if cfg.Routers.Selector.Status == "" {
-		cfg.Routers.Selector.Status = "enabled"
+	for _, selector := range []*RouterSelector{&cfg.Routers.Selector, &cfg.Routers.TCP.Selector, &cfg.Routers.UDP.Selector} {
+		if selector.Status == "" {
+			selector.Status = "enabled"
+		}
 	}
 
 	if cfg.Log.Format == "" {
@@ -115,37 +768,550 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
-// Validate checks if the configuration is valid
+// setHTTPOutputDefaults applies default values to the HTTP output
+func setHTTPOutputDefaults(h *HTTPOutput, pollInterval time.Duration) {
+	if h.Path == "" {
+		h.Path = "/config"
+	}
+
+	if h.HealthPath == "" {
+		h.HealthPath = "/health"
+	}
+
+	if h.LivenessPath == "" {
+		h.LivenessPath = "/livez"
+	}
+
+	if h.ReadinessPath == "" {
+		h.ReadinessPath = "/readyz"
+	}
+
+	if h.ReadyMaxAge == 0 {
+		h.ReadyMaxAge = 3 * pollInterval
+	}
+}
+
+// maxPollInterval returns the longest poll interval of all upstreams
+func (c *Config) maxPollInterval() time.Duration {
+	interval := c.Server.PollInterval
+
+	for _, upstream := range c.Upstreams {
+		interval = max(interval, upstream.PollInterval)
+	}
+
+	return interval
+}
+
+// setFileOutputDefaults applies default values to a file output
+func setFileOutputDefaults(f *FileOutput) {
+	if f.Format == "" {
+		f.Format = "yaml"
+	}
+
+	if f.Layout == "" {
+		f.Layout = LayoutSingle
+	}
+
+	if f.Interval == 0 {
+		f.Interval = 30 * time.Second
+	}
+
+	if f.Debounce == 0 {
+		f.Debounce = 2 * time.Second
+	}
+}
+
+// setRedisOutputDefaults applies defaults to the Redis output
+func setRedisOutputDefaults(r *RedisOutput) {
+	if r.RootKey == "" {
+		r.RootKey = "traefik"
+	}
+
+	if r.Debounce == 0 {
+		r.Debounce = 2 * time.Second
+	}
+}
+
+// setWebhookOutputDefaults applies defaults to a webhook output
+func setWebhookOutputDefaults(w *WebhookOutput) {
+	if w.Method == "" {
+		w.Method = http.MethodPost
+	}
+
+	if w.Format == "" {
+		w.Format = "json"
+	}
+
+	if w.Debounce == 0 {
+		w.Debounce = 2 * time.Second
+	}
+
+	if w.Timeout == 0 {
+		w.Timeout = 10 * time.Second
+	}
+
+	if w.MaxRetries == nil {
+		retries := DefaultWebhookMaxRetries
+		w.MaxRetries = &retries
+	}
+
+	if w.RetryBackoff == 0 {
+		w.RetryBackoff = time.Second
+	}
+}
+
+// setCircuitBreakerDefaults applies defaults to the circuit breaker
+func setCircuitBreakerDefaults(b *CircuitBreaker) {
+	if b.FailThreshold == 0 {
+		b.FailThreshold = 3
+	}
+
+	if b.CoolDown == 0 {
+		b.CoolDown = 30 * time.Second
+	}
+
+	if b.MaxCoolDown == 0 {
+		b.MaxCoolDown = 5 * time.Minute
+	}
+}
+
+// providerSuffixWarnings warns about default entrypoint and middleware names
+// with an @provider suffix. They are copied as is to generated routers, where
+// they rarely resolve: entrypoints never have a provider, and the provider of a
+// middleware must also exist on the federated Traefik. Traefik then silently
+// fails the routers.
+func (c *Config) providerSuffixWarnings() []string {
+	const (
+		entryPointHint = "entrypoint names have no provider suffix"
+		middlewareHint = "the federated Traefik must also have this provider"
+	)
+
+	lists := []struct {
+		field string
+		names []string
+		hint  string
+	}{
+		{"routers.defaults.entrypoints", c.Routers.Defaults.EntryPoints, entryPointHint},
+		{"routers.defaults.middlewares", c.Routers.Defaults.Middlewares, middlewareHint},
+		{"routers.tcp.defaults.entrypoints", c.Routers.TCP.Defaults.EntryPoints, entryPointHint},
+		{"routers.udp.defaults.entrypoints", c.Routers.UDP.Defaults.EntryPoints, entryPointHint},
+	}
+
+	var warnings []string
+
+	for _, list := range lists {
+		for _, name := range list.names {
+			if strings.Contains(name, "@") {
+				warnings = append(warnings, fmt.Sprintf("%s: %q has a provider suffix, %s", list.field, name, list.hint))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// Validate checks if the configuration is valid. Every problem found is
+// reported at once in a *ValidationError.
 func (c *Config) Validate() error {
+	errs := append(c.aggregationProblems(), c.outputProblems()...)
+
+	return errs.err()
+}
+
+// ValidateAggregation checks the configuration like Validate, except for the
+// outputs, for programs embedding the aggregation without them
+func (c *Config) ValidateAggregation() error {
+	return c.aggregationProblems().err()
+}
+
+// aggregationProblems checks the upstream, router and server settings
+func (c *Config) aggregationProblems() problems {
+	var errs problems
+
 	if len(c.Upstreams) == 0 {
-		return fmt.Errorf("at least one upstream must be configured")
+		errs.add(fmt.Errorf("at least one upstream must be configured"))
 	}
 
 	for i, upstream := range c.Upstreams {
-		if upstream.Name == "" {
-			return fmt.Errorf("upstream %d: name is required", i)
+		name := upstream.Name
+		if name == "" {
+			errs.add(fmt.Errorf("upstream %d: name is required", i))
+			name = strconv.Itoa(i)
+		}
+
+		if upstream.AdminURL == "" && len(upstream.AdminURLs) == 0 && upstream.FixtureFile == "" {
+			errs.add(fmt.Errorf("upstream %s: admin_url or fixture_file is required", name))
 		}
 
-		if upstream.AdminURL == "" {
-			return fmt.Errorf("upstream %s: admin_url is required", upstream.Name)
+		if upstream.AdminURL != "" {
+			if err := validateURL(upstream.AdminURL); err != nil {
+				errs.add(fmt.Errorf("upstream %s: admin_url: %w", name, err))
+			}
+		}
+
+		for j, adminURL := range upstream.AdminURLs {
+			if err := validateURL(adminURL); err != nil {
+				errs.add(fmt.Errorf("upstream %s: admin_urls[%d]: %w", name, j, err))
+			}
 		}
 
 		if upstream.ServerURL == "" {
-			return fmt.Errorf("upstream %s: server_url is required", upstream.Name)
+			errs.add(fmt.Errorf("upstream %s: server_url is required", name))
+		} else if err := validateURL(upstream.ServerURL); err != nil {
+			errs.add(fmt.Errorf("upstream %s: server_url: %w", name, err))
+		} else if rewrite := upstream.ServerURLRewrite; rewrite != nil {
+			if rewrite.From == "" || rewrite.To == "" {
+				errs.add(fmt.Errorf("upstream %s: server_url_rewrite requires from and to", name))
+			} else if _, err := upstream.TargetURL(); err != nil {
+				errs.add(fmt.Errorf("upstream %s: %w", name, err))
+			}
+		}
+
+		if upstream.BasicAuth != nil && upstream.BearerToken != "" {
+			errs.add(fmt.Errorf("upstream %s: basic_auth and bearer_token are mutually exclusive", name))
 		}
+
+		if upstream.HealthCheck != nil {
+			if err := upstream.HealthCheck.validate(); err != nil {
+				errs.add(fmt.Errorf("upstream %s: healthcheck: %w", name, err))
+			}
+		}
+
+		if upstream.Weight < 0 {
+			errs.add(fmt.Errorf("upstream %s: weight must not be negative", name))
+		}
+
+		if upstream.MaxResponseBytes < 0 {
+			errs.add(fmt.Errorf("upstream %s: max_response_bytes must not be negative", name))
+		}
+
+		if upstream.MaxRouters < 0 {
+			errs.add(fmt.Errorf("upstream %s: max_routers must not be negative", name))
+		}
+
+		if upstream.CAFile != "" {
+			if err := validateCAFile(upstream.CAFile); err != nil {
+				errs.add(fmt.Errorf("upstream %s: ca_file: %w", name, err))
+			}
+		}
+
+		if upstream.FixtureFile != "" {
+			if _, err := os.Stat(upstream.FixtureFile); err != nil {
+				errs.add(fmt.Errorf("upstream %s: fixture_file: %w", name, err))
+			}
+		}
+	}
+
+	errs.add(c.Routers.Selector.validate("routers.selector"))
+	errs.add(c.Routers.TCP.Selector.validate("routers.tcp.selector"))
+	errs.add(c.Routers.UDP.Selector.validate("routers.udp.selector"))
+
+	// Every upstream would generate the same service names, and all but the
+	// first upstream would be skipped
+	if !c.Routers.ShouldNamespaceServices() && len(c.Upstreams) > 1 {
+		errs.add(errors.New("routers.namespace_services can only be disabled with a single upstream"))
+	}
+
+	if !serviceAffixPattern.MatchString(c.Routers.ServicePrefix) {
+		errs.add(fmt.Errorf("routers.service_prefix: %q may only contain letters, digits, '-', '_' and '.'", c.Routers.ServicePrefix))
 	}
 
-	if !c.Output.HTTP.Enabled && !c.Output.File.Enabled {
-		return fmt.Errorf("at least one output method (HTTP or File) must be enabled")
+	if !serviceAffixPattern.MatchString(c.Routers.ServiceSuffix) {
+		errs.add(fmt.Errorf("routers.service_suffix: %q may only contain letters, digits, '-', '_' and '.'", c.Routers.ServiceSuffix))
 	}
 
-	if c.Output.HTTP.Enabled && c.Output.HTTP.Port <= 0 {
-		return fmt.Errorf("HTTP output port must be specified")
+	switch c.Routers.EntryPointSource {
+	case "", EntryPointSourceEntryPoints, EntryPointSourceUsing:
+	default:
+		errs.add(fmt.Errorf("routers.entrypoint_source: must be entrypoints or using, got %q", c.Routers.EntryPointSource))
 	}
 
-	if c.Output.File.Enabled && c.Output.File.Path == "" {
-		return fmt.Errorf("file output path must be specified")
+	if c.Routers.WarnThreshold < 0 {
+		errs.add(errors.New("routers.warn_threshold must not be negative"))
+	}
+
+	switch c.Routers.TargetSyntax {
+	case "", "v2", "v3":
+	default:
+		errs.add(fmt.Errorf("routers.target_syntax: must be v2 or v3, got %q", c.Routers.TargetSyntax))
+	}
+
+	for i, transform := range c.Routers.RuleTransforms {
+		if err := transform.validate(); err != nil {
+			errs.add(fmt.Errorf("routers.rule_transforms[%d]: %w", i, err))
+		}
+	}
+
+	if err := validateRouterTLS(c.Routers.Defaults.TLS); err != nil {
+		errs.add(fmt.Errorf("routers.defaults.tls.%w", err))
+	}
+
+	if c.Routers.Defaults.HealthCheck != nil {
+		if err := c.Routers.Defaults.HealthCheck.validate(); err != nil {
+			errs.add(fmt.Errorf("routers.defaults.healthcheck: %w", err))
+		}
+	}
+
+	if tls := c.Routers.TCP.Defaults.TLS; tls != nil {
+		routerTLS := &dynamic.RouterTLSConfig{Options: tls.Options, CertResolver: tls.CertResolver, Domains: tls.Domains}
+		if err := validateRouterTLS(routerTLS); err != nil {
+			errs.add(fmt.Errorf("routers.tcp.defaults.tls.%w", err))
+		}
+	}
+
+	if o := c.Routers.Defaults.Observability; o != nil {
+		switch o.TraceVerbosity {
+		case "", "minimal", "detailed":
+		default:
+			errs.add(fmt.Errorf("routers.defaults.observability.traceVerbosity must be minimal or detailed, got %q", o.TraceVerbosity))
+		}
+	}
+
+	if c.Server.StartupCheck.FailThreshold < 0 {
+		errs.add(fmt.Errorf("server.startup_check.fail_threshold must not be negative"))
+	}
+
+	if b := c.Server.CircuitBreaker; b.Enabled {
+		if b.FailThreshold < 1 {
+			errs.add(fmt.Errorf("server.circuit_breaker.fail_threshold must be at least 1"))
+		}
+
+		if b.CoolDown <= 0 {
+			errs.add(fmt.Errorf("server.circuit_breaker.cool_down must be positive"))
+		}
+
+		if b.MaxCoolDown < b.CoolDown {
+			errs.add(fmt.Errorf("server.circuit_breaker.max_cool_down must not be less than cool_down"))
+		}
+	}
+
+	if c.Server.ApplyDelay < 0 {
+		errs.add(fmt.Errorf("server.apply_delay must not be negative"))
+	}
+
+	if t := c.Server.HTTPTransport; t.MaxIdleConns < 0 || t.MaxIdleConnsPerHost < 0 || t.IdleConnTimeout < 0 {
+		errs.add(fmt.Errorf("server.http_transport: max_idle_conns, max_idle_conns_per_host and idle_conn_timeout must not be negative"))
+	}
+
+	if c.Server.Tracing.Enabled {
+		errs.add(c.Server.Tracing.validate())
+	}
+
+	if _, err := parseJitter(c.Server.PollJitter, c.Server.PollInterval); err != nil {
+		errs.add(fmt.Errorf("server.poll_jitter: %w", err))
+	}
+
+	return errs
+}
+
+// outputProblems checks the output settings
+func (c *Config) outputProblems() problems {
+	var errs problems
+
+	fileOutputs := c.Output.FileOutputs()
+
+	if !c.Output.HTTP.Enabled && len(fileOutputs) == 0 && !c.Output.Webhook.Enabled && !c.Output.Redis.Enabled {
+		errs.add(fmt.Errorf("at least one output method (HTTP, File, Webhook or Redis) must be enabled"))
+	}
+
+	if c.Output.Webhook.Enabled {
+		errs.add(c.Output.Webhook.validate())
+	}
+
+	if c.Output.Redis.Enabled {
+		errs.add(c.Output.Redis.validate())
+	}
+
+	if c.Output.Metadata.Enabled {
+		if c.Output.Metadata.Path == "" {
+			errs.add(fmt.Errorf("output.metadata.path must be specified"))
+		}
+
+		if _, err := parseFileMode(c.Output.Metadata.Mode); err != nil {
+			errs.add(fmt.Errorf("output.metadata.mode: %w", err))
+		}
+
+		if _, err := parseFileMode(c.Output.Metadata.DirMode); err != nil {
+			errs.add(fmt.Errorf("output.metadata.dir_mode: %w", err))
+		}
+	}
+
+	if c.Output.HTTP.Enabled {
+		errs.add(c.Output.HTTP.validate())
+	}
+
+	if err := validateBindAddress(c.Output.HTTP.Address); err != nil {
+		errs.add(fmt.Errorf("output.http.address: %w", err))
+	}
+
+	paths := make(map[string]bool)
+
+	for _, f := range fileOutputs {
+		errs.add(f.validate())
+
+		for _, p := range f.AllPaths() {
+			if paths[p] {
+				errs.add(fmt.Errorf("file output %s: path is used by more than one file output", p))
+			}
+
+			paths[p] = true
+		}
+	}
+
+	return errs
+}
+
+// validateCAFile checks that the file is a readable PEM bundle holding at
+// least one certificate, as loaded by the upstream API client
+func validateCAFile(path string) error {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in %s", path)
 	}
 
 	return nil
 }
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []error
+}
+
+// Error returns the single problem, or all of them separated by semicolons
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].Error()
+	}
+
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.Error()
+	}
+
+	return fmt.Sprintf("%d problems: %s", len(e.Problems), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the problems, for errors.Is and errors.As
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// problems collects validation errors
+type problems []error
+
+// add records err unless it is nil. Errors joined by a section validator are
+// recorded one by one.
+func (p *problems) add(err error) {
+	if err == nil {
+		return
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		*p = append(*p, joined.Unwrap()...)
+		return
+	}
+
+	*p = append(*p, err)
+}
+
+// err returns the collected problems as a *ValidationError, or nil
+func (p problems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Problems: p}
+}
+
+// validate checks if the file output is valid
+func (f FileOutput) validate() error {
+	var errs []error
+
+	if len(f.AllPaths()) == 0 {
+		errs = append(errs, fmt.Errorf("file output path must be specified"))
+	}
+
+	if slices.Contains(f.Paths, "") {
+		errs = append(errs, fmt.Errorf("file output %s: paths must not be empty", f.name()))
+	}
+
+	if f.Format != "" && f.Format != "yaml" && f.Format != "json" {
+		errs = append(errs, fmt.Errorf("file output %s: unsupported format %q", f.name(), f.Format))
+	}
+
+	switch f.Layout {
+	case "", LayoutSingle:
+	case LayoutPerUpstreamDocuments:
+		if f.Format == "json" {
+			errs = append(errs, fmt.Errorf("file output %s: layout %s requires the yaml format", f.name(), f.Layout))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("file output %s: unsupported layout %q", f.name(), f.Layout))
+	}
+
+	if f.StartupGrace < 0 {
+		errs = append(errs, fmt.Errorf("file output %s: startup_grace must not be negative", f.name()))
+	}
+
+	if _, err := parseFileMode(f.Mode); err != nil {
+		errs = append(errs, fmt.Errorf("file output %s: mode: %w", f.name(), err))
+	}
+
+	if _, err := parseFileMode(f.DirMode); err != nil {
+		errs = append(errs, fmt.Errorf("file output %s: dir_mode: %w", f.name(), err))
+	}
+
+	for _, pattern := range f.Selector.Names {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("file output %s: invalid selector name pattern %q: %w", f.name(), pattern, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks if the Redis output is valid
+func (r RedisOutput) validate() error {
+	var errs []error
+
+	if r.Address == "" {
+		errs = append(errs, fmt.Errorf("redis output address must be specified"))
+	} else if _, _, err := net.SplitHostPort(r.Address); err != nil {
+		errs = append(errs, fmt.Errorf("redis output address: %w", err))
+	}
+
+	if r.DB < 0 {
+		errs = append(errs, fmt.Errorf("redis output: db must not be negative"))
+	}
+
+	if strings.Trim(r.RootKey, "/") == "" {
+		errs = append(errs, fmt.Errorf("redis output: root_key must not be empty"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks if the webhook output is valid
+func (w WebhookOutput) validate() error {
+	var errs []error
+
+	if w.URL == "" {
+		errs = append(errs, fmt.Errorf("webhook output url must be specified"))
+	} else if err := validateURL(w.URL); err != nil {
+		errs = append(errs, fmt.Errorf("webhook output url: %w", err))
+	}
+
+	if w.Format != "" && w.Format != "yaml" && w.Format != "json" {
+		errs = append(errs, fmt.Errorf("webhook output: unsupported format %q", w.Format))
+	}
+
+	if w.MaxRetries != nil && *w.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("webhook output: max_retries must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,812 @@
+package config
+
+import (
+	"encoding/pem"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Upstreams: []Upstream{
+			{
+				Name:      "host1",
+				AdminURL:  "http://192.168.1.10:8080",
+				ServerURL: "http://192.168.1.10:80",
+			},
+		},
+		Output: OutputConfig{
+			HTTP: HTTPOutput{Enabled: true, Port: 8080, Path: "/config"},
+		},
+	}
+}
+
+func TestValidateExcludePattern(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routers.Selector.Exclude = []string{"admin-*", "[invalid"}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routers.selector.exclude")
+}
+
+func TestValidateRuleRegex(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routers.Selector.RuleRegex = `\.example\.com`
+
+	require.NoError(t, cfg.Validate())
+	require.NotNil(t, cfg.Routers.Selector.RuleRegexp())
+	assert.True(t, cfg.Routers.Selector.RuleRegexp().MatchString("Host(`app.example.com`)"))
+}
+
+func TestValidateRuleRegexInvalid(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routers.Selector.RuleRegex = `Host(`
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routers.selector.rule_regex")
+
+	// An invalid regex filters out every router rather than none
+	require.NotNil(t, cfg.Routers.Selector.RuleRegexp())
+	assert.False(t, cfg.Routers.Selector.RuleRegexp().MatchString("Host(`app.example.com`)"))
+}
+
+func TestRuleRegexpWithoutValidate(t *testing.T) {
+	selector := RouterSelector{RuleRegex: `\.example\.com`}
+	require.NotNil(t, selector.RuleRegexp())
+	assert.True(t, selector.RuleRegexp().MatchString("Host(`app.example.com`)"))
+	assert.False(t, selector.RuleRegexp().MatchString("Host(`app.example.org`)"))
+
+	selector = RouterSelector{RuleRegex: `Host(`}
+	require.NotNil(t, selector.RuleRegexp())
+	assert.False(t, selector.RuleRegexp().MatchString("Host(`app.example.com`)"))
+
+	assert.Nil(t, (&RouterSelector{}).RuleRegexp())
+}
+
+func TestFileOutputs(t *testing.T) {
+	cfg := validConfig()
+	cfg.Output.File = FileOutput{Enabled: true, Path: "/etc/traefik/dynamic/federation.yml"}
+	cfg.Output.Files = []FileOutput{
+		{Path: "/etc/traefik/dynamic/edge.yml"},
+		{Path: "/etc/traefik/dynamic/internal.yml"},
+	}
+
+	require.NoError(t, cfg.Validate())
+
+	outputs := cfg.Output.FileOutputs()
+	require.Len(t, outputs, 3)
+	assert.Equal(t, "/etc/traefik/dynamic/federation.yml", outputs[0].Path)
+	assert.Equal(t, "/etc/traefik/dynamic/internal.yml", outputs[2].Path)
+
+	cfg.Output.File.Enabled = false
+	assert.Len(t, cfg.Output.FileOutputs(), 2)
+}
+
+func TestValidateFileOutputs(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []FileOutput
+		errMsg string
+	}{
+		{
+			name:   "missing path",
+			files:  []FileOutput{{Format: "yaml"}},
+			errMsg: "file output path must be specified",
+		},
+		{
+			name:   "duplicate path",
+			files:  []FileOutput{{Path: "/tmp/a.yml"}, {Path: "/tmp/a.yml"}},
+			errMsg: "used by more than one file output",
+		},
+		{
+			name:   "duplicate path in paths",
+			files:  []FileOutput{{Path: "/tmp/a.yml"}, {Paths: []string{"/tmp/b.yml", "/tmp/a.yml"}}},
+			errMsg: "file output /tmp/a.yml: path is used by more than one file output",
+		},
+		{
+			name:   "empty paths entry",
+			files:  []FileOutput{{Path: "/tmp/a.yml", Paths: []string{""}}},
+			errMsg: "paths must not be empty",
+		},
+		{
+			name:   "negative startup grace",
+			files:  []FileOutput{{Path: "/tmp/a.yml", StartupGrace: -time.Second}},
+			errMsg: "startup_grace must not be negative",
+		},
+		{
+			name:   "unsupported format",
+			files:  []FileOutput{{Path: "/tmp/a.toml", Format: "toml"}},
+			errMsg: "unsupported format",
+		},
+		{
+			name:   "unsupported layout",
+			files:  []FileOutput{{Path: "/tmp/a.yml", Layout: "per_router"}},
+			errMsg: "unsupported layout",
+		},
+		{
+			name:   "per upstream documents in json",
+			files:  []FileOutput{{Path: "/tmp/a.json", Format: "json", Layout: LayoutPerUpstreamDocuments}},
+			errMsg: "requires the yaml format",
+		},
+		{
+			name:   "invalid name pattern",
+			files:  []FileOutput{{Path: "/tmp/a.yml", Selector: FileSelector{Names: []string{"[bad"}}}},
+			errMsg: "invalid selector name pattern",
+		},
+		{
+			name:   "invalid mode",
+			files:  []FileOutput{{Path: "/tmp/a.yml", Mode: "rw-r-----"}},
+			errMsg: "mode: invalid octal mode",
+		},
+		{
+			name:   "mode out of range",
+			files:  []FileOutput{{Path: "/tmp/a.yml", Mode: "4755"}},
+			errMsg: "outside of 0777",
+		},
+		{
+			name:   "invalid dir mode",
+			files:  []FileOutput{{Path: "/tmp/a.yml", DirMode: "0789"}},
+			errMsg: "dir_mode: invalid octal mode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Output.Files = tt.files
+
+			err := cfg.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestFileOutputModes(t *testing.T) {
+	f := FileOutput{}
+	assert.Equal(t, fs.FileMode(0644), f.FileMode())
+
+	dirMode, explicit := f.DirFileMode()
+	assert.Equal(t, fs.FileMode(0755), dirMode)
+	assert.False(t, explicit)
+
+	f = FileOutput{Mode: "0640", DirMode: "750"}
+	assert.Equal(t, fs.FileMode(0640), f.FileMode())
+
+	dirMode, explicit = f.DirFileMode()
+	assert.Equal(t, fs.FileMode(0750), dirMode)
+	assert.True(t, explicit)
+}
+
+func TestValidateUpstreamWeight(t *testing.T) {
+	cfg := validConfig()
+	cfg.Upstreams[0].Weight = -1
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "weight must not be negative")
+}
+
+func TestValidateServiceHealthCheck(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routers.Defaults.HealthCheck = &ServiceHealthCheck{Path: "/ping", Interval: 10 * time.Second}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Routers.Defaults.HealthCheck = &ServiceHealthCheck{Path: "ping", Timeout: -time.Second}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `routers.defaults.healthcheck: path "ping" must start with /`)
+}
+
+func TestValidateRouterLimits(t *testing.T) {
+	cfg := validConfig()
+	cfg.Upstreams[0].MaxRouters = -1
+	cfg.Routers.WarnThreshold = -1
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_routers must not be negative")
+	assert.Contains(t, err.Error(), "routers.warn_threshold must not be negative")
+}
+
+func TestWarningsProviderSuffixes(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routers.Defaults.EntryPoints = []string{"websecure", "web@docker"}
+	cfg.Routers.Defaults.Middlewares = []string{"auth@file", "compress"}
+	cfg.Routers.TCP.Defaults.EntryPoints = []string{"postgres@file"}
+
+	// The names are valid, only likely mistaken
+	require.NoError(t, cfg.Validate())
+
+	assert.Equal(t, []string{
+		`routers.defaults.entrypoints: "web@docker" has a provider suffix, entrypoint names have no provider suffix`,
+		`routers.defaults.middlewares: "auth@file" has a provider suffix, the federated Traefik must also have this provider`,
+		`routers.tcp.defaults.entrypoints: "postgres@file" has a provider suffix, entrypoint names have no provider suffix`,
+	}, cfg.Warnings())
+
+	cfg.Routers.Defaults.EntryPoints = []string{"websecure"}
+	cfg.Routers.Defaults.Middlewares = []string{"compress"}
+	cfg.Routers.TCP.Defaults.EntryPoints = nil
+	assert.Empty(t, cfg.Warnings())
+}
+
+func TestValidateTracing(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Tracing = Tracing{Enabled: true, Protocol: "zipkin", SampleRate: 2}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `server.tracing.protocol must be http or grpc, got "zipkin"`)
+	assert.Contains(t, err.Error(), "server.tracing.sample_rate must be between 0 and 1")
+
+	// Settings of disabled tracing are not checked
+	cfg.Server.Tracing.Enabled = false
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidateNamespaceServices(t *testing.T) {
+	namespace := false
+
+	cfg := validConfig()
+	cfg.Routers.NamespaceServices = &namespace
+	require.NoError(t, cfg.Validate())
+
+	cfg.Upstreams = append(cfg.Upstreams, Upstream{Name: "host2", AdminURL: "http://192.168.1.11:8080", ServerURL: "http://192.168.1.11:80"})
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routers.namespace_services can only be disabled with a single upstream")
+}
+
+func TestLoadWebhookMaxRetries(t *testing.T) {
+	for _, tt := range []struct {
+		setting  string
+		expected int
+	}{
+		{"", DefaultWebhookMaxRetries},
+		{"max_retries: 0", 0},
+		{"max_retries: 5", 5},
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`upstreams:
+  - name: host1
+    admin_url: http://192.168.1.10:8080
+    server_url: http://192.168.1.10:80
+output:
+  webhook:
+    enabled: true
+    url: http://hooks.example.com/traefik
+    `+tt.setting+`
+`), 0o600))
+
+		cfg, err := Load(path)
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+		assert.Equal(t, tt.expected, cfg.Output.Webhook.Retries(), tt.setting)
+	}
+}
+
+func TestValidateCAFile(t *testing.T) {
+	dir := t.TempDir()
+
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	server.Close()
+
+	validCA := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(validCA, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	invalidCA := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(invalidCA, []byte("not a certificate"), 0o600))
+
+	cfg := validConfig()
+	cfg.Upstreams[0].CAFile = validCA
+	require.NoError(t, cfg.Validate())
+
+	cfg.Upstreams[0].CAFile = invalidCA
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream host1: ca_file: no certificates found in "+invalidCA)
+
+	cfg.Upstreams[0].CAFile = filepath.Join(dir, "missing.pem")
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream host1: ca_file: open ")
+}
+
+func TestValidateMetadataModes(t *testing.T) {
+	cfg := validConfig()
+	cfg.Output.Metadata = MetadataOutput{Enabled: true, Path: "/var/lib/traefik-fed/federation.meta.json", Mode: "0640", DirMode: "rwx"}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `output.metadata.dir_mode: invalid octal mode "rwx"`)
+
+	cfg.Output.Metadata.DirMode = "0750"
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidateRuleTransforms(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routers.RuleTransforms = []RuleTransform{
+		{Type: RuleTransformHostRewrite, Hosts: map[string]string{".internal.lan": ".example.com"}},
+		{Type: RuleTransformPathPrefix, Prefix: "/app"},
+	}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Routers.RuleTransforms = []RuleTransform{
+		{Type: RuleTransformHostRewrite},
+		{Type: RuleTransformPathPrefix, Prefix: "app"},
+		{Type: "regex"},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routers.rule_transforms[0]: host_rewrite requires hosts")
+	assert.Contains(t, err.Error(), `routers.rule_transforms[1]: path_prefix: prefix "app" must start with /`)
+	assert.Contains(t, err.Error(), `routers.rule_transforms[2]: unsupported type "regex"`)
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := validConfig()
+	cfg.Upstreams = append(cfg.Upstreams,
+		Upstream{AdminURL: "192.168.1.11:8080", ServerURL: "http://192.168.1.11:80"},
+		Upstream{Name: "host3", AdminURL: "http://192.168.1.12:8080", Weight: -1},
+	)
+	cfg.Routers.Selector.RuleRegex = "(unclosed"
+	cfg.Output.Files = []FileOutput{{Path: "/tmp/a.yml", Mode: "rw-r-----", DirMode: "0789"}}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	messages := make([]string, len(validationErr.Problems))
+	for i, problem := range validationErr.Problems {
+		messages[i] = problem.Error()
+	}
+
+	require.Len(t, messages, 7)
+	assert.Equal(t, "upstream 1: name is required", messages[0])
+	assert.Contains(t, messages[1], "upstream 1: admin_url")
+	assert.Equal(t, "upstream host3: server_url is required", messages[2])
+	assert.Equal(t, "upstream host3: weight must not be negative", messages[3])
+	assert.Contains(t, messages[4], "routers.selector.rule_regex")
+	assert.Contains(t, messages[5], "file output /tmp/a.yml: mode")
+	assert.Contains(t, messages[6], "file output /tmp/a.yml: dir_mode")
+
+	assert.Regexp(t, `^7 problems: upstream 1: name is required; `, err.Error())
+}
+
+func TestValidateRedisOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		redis  RedisOutput
+		errMsg string
+	}{
+		{
+			name:   "missing address",
+			redis:  RedisOutput{Enabled: true, RootKey: "traefik"},
+			errMsg: "redis output address must be specified",
+		},
+		{
+			name:   "address without port",
+			redis:  RedisOutput{Enabled: true, Address: "redis", RootKey: "traefik"},
+			errMsg: "redis output address",
+		},
+		{
+			name:   "empty root key",
+			redis:  RedisOutput{Enabled: true, Address: "redis:6379", RootKey: "/"},
+			errMsg: "root_key must not be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Output.Redis = tt.redis
+
+			err := cfg.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+
+	cfg := validConfig()
+	cfg.Output.HTTP.Enabled = false
+	cfg.Output.File.Enabled = false
+	cfg.Output.Redis = RedisOutput{Enabled: true, Address: "redis:6379", RootKey: "traefik"}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateUpstreamMaxResponseBytes(t *testing.T) {
+	cfg := validConfig()
+	cfg.Upstreams[0].MaxResponseBytes = -1
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_response_bytes must not be negative")
+}
+
+func TestValidateHTTPTransport(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.HTTPTransport.MaxIdleConnsPerHost = -1
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.http_transport")
+}
+
+func TestValidateTCPAndUDPRouters(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routers.TCP.Selector.Exclude = []string{"["}
+	cfg.Routers.TCP.Defaults.TLS = &dynamic.RouterTCPTLSConfig{CertResolver: "bad resolver"}
+	cfg.Routers.UDP.Selector.RuleRegex = "("
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routers.tcp.selector.exclude: invalid pattern")
+	assert.Contains(t, err.Error(), "routers.tcp.defaults.tls.certResolver")
+	assert.Contains(t, err.Error(), "routers.udp.selector.rule_regex")
+}
+
+func TestValidateEntryPointSource(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routers.EntryPointSource = "resolved"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routers.entrypoint_source: must be entrypoints or using")
+}
+
+func TestValidateServiceAffixes(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routers.ServicePrefix = "fed-"
+	cfg.Routers.ServiceSuffix = "-svc"
+	require.NoError(t, cfg.Validate())
+
+	cfg.Routers.ServiceSuffix = "@file"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routers.service_suffix")
+}
+
+func TestValidateApplyDelay(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.ApplyDelay = -time.Second
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.apply_delay must not be negative")
+}
+
+func TestValidateUpstreamHealthCheck(t *testing.T) {
+	cfg := validConfig()
+	cfg.Upstreams[0].HealthCheck = &HealthCheck{Path: "ping"}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream host1: healthcheck: path \"ping\" must start with /")
+}
+
+func TestValidateHTTPSelectorPaths(t *testing.T) {
+	cfg := validConfig()
+	cfg.Output.HTTP.Paths = []HTTPPath{
+		{Path: "/config/edge", Selector: FileSelector{EntryPoints: []string{"websecure"}}},
+		{Path: "/config/edge"},
+		{Path: "internal"},
+		{Path: "/config/lan", Selector: FileSelector{Names: []string{"[lan"}}},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `output.http.paths[1]: "/config/edge" is already used by paths[0]`)
+	assert.Contains(t, err.Error(), `output.http.paths[2]: "internal" must start with /`)
+	assert.Contains(t, err.Error(), `output.http.paths[3]: invalid selector name pattern "[lan"`)
+
+	cfg.Output.HTTP.Paths = cfg.Output.HTTP.Paths[:1]
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidateHTTPPathsReservedEndpoints(t *testing.T) {
+	cfg := validConfig()
+	cfg.Output.HTTP.Debug = true
+	cfg.Output.HTTP.ReloadToken = "secret"
+	cfg.Output.HTTP.HealthPath = "/stats"
+	cfg.Output.HTTP.Paths = []HTTPPath{
+		{Path: "/routers"},
+		{Path: "/debug/upstream/host1"},
+		{Path: "/reload"},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `output.http.health_path: "/stats" is already used by the stats endpoint`)
+	assert.Contains(t, err.Error(), `output.http.paths[0]: "/routers" is already used by the routers endpoint`)
+	assert.Contains(t, err.Error(), `output.http.paths[1]: "/debug/upstream/host1" is already used by the debug upstream endpoint`)
+	assert.Contains(t, err.Error(), `output.http.paths[2]: "/reload" is already used by the reload endpoint`)
+
+	// Debug and reload endpoints are only reserved while enabled
+	cfg.Output.HTTP.Debug = false
+	cfg.Output.HTTP.ReloadToken = ""
+	cfg.Output.HTTP.HealthPath = ""
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidateHTTPHealthPaths(t *testing.T) {
+	cfg := validConfig()
+	cfg.Output.HTTP.ReadinessPath = "/config"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `output.http.readiness_path: "/config" is already used by path`)
+
+	cfg.Output.HTTP.ReadinessPath = "ready"
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must start with /")
+}
+
+func TestValidateObservabilityDefaults(t *testing.T) {
+	cfg := validConfig()
+	cfg.Routers.Defaults.Observability = &dynamic.RouterObservabilityConfig{TraceVerbosity: "detailed"}
+
+	require.NoError(t, cfg.Validate())
+
+	cfg.Routers.Defaults.Observability.TraceVerbosity = "verbose"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routers.defaults.observability.traceVerbosity")
+}
+
+func TestValidateUpstreamFixtureFile(t *testing.T) {
+	cfg := validConfig()
+	cfg.Upstreams[0].AdminURL = ""
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "admin_url or fixture_file is required")
+
+	cfg.Upstreams[0].FixtureFile = filepath.Join(t.TempDir(), "routers.json")
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream host1: fixture_file")
+
+	require.NoError(t, os.WriteFile(cfg.Upstreams[0].FixtureFile, []byte("[]"), 0600))
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidateCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name    string
+		breaker CircuitBreaker
+		errMsg  string
+	}{
+		{
+			name:    "disabled is not checked",
+			breaker: CircuitBreaker{},
+		},
+		{
+			name:    "valid",
+			breaker: CircuitBreaker{Enabled: true, FailThreshold: 3, CoolDown: time.Second, MaxCoolDown: time.Minute},
+		},
+		{
+			name:    "zero threshold",
+			breaker: CircuitBreaker{Enabled: true, CoolDown: time.Second, MaxCoolDown: time.Minute},
+			errMsg:  "fail_threshold must be at least 1",
+		},
+		{
+			name:    "max below cool down",
+			breaker: CircuitBreaker{Enabled: true, FailThreshold: 3, CoolDown: time.Minute, MaxCoolDown: time.Second},
+			errMsg:  "max_cool_down must not be less than cool_down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Server.CircuitBreaker = tt.breaker
+
+			err := cfg.Validate()
+			if tt.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestServerJitter(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{value: "", expected: 0, valid: true},
+		{value: "2s", expected: 2 * time.Second, valid: true},
+		{value: "10%", expected: time.Second, valid: true},
+		{value: "12.5%", expected: 1250 * time.Millisecond, valid: true},
+		{value: "150%", valid: false},
+		{value: "-1s", valid: false},
+		{value: "soon", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Server = ServerConfig{PollInterval: 10 * time.Second, PollJitter: tt.value}
+
+			err := cfg.Validate()
+			if !tt.valid {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "server.poll_jitter")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg.Server.Jitter(cfg.Server.PollInterval))
+		})
+	}
+}
+
+func TestUpstreamTargetURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		serverURL string
+		rewrite   *URLRewrite
+		expected  string
+	}{
+		{
+			name:      "no rewrite",
+			serverURL: "http://100.64.1.2:80",
+			expected:  "http://100.64.1.2:80",
+		},
+		{
+			name:      "host rewrite keeps port and path",
+			serverURL: "http://100.64.1.2:8000/base",
+			rewrite:   &URLRewrite{From: "100.64.1.2", To: "gateway.internal"},
+			expected:  "http://gateway.internal:8000/base",
+		},
+		{
+			name:      "host rewrite without port",
+			serverURL: "http://100.64.1.2",
+			rewrite:   &URLRewrite{From: "100.64.1.2", To: "gateway.internal"},
+			expected:  "http://gateway.internal",
+		},
+		{
+			name:      "replacement port and scheme",
+			serverURL: "http://100.64.1.2:80",
+			rewrite:   &URLRewrite{From: "100.64.1.2", To: "gateway.internal:8443", Scheme: "https"},
+			expected:  "https://gateway.internal:8443",
+		},
+		{
+			name:      "other host is untouched",
+			serverURL: "http://100.64.1.3:80",
+			rewrite:   &URLRewrite{From: "100.64.1.2", To: "gateway.internal"},
+			expected:  "http://100.64.1.3:80",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := Upstream{Name: "host1", ServerURL: tt.serverURL, ServerURLRewrite: tt.rewrite}
+
+			target, err := upstream.TargetURL()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, target)
+		})
+	}
+}
+
+func TestValidateServerURLRewrite(t *testing.T) {
+	cfg := validConfig()
+	cfg.Upstreams[0].ServerURLRewrite = &URLRewrite{From: "192.168.1.10"}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server_url_rewrite")
+}
+
+func TestUpstreamAPIURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		adminURL string
+		apiPath  string
+		expected string
+	}{
+		{name: "default", adminURL: "http://192.168.1.10:8080", expected: "http://192.168.1.10:8080/api"},
+		{name: "default with trailing slash", adminURL: "http://192.168.1.10:8080/", expected: "http://192.168.1.10:8080/api"},
+		{name: "admin url already has /api", adminURL: "http://192.168.1.10:8080/api", expected: "http://192.168.1.10:8080/api"},
+		{name: "admin url already has /api/", adminURL: "http://192.168.1.10:8080/api/", expected: "http://192.168.1.10:8080/api"},
+		{name: "custom path", adminURL: "https://proxy.internal", apiPath: "/traefik/api", expected: "https://proxy.internal/traefik/api"},
+		{name: "custom path without leading slash", adminURL: "https://proxy.internal", apiPath: "traefik/api/", expected: "https://proxy.internal/traefik/api"},
+		{name: "custom path already in admin url", adminURL: "https://proxy.internal/traefik/api", apiPath: "/traefik/api", expected: "https://proxy.internal/traefik/api"},
+		{name: "root path", adminURL: "https://proxy.internal/traefik-api", apiPath: "/", expected: "https://proxy.internal/traefik-api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := Upstream{AdminURL: tt.adminURL, APIPath: tt.apiPath}
+			assert.Equal(t, tt.expected, upstream.APIURL())
+		})
+	}
+}
+
+func TestUpstreamAPIURLs(t *testing.T) {
+	upstream := Upstream{
+		AdminURL:  "http://192.168.1.10:8080",
+		AdminURLs: []string{"http://192.168.1.11:8080/"},
+		APIPath:   "/traefik/api",
+	}
+	assert.Equal(t, []string{
+		"http://192.168.1.10:8080/traefik/api",
+		"http://192.168.1.11:8080/traefik/api",
+	}, upstream.APIURLs())
+
+	upstream.AdminURL = ""
+	assert.Equal(t, []string{"http://192.168.1.11:8080/traefik/api"}, upstream.APIURLs())
+}
+
+func TestValidateUpstreamAdminURLs(t *testing.T) {
+	cfg := validConfig()
+	cfg.Upstreams[0].AdminURL = ""
+	cfg.Upstreams[0].AdminURLs = []string{"http://192.168.1.10:8080", "192.168.1.11:8080"}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream host1: admin_urls[1]")
+
+	cfg.Upstreams[0].AdminURLs[1] = "http://192.168.1.11:8080"
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidateHTTPAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		valid   bool
+	}{
+		{address: "", valid: true},
+		{address: "127.0.0.1", valid: true},
+		{address: "::1", valid: true},
+		{address: "internal.example.com", valid: true},
+		{address: "127.0.0.1:8080", valid: false},
+		{address: "http://127.0.0.1", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Output.HTTP.Address = tt.address
+
+			err := cfg.Validate()
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "output.http.address")
+			}
+		})
+	}
+}
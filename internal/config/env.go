@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envPattern matches ${VAR} and ${VAR:-default} references
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnv replaces ${VAR} and ${VAR:-default} references in data with values
+// from the environment. The default is used when the variable is unset or empty.
+// Undefined variables without a default expand to an empty string, or fail
+// when strict is set.
+func expandEnv(data []byte, strict bool) ([]byte, error) {
+	var missing []string
+
+	expanded := envPattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		groups := envPattern.FindSubmatch(match)
+		name := string(groups[1])
+
+		value, defined := os.LookupEnv(name)
+
+		switch {
+		case value != "":
+			return []byte(value)
+		case groups[2] != nil:
+			return groups[3]
+		case !defined:
+			missing = append(missing, name)
+		}
+
+		return nil
+	})
+
+	if strict && len(missing) > 0 {
+		return nil, fmt.Errorf("undefined environment variables: %s", strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
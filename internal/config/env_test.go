@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("TFED_ADMIN_URL", "http://10.0.0.1:8080")
+	t.Setenv("TFED_EMPTY", "")
+
+	tests := []struct {
+		name     string
+		input    string
+		strict   bool
+		expected string
+		wantErr  bool
+	}{
+		{name: "defined", input: "admin_url: ${TFED_ADMIN_URL}", expected: "admin_url: http://10.0.0.1:8080"},
+		{name: "defined ignores default", input: "${TFED_ADMIN_URL:-http://fallback}", expected: "http://10.0.0.1:8080"},
+		{name: "defaulted", input: "port: ${TFED_UNDEFINED:-8080}", expected: "port: 8080"},
+		{name: "empty uses default", input: "${TFED_EMPTY:-fallback}", expected: "fallback"},
+		{name: "empty default", input: "path: '${TFED_UNDEFINED:-}'", strict: true, expected: "path: ''"},
+		{name: "undefined expands to empty", input: "url: '${TFED_UNDEFINED}'", expected: "url: ''"},
+		{name: "undefined in strict mode", input: "url: ${TFED_UNDEFINED}", strict: true, wantErr: true},
+		{name: "defined empty in strict mode", input: "url: '${TFED_EMPTY}'", strict: true, expected: "url: ''"},
+		{name: "plain dollar is kept", input: "rule: $TFED_ADMIN_URL", expected: "rule: $TFED_ADMIN_URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := expandEnv([]byte(tt.input), tt.strict)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "TFED_UNDEFINED")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestLoadExpandsEnv(t *testing.T) {
+	t.Setenv("TFED_ADMIN_URL", "http://10.0.0.1:8080")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`upstreams:
+  - name: host1
+    admin_url: ${TFED_ADMIN_URL}
+    server_url: ${TFED_SERVER_URL:-http://10.0.0.1:80}
+`), 0o600))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "http://10.0.0.1:8080", cfg.Upstreams[0].AdminURL)
+	assert.Equal(t, "http://10.0.0.1:80", cfg.Upstreams[0].ServerURL)
+
+	_, err = LoadWithOptions(path, LoadOptions{StrictEnv: true})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("upstreams:\n  - name: ${TFED_UNDEFINED}\n"), 0o600))
+
+	_, err = LoadWithOptions(path, LoadOptions{StrictEnv: true})
+	require.Error(t, err)
+}
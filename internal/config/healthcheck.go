@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HealthCheck configures an active probe of the upstream server_url, separate
+// from polling the admin API. Routers of an upstream failing the probe
+// consistently are left out of the served configuration.
+type HealthCheck struct {
+	Path          string        `yaml:"path"`           // HTTP path probed with HEAD; a TCP connect is used when empty
+	Interval      time.Duration `yaml:"interval"`       // Time between probes (default: the upstream poll interval)
+	Timeout       time.Duration `yaml:"timeout"`        // Timeout of a single probe (default: 2s)
+	FailThreshold int           `yaml:"fail_threshold"` // Consecutive failed probes before the upstream is skipped (default: 3)
+}
+
+// setHealthCheckDefaults applies defaults to an upstream health check
+func setHealthCheckDefaults(h *HealthCheck) {
+	if h.Timeout == 0 {
+		h.Timeout = 2 * time.Second
+	}
+
+	if h.FailThreshold == 0 {
+		h.FailThreshold = 3
+	}
+}
+
+// validate checks if the health check is valid
+func (h HealthCheck) validate() error {
+	if h.Path != "" && !strings.HasPrefix(h.Path, "/") {
+		return fmt.Errorf("path %q must start with /", h.Path)
+	}
+
+	if h.Interval < 0 || h.Timeout < 0 {
+		return fmt.Errorf("interval and timeout must not be negative")
+	}
+
+	if h.FailThreshold < 0 {
+		return fmt.Errorf("fail_threshold must not be negative")
+	}
+
+	return nil
+}
+
+// ServiceHealthCheck configures the health check Traefik runs against the
+// servers of generated services, so that the federated Traefik stops routing
+// to an upstream whose server_url stops answering
+type ServiceHealthCheck struct {
+	Path     string        `yaml:"path"`     // HTTP path requested on server_url
+	Interval time.Duration `yaml:"interval"` // Time between checks (default: Traefik's, 30s)
+	Timeout  time.Duration `yaml:"timeout"`  // Timeout of a single check (default: Traefik's, 5s)
+	Hostname string        `yaml:"hostname"` // Host header of the checks, to reach a router of the upstream Traefik (optional)
+}
+
+// validate checks if the service health check is valid
+func (h ServiceHealthCheck) validate() error {
+	if !strings.HasPrefix(h.Path, "/") {
+		return fmt.Errorf("path %q must start with /", h.Path)
+	}
+
+	if h.Interval < 0 || h.Timeout < 0 {
+		return fmt.Errorf("interval and timeout must not be negative")
+	}
+
+	return nil
+}
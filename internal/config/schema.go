@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SchemaVersion is the configuration schema version written by this release
+const SchemaVersion = 1
+
+// migrations upgrade a configuration by one schema version: migrations[i]
+// turns version i into version i+1 and returns a warning describing it
+var migrations = []func(cfg *Config) string{
+	0: func(*Config) string {
+		return fmt.Sprintf("configuration has no version field, assuming version %d; add \"version: %d\" to silence this warning",
+			SchemaVersion, SchemaVersion)
+	},
+}
+
+// migrate upgrades configurations written for older schema versions to the
+// current one, returning a warning per applied migration. Versions newer than
+// SchemaVersion are rejected since their fields may be misread.
+func migrate(cfg *Config) ([]string, error) {
+	if cfg.Version < 0 || cfg.Version > SchemaVersion {
+		return nil, fmt.Errorf("unsupported config version %d (supported: up to %d)", cfg.Version, SchemaVersion)
+	}
+
+	var warnings []string
+
+	for cfg.Version < SchemaVersion {
+		warnings = append(warnings, migrations[cfg.Version](cfg))
+		cfg.Version++
+	}
+
+	return warnings, nil
+}
+
+// Warnings returns the migration warnings collected while loading the
+// configuration, followed by warnings about valid but likely mistaken settings
+func (c *Config) Warnings() []string {
+	return slices.Concat(c.warnings, c.providerSuffixWarnings())
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSchemaVersion(t *testing.T) {
+	const upstreams = `
+upstreams:
+  - name: host1
+    admin_url: http://192.168.1.10:8080
+    server_url: http://192.168.1.10:80
+`
+
+	tests := []struct {
+		name     string
+		version  string
+		warnings int
+		wantErr  string
+	}{
+		{name: "current", version: "version: 1"},
+		{name: "unversioned", version: "", warnings: 1},
+		{name: "newer", version: "version: 2", wantErr: "unsupported config version 2"},
+		{name: "negative", version: "version: -1", wantErr: "unsupported config version -1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.version+upstreams), 0o600))
+
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, SchemaVersion, cfg.Version)
+			assert.Len(t, cfg.Warnings(), tt.warnings)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// BasicAuth holds HTTP basic auth credentials for an upstream admin API
+type BasicAuth struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"` // Read the password from this file, e.g. a mounted secret
+}
+
+// resolveSecrets reads the credentials referenced by *_file fields.
+// Trailing newlines are trimmed, and a file takes precedence over the inline value.
+func resolveSecrets(cfg *Config) error {
+	for i := range cfg.Upstreams {
+		upstream := &cfg.Upstreams[i]
+
+		if upstream.BasicAuth != nil && upstream.BasicAuth.PasswordFile != "" {
+			password, err := readSecretFile(upstream.BasicAuth.PasswordFile)
+			if err != nil {
+				return fmt.Errorf("upstream %s: basic_auth.password_file: %w", upstream.Name, err)
+			}
+
+			upstream.BasicAuth.Password = password
+		}
+
+		if upstream.BearerTokenFile != "" {
+			token, err := readSecretFile(upstream.BearerTokenFile)
+			if err != nil {
+				return fmt.Errorf("upstream %s: bearer_token_file: %w", upstream.Name, err)
+			}
+
+			upstream.BearerToken = token
+		}
+	}
+
+	if path := cfg.Output.HTTP.ReloadTokenFile; path != "" {
+		token, err := readSecretFile(path)
+		if err != nil {
+			return fmt.Errorf("output.http.reload_token_file: %w", err)
+		}
+
+		cfg.Output.HTTP.ReloadToken = token
+	}
+
+	if path := cfg.Output.Redis.PasswordFile; path != "" {
+		password, err := readSecretFile(path)
+		if err != nil {
+			return fmt.Errorf("output.redis.password_file: %w", err)
+		}
+
+		cfg.Output.Redis.Password = password
+	}
+
+	return nil
+}
+
+// readSecretFile reads a secret from a file, trimming trailing newlines
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
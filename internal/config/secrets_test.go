@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadReadsSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("s3cret\n"), 0o600))
+
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("tok3n"), 0o600))
+
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`upstreams:
+  - name: host1
+    admin_url: http://192.168.1.10:8080
+    server_url: http://192.168.1.10:80
+    basic_auth:
+      username: admin
+      password: inline
+      password_file: `+passwordFile+`
+  - name: host2
+    admin_url: http://192.168.1.11:8080
+    server_url: http://192.168.1.11:80
+    bearer_token_file: `+tokenFile+`
+output:
+  http:
+    reload_token_file: `+tokenFile+`
+  redis:
+    password_file: `+passwordFile+`
+`), 0o600))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	require.NotNil(t, cfg.Upstreams[0].BasicAuth)
+	assert.Equal(t, "admin", cfg.Upstreams[0].BasicAuth.Username)
+	assert.Equal(t, "s3cret", cfg.Upstreams[0].BasicAuth.Password)
+	assert.Equal(t, "tok3n", cfg.Upstreams[1].BearerToken)
+	assert.Equal(t, "tok3n", cfg.Output.HTTP.ReloadToken)
+	assert.Equal(t, "s3cret", cfg.Output.Redis.Password)
+
+	// Rotated secrets are picked up on the next load
+	require.NoError(t, os.WriteFile(tokenFile, []byte("rotated\n"), 0o600))
+
+	cfg, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", cfg.Upstreams[1].BearerToken)
+}
+
+func TestLoadMissingSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`upstreams:
+  - name: host1
+    bearer_token_file: /nonexistent/token
+`), 0o600))
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bearer_token_file")
+}
+
+func TestValidateBothCredentials(t *testing.T) {
+	cfg := validConfig()
+	cfg.Upstreams[0].BasicAuth = &BasicAuth{Username: "admin", Password: "secret"}
+	cfg.Upstreams[0].BearerToken = "token"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "basic_auth")
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// domainPattern matches a domain name, optionally with a leading wildcard label
+var domainPattern = regexp.MustCompile(`^(\*\.)?([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+
+// validateRouterTLS checks the TLS settings applied to generated routers.
+// An empty section is valid and enables TLS with the default certificate.
+func validateRouterTLS(tls *dynamic.RouterTLSConfig) error {
+	if tls == nil {
+		return nil
+	}
+
+	if tls.Options != "" {
+		name, provider, qualified := strings.Cut(tls.Options, "@")
+		if strings.ContainsAny(tls.Options, " \t") || name == "" || qualified && (provider == "" || strings.Contains(provider, "@")) {
+			return fmt.Errorf("options: invalid TLS options name %q, expected name or name@provider", tls.Options)
+		}
+	}
+
+	if strings.ContainsAny(tls.CertResolver, " \t@") {
+		return fmt.Errorf("certResolver: invalid resolver name %q", tls.CertResolver)
+	}
+
+	for i, domain := range tls.Domains {
+		if domain.Main == "" {
+			return fmt.Errorf("domains[%d].main is required", i)
+		}
+
+		for _, name := range append([]string{domain.Main}, domain.SANs...) {
+			if !domainPattern.MatchString(name) {
+				return fmt.Errorf("domains[%d]: invalid domain %q", i, name)
+			}
+		}
+	}
+
+	return nil
+}
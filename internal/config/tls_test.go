@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefik/traefik/v3/pkg/types"
+)
+
+func TestLoadRouterTLSDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`upstreams:
+  - name: host1
+    admin_url: http://192.168.1.10:8080
+    server_url: http://192.168.1.10:80
+routers:
+  defaults:
+    tls:
+      certResolver: letsencrypt
+      options: modern@file
+      domains:
+        - main: example.com
+          sans:
+            - "*.example.com"
+output:
+  http:
+    enabled: true
+    port: 8080
+`), 0o600))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	assert.Equal(t, &dynamic.RouterTLSConfig{
+		CertResolver: "letsencrypt",
+		Options:      "modern@file",
+		Domains:      []types.Domain{{Main: "example.com", SANs: []string{"*.example.com"}}},
+	}, cfg.Routers.Defaults.TLS)
+}
+
+func TestValidateRouterTLS(t *testing.T) {
+	tests := []struct {
+		name   string
+		tls    *dynamic.RouterTLSConfig
+		errMsg string
+	}{
+		{name: "unset"},
+		{name: "empty enables default certificate", tls: &dynamic.RouterTLSConfig{}},
+		{name: "options without provider", tls: &dynamic.RouterTLSConfig{Options: "modern"}},
+		{
+			name:   "options with empty provider",
+			tls:    &dynamic.RouterTLSConfig{Options: "modern@"},
+			errMsg: "routers.defaults.tls.options: invalid TLS options name",
+		},
+		{
+			name:   "resolver with provider",
+			tls:    &dynamic.RouterTLSConfig{CertResolver: "letsencrypt@file"},
+			errMsg: "routers.defaults.tls.certResolver: invalid resolver name",
+		},
+		{
+			name:   "domain without main",
+			tls:    &dynamic.RouterTLSConfig{Domains: []types.Domain{{SANs: []string{"www.example.com"}}}},
+			errMsg: "routers.defaults.tls.domains[0].main is required",
+		},
+		{
+			name:   "invalid san",
+			tls:    &dynamic.RouterTLSConfig{Domains: []types.Domain{{Main: "example.com", SANs: []string{"bad domain"}}}},
+			errMsg: `routers.defaults.tls.domains[0]: invalid domain "bad domain"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Routers.Defaults.TLS = tt.tls
+
+			err := cfg.Validate()
+			if tt.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Tracing protocols of the OTLP exporter
+const (
+	TracingProtocolHTTP = "http"
+	TracingProtocolGRPC = "grpc"
+)
+
+// DefaultTracingServiceName is the service name reported in traces
+const DefaultTracingServiceName = "traefik-fed"
+
+// Tracing configures exporting OpenTelemetry spans of poll cycles and
+// upstream requests over OTLP
+type Tracing struct {
+	Enabled     bool              `yaml:"enabled"`
+	Endpoint    string            `yaml:"endpoint"`     // Collector address, e.g. otel-collector:4318 (default: the OTEL_EXPORTER_OTLP_* environment variables)
+	Protocol    string            `yaml:"protocol"`     // http or grpc (default: http)
+	Insecure    bool              `yaml:"insecure"`     // Export without TLS
+	Headers     map[string]string `yaml:"headers"`      // Extra headers of export requests, e.g. for authentication
+	ServiceName string            `yaml:"service_name"` // service.name resource attribute (default: traefik-fed)
+	SampleRate  float64           `yaml:"sample_rate"`  // Fraction of poll cycles traced (default: 1)
+	Timeout     time.Duration     `yaml:"timeout"`      // Timeout of a single export (default: the exporter's, 10s)
+}
+
+// setTracingDefaults applies defaults to tracing
+func setTracingDefaults(t *Tracing) {
+	if t.Protocol == "" {
+		t.Protocol = TracingProtocolHTTP
+	}
+
+	if t.ServiceName == "" {
+		t.ServiceName = DefaultTracingServiceName
+	}
+
+	if t.SampleRate == 0 {
+		t.SampleRate = 1
+	}
+}
+
+// validate checks if the tracing configuration is valid
+func (t Tracing) validate() error {
+	var errs []error
+
+	switch t.Protocol {
+	case TracingProtocolHTTP, TracingProtocolGRPC:
+	default:
+		errs = append(errs, fmt.Errorf("server.tracing.protocol must be %s or %s, got %q", TracingProtocolHTTP, TracingProtocolGRPC, t.Protocol))
+	}
+
+	if t.SampleRate < 0 || t.SampleRate > 1 {
+		errs = append(errs, fmt.Errorf("server.tracing.sample_rate must be between 0 and 1, got %v", t.SampleRate))
+	}
+
+	if t.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("server.tracing.timeout must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Rule transform types
+const (
+	RuleTransformHostRewrite = "host_rewrite" // Substitute hosts in Host matchers, like rule_rewrite
+	RuleTransformPathPrefix  = "path_prefix"  // Scope the rule to a path prefix
+)
+
+// RuleTransform is a step of routers.rule_transforms, changing the rule of
+// every generated HTTP router. Which fields apply depends on the type.
+type RuleTransform struct {
+	Type   string            `yaml:"type"`
+	Hosts  map[string]string `yaml:"hosts"`  // host_rewrite: exact hosts or ".domain" suffixes and their replacements
+	Prefix string            `yaml:"prefix"` // path_prefix: path the rule is restricted to, e.g. /app
+}
+
+// validate checks if the rule transform is valid
+func (t RuleTransform) validate() error {
+	switch t.Type {
+	case RuleTransformHostRewrite:
+		if len(t.Hosts) == 0 {
+			return fmt.Errorf("%s requires hosts", t.Type)
+		}
+
+		var errs []error
+
+		for from, to := range t.Hosts {
+			if from == "" || to == "" {
+				errs = append(errs, fmt.Errorf("%s: hosts must not be empty, got %q: %q", t.Type, from, to))
+			}
+		}
+
+		return errors.Join(errs...)
+	case RuleTransformPathPrefix:
+		if !strings.HasPrefix(t.Prefix, "/") {
+			return fmt.Errorf("%s: prefix %q must start with /", t.Type, t.Prefix)
+		}
+
+		if strings.ContainsAny(t.Prefix, "`\"") {
+			return fmt.Errorf("%s: prefix %q must not contain quotes", t.Type, t.Prefix)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %q (supported: %s, %s)", t.Type, RuleTransformHostRewrite, RuleTransformPathPrefix)
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher watches the configuration file and emits a new Config whenever
+// its content changes and the new content is valid.
+//
+// The parent directory is watched rather than the file itself so that
+// atomic replacements (editors writing a temp file and renaming it, or
+// Kubernetes ConfigMap updates swapping the ..data symlink) are detected
+// even though the watched inode changes.
+type Watcher struct {
+	path    string
+	logger  *slog.Logger
+	watcher *fsnotify.Watcher
+	configs chan *Config
+	options LoadOptions
+	trigger chan struct{}
+
+	lastHash [sha256.Size]byte
+}
+
+// NewWatcher creates a new watcher for the configuration file at path
+func NewWatcher(path string, logger *slog.Logger) (*Watcher, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	if err := fsw.Add(filepath.Dir(absPath)); err != nil {
+		_ = fsw.Close()
+		return nil, fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	w := &Watcher{
+		path:    absPath,
+		logger:  logger,
+		watcher: fsw,
+		configs: make(chan *Config, 1),
+		trigger: make(chan struct{}, 1),
+	}
+
+	// Remember the current content so the first event only triggers a
+	// reload when something actually changed
+	if data, err := os.ReadFile(absPath); err == nil {
+		w.lastHash = sha256.Sum256(data)
+	}
+
+	return w, nil
+}
+
+// SetLoadOptions sets the options used when reloading the configuration
+func (w *Watcher) SetLoadOptions(opts LoadOptions) {
+	w.options = opts
+}
+
+// Reload asks Run to reload the configuration even if the file did not change,
+// e.g. to pick up rotated secret files
+func (w *Watcher) Reload() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+		// A reload is already pending
+	}
+}
+
+// Configs returns the channel of reloaded configurations
+func (w *Watcher) Configs() <-chan *Config {
+	return w.configs
+}
+
+// Run processes file system events until the context is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	defer func() {
+		_ = w.watcher.Close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			w.logger.Debug("config directory event", "name", event.Name, "op", event.Op.String())
+			w.reload(false)
+		case <-w.trigger:
+			w.logger.Info("reloading configuration on request")
+			w.reload(true)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			w.logger.Error("config watcher error", "error", err)
+		}
+	}
+}
+
+// reload reads the config file and emits it if the content changed, or force
+// is set, and it is valid. Invalid configurations are logged and the running
+// config is retained.
+func (w *Watcher) reload(force bool) {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		// The file may be briefly missing during an atomic swap
+		w.logger.Debug("config file not readable", "path", w.path, "error", err)
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	if hash == w.lastHash && !force {
+		return
+	}
+
+	w.lastHash = hash
+
+	cfg, err := LoadWithOptions(w.path, w.options)
+	if err != nil {
+		w.logger.Error("failed to reload configuration, keeping current", "error", err)
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		w.logger.Error("invalid reloaded configuration, keeping current", "error", err)
+		return
+	}
+
+	for _, warning := range cfg.Warnings() {
+		w.logger.Warn(warning)
+	}
+
+	// Replace any pending config that has not been consumed yet
+	select {
+	case <-w.configs:
+	default:
+	}
+
+	w.configs <- cfg
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfigYAML(pollInterval string) string {
+	return fmt.Sprintf(`upstreams:
+  - name: host1
+    admin_url: http://192.168.1.10:8080
+    server_url: http://192.168.1.10:80
+output:
+  http:
+    enabled: true
+    port: 8080
+server:
+  poll_interval: %s
+`, pollInterval)
+}
+
+func startWatcher(t *testing.T, path string) *Watcher {
+	t.Helper()
+
+	w, err := NewWatcher(path, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		_ = w.Run(ctx)
+	}()
+
+	return w
+}
+
+func receiveConfig(t *testing.T, w *Watcher) *Config {
+	t.Helper()
+
+	select {
+	case cfg := <-w.Configs():
+		return cfg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reloaded config")
+		return nil
+	}
+}
+
+func TestWatcherReloadsOnRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigYAML("10s")), 0644))
+
+	w := startWatcher(t, path)
+
+	require.NoError(t, os.WriteFile(path, []byte(testConfigYAML("20s")), 0644))
+
+	cfg := receiveConfig(t, w)
+	assert.Equal(t, 20*time.Second, cfg.Server.PollInterval)
+}
+
+func TestWatcherReloadsOnAtomicRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigYAML("10s")), 0644))
+
+	w := startWatcher(t, path)
+
+	tmpPath := filepath.Join(dir, "config.yaml.tmp")
+	require.NoError(t, os.WriteFile(tmpPath, []byte(testConfigYAML("30s")), 0644))
+	require.NoError(t, os.Rename(tmpPath, path))
+
+	cfg := receiveConfig(t, w)
+	assert.Equal(t, 30*time.Second, cfg.Server.PollInterval)
+}
+
+func TestWatcherReloadsOnConfigMapSwap(t *testing.T) {
+	// Mimic the Kubernetes ConfigMap volume layout:
+	// config.yaml -> ..data/config.yaml, ..data -> ..<timestamp>
+	dir := t.TempDir()
+
+	writeVersion := func(name, pollInterval string) {
+		versionDir := filepath.Join(dir, name)
+		require.NoError(t, os.Mkdir(versionDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(versionDir, "config.yaml"), []byte(testConfigYAML(pollInterval)), 0644))
+	}
+
+	writeVersion("..v1", "10s")
+	require.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "config.yaml"), filepath.Join(dir, "config.yaml")))
+
+	w := startWatcher(t, filepath.Join(dir, "config.yaml"))
+
+	writeVersion("..v2", "40s")
+	require.NoError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+
+	cfg := receiveConfig(t, w)
+	assert.Equal(t, 40*time.Second, cfg.Server.PollInterval)
+}
+
+func TestWatcherKeepsConfigOnInvalidReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigYAML("10s")), 0644))
+
+	w := startWatcher(t, path)
+
+	// Missing upstreams fails validation
+	require.NoError(t, os.WriteFile(path, []byte("upstreams: []\n"), 0644))
+
+	select {
+	case cfg := <-w.Configs():
+		t.Fatalf("unexpected config reload: %+v", cfg)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(testConfigYAML("50s")), 0644))
+
+	cfg := receiveConfig(t, w)
+	assert.Equal(t, 50*time.Second, cfg.Server.PollInterval)
+}
+
+func TestWatcherForcedReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigYAML("10s")), 0o600))
+
+	w := startWatcher(t, path)
+
+	// A forced reload emits the config although the file is unchanged
+	w.Reload()
+
+	select {
+	case cfg := <-w.Configs():
+		assert.Equal(t, 10*time.Second, cfg.Server.PollInterval)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for forced reload")
+	}
+}
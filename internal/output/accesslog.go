@@ -0,0 +1,46 @@
+package output
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// statusRecorder wraps http.ResponseWriter to capture the status code and response size
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+// WriteHeader records the status code before writing it
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+
+	return n, err
+}
+
+// accessLogMiddleware logs every request at debug level
+func accessLogMiddleware(next http.Handler, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		logger.Debug("http request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"size", rec.size,
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr)
+	})
+}
@@ -0,0 +1,113 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"gopkg.in/yaml.v3"
+)
+
+// wireConfiguration mirrors dynamic.Configuration for serialization, always
+// emitting the http section with its routers, services and middlewares keys
+// so that strict consumers get a well-formed provider configuration
+type wireConfiguration struct {
+	HTTP *wireHTTPConfiguration    `json:"http" yaml:"http"`
+	TCP  *dynamic.TCPConfiguration `json:"tcp,omitempty" yaml:"tcp,omitempty"`
+	UDP  *dynamic.UDPConfiguration `json:"udp,omitempty" yaml:"udp,omitempty"`
+	TLS  *dynamic.TLSConfiguration `json:"tls,omitempty" yaml:"tls,omitempty"`
+}
+
+// wireHTTPConfiguration mirrors dynamic.HTTPConfiguration without omitting
+// empty routers, services and middlewares
+type wireHTTPConfiguration struct {
+	Routers           map[string]*dynamic.Router           `json:"routers" yaml:"routers"`
+	Services          map[string]*dynamic.Service          `json:"services" yaml:"services"`
+	Middlewares       map[string]*dynamic.Middleware       `json:"middlewares" yaml:"middlewares"`
+	Models            map[string]*dynamic.Model            `json:"models,omitempty" yaml:"models,omitempty"`
+	ServersTransports map[string]*dynamic.ServersTransport `json:"serversTransports,omitempty" yaml:"serversTransports,omitempty"`
+}
+
+// toWire converts the configuration to its serialized shape
+func toWire(config *dynamic.Configuration) *wireConfiguration {
+	httpConfig := &dynamic.HTTPConfiguration{}
+	if config.HTTP != nil {
+		httpConfig = config.HTTP
+	}
+
+	wire := &wireConfiguration{
+		HTTP: &wireHTTPConfiguration{
+			Routers:           httpConfig.Routers,
+			Services:          httpConfig.Services,
+			Middlewares:       httpConfig.Middlewares,
+			Models:            httpConfig.Models,
+			ServersTransports: httpConfig.ServersTransports,
+		},
+		TCP: config.TCP,
+		UDP: config.UDP,
+		TLS: config.TLS,
+	}
+
+	if wire.HTTP.Routers == nil {
+		wire.HTTP.Routers = map[string]*dynamic.Router{}
+	}
+
+	if wire.HTTP.Services == nil {
+		wire.HTTP.Services = map[string]*dynamic.Service{}
+	}
+
+	if wire.HTTP.Middlewares == nil {
+		wire.HTTP.Middlewares = map[string]*dynamic.Middleware{}
+	}
+
+	return wire
+}
+
+// EncodeYAML writes the configuration as YAML in Traefik dynamic configuration format.
+// Map keys are written in sorted order, so identical configurations encode to
+// identical bytes.
+func EncodeYAML(w io.Writer, config *dynamic.Configuration) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(toWire(config)); err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to close encoder: %w", err)
+	}
+
+	return nil
+}
+
+// EncodeYAMLDocuments writes the configuration of every upstream as a
+// separate YAML document, each starting with a comment naming the upstream
+func EncodeYAMLDocuments(w io.Writer, groups []aggregator.UpstreamConfiguration) error {
+	for _, group := range groups {
+		if _, err := fmt.Fprintf(w, "---\n# upstream: %s\n", group.Upstream); err != nil {
+			return fmt.Errorf("failed to write document separator: %w", err)
+		}
+
+		if err := EncodeYAML(w, group.Config); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EncodeJSON writes the configuration as indented JSON in Traefik dynamic configuration format.
+// Like EncodeYAML, map keys are written in sorted order.
+func EncodeJSON(w io.Writer, config *dynamic.Configuration) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(toWire(config)); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// shuffledConfig builds a configuration with many routers and services,
+// inserting them into the maps in a random order
+func shuffledConfig(names []string) *dynamic.Configuration {
+	cfg := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers:  map[string]*dynamic.Router{},
+			Services: map[string]*dynamic.Service{},
+		},
+	}
+
+	for _, i := range rand.Perm(len(names)) {
+		name := names[i]
+
+		cfg.HTTP.Routers[name] = &dynamic.Router{Rule: "Host(`" + name + ".example.com`)", Service: name}
+		cfg.HTTP.Services[name] = &dynamic.Service{
+			LoadBalancer: &dynamic.ServersLoadBalancer{
+				Servers: []dynamic.Server{{URL: "http://192.168.1.10:80"}},
+			},
+		}
+	}
+
+	return cfg
+}
+
+func TestEncodeDeterministic(t *testing.T) {
+	names := make([]string, 0, 50)
+	for i := range 50 {
+		names = append(names, fmt.Sprintf("host%d-app%d", i%3, i))
+	}
+
+	encoders := map[string]func(io.Writer, *dynamic.Configuration) error{
+		"yaml": EncodeYAML,
+		"json": EncodeJSON,
+	}
+
+	for format, encode := range encoders {
+		t.Run(format, func(t *testing.T) {
+			var first, second bytes.Buffer
+
+			require.NoError(t, encode(&first, shuffledConfig(names)))
+			require.NoError(t, encode(&second, shuffledConfig(names)))
+
+			assert.Equal(t, first.String(), second.String())
+		})
+	}
+}
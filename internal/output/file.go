@@ -1,113 +1,472 @@
 package output
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
 	"github.com/traefik/traefik/v3/pkg/config/dynamic"
-	"gopkg.in/yaml.v3"
 )
 
-// FileWriter writes the aggregated configuration to a file
+// FileWriter writes the aggregated configuration to one or more files with
+// identical content
 type FileWriter struct {
-	path     string
+	paths    []string
+	format   string
+	layout   string
 	interval time.Duration
+	debounce time.Duration
+	selector config.FileSelector
+	fileMode fs.FileMode
+	dirMode  fs.FileMode
+	chmodDir bool // Apply dirMode to an existing directory
+	fsync    bool // Flush the file and directory to disk on every write
 	logger   *slog.Logger
+	updates  chan fileUpdate // Single slot holding the most recent config
+
+	hashes map[string][sha256.Size]byte // Content last written to each path
+
+	startupGrace time.Duration // Empty configs are not written for this long after starting
+	started      time.Time
+	wroteRouters bool // A config with routers was written, empty ones are trusted since
+	emptyWarned  bool
+
+	failures int       // Consecutive failed writes
+	retryAt  time.Time // No write is attempted before this time after failures
+
+	openFile func(name string, flag int, perm fs.FileMode) (syncFile, error)
+}
+
+// syncFile is the part of *os.File used to write the file durably
+type syncFile interface {
+	io.Writer
+	Sync() error
+	Close() error
+}
+
+// openOSFile opens a file of the local filesystem
+func openOSFile(name string, flag int, perm fs.FileMode) (syncFile, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+// fileUpdate is a configuration queued for writing
+type fileUpdate struct {
+	config *dynamic.Configuration
+	groups []aggregator.UpstreamConfiguration // Grouping of config by upstream, nil when unknown
 }
 
 // NewFileWriter creates a new file writer
-func NewFileWriter(path string, interval time.Duration, logger *slog.Logger) *FileWriter {
+func NewFileWriter(cfg config.FileOutput, logger *slog.Logger) *FileWriter {
+	dirMode, chmodDir := cfg.DirFileMode()
+
 	return &FileWriter{
-		path:     path,
-		interval: interval,
-		logger:   logger,
+		paths:    cfg.AllPaths(),
+		format:   cfg.Format,
+		layout:   cfg.Layout,
+		interval: cfg.Interval,
+		debounce: cfg.Debounce,
+		selector: cfg.Selector,
+		fileMode: cfg.FileMode(),
+		dirMode:  dirMode,
+		chmodDir: chmodDir,
+		fsync:    cfg.Fsync,
+		logger:   logger.With("path", strings.Join(cfg.AllPaths(), ",")),
+		updates:  make(chan fileUpdate, 1),
+		hashes:   make(map[string][sha256.Size]byte),
+		openFile: openOSFile,
+
+		startupGrace: cfg.StartupGrace,
 	}
 }
 
-// Start starts the periodic file writing
-func (w *FileWriter) Start(configChan <-chan *dynamic.HTTPConfiguration) error {
+// Name identifies the file writer in logs
+func (w *FileWriter) Name() string {
+	return "file:" + strings.Join(w.paths, ",")
+}
+
+// Update queues the configuration for Run, replacing any pending one
+func (w *FileWriter) Update(_ context.Context, dynConfig *dynamic.Configuration) error {
+	replacePending(w.updates, fileUpdate{config: dynConfig})
+	return nil
+}
+
+// UpdateUpstreams queues the configuration and its grouping by upstream for
+// Run, replacing any pending one
+func (w *FileWriter) UpdateUpstreams(_ context.Context, dynConfig *dynamic.Configuration, groups []aggregator.UpstreamConfiguration) error {
+	replacePending(w.updates, fileUpdate{config: dynConfig, groups: groups})
+	return nil
+}
+
+// WriteOnce writes the configuration right away, without Run, debounce or
+// startup grace. An empty configuration still never replaces an existing
+// non-empty file.
+func (w *FileWriter) WriteOnce(dynConfig *dynamic.Configuration, groups []aggregator.UpstreamConfiguration) error {
+	_, err := w.writeUpdate(fileUpdate{config: dynConfig, groups: groups})
+	return err
+}
+
+// Run runs the file writing loop on configurations received through Update
+// until ctx is cancelled, then writes the latest configuration one last time
+func (w *FileWriter) Run(ctx context.Context) error {
+	return w.start(ctx, w.updates)
+}
+
+// start runs the file writing loop.
+// Incoming configs are coalesced for the debounce duration and only written
+// when they differ from the last written config. The interval ticker acts as
+// a fallback that flushes any pending config and recreates a missing file.
+func (w *FileWriter) start(ctx context.Context, updates <-chan fileUpdate) error {
 	ticker := time.NewTicker(w.interval)
 	defer ticker.Stop()
 
-	var currentConfig *dynamic.HTTPConfiguration
+	w.started = time.Now()
 
-	// Write initial config if available
-	select {
-	case config := <-configChan:
-		currentConfig = config
-		if err := w.writeConfig(config); err != nil {
-			w.logger.Error("failed to write initial config", "error", err)
-		}
-	case <-time.After(1 * time.Second):
-		// No initial config yet
+	var (
+		latest    *fileUpdate
+		debounceC <-chan time.Time
+		graceC    <-chan time.Time
+	)
+
+	// Write an empty config held back during the grace once it is over
+	if w.startupGrace > 0 {
+		graceC = time.After(w.startupGrace)
 	}
 
 	for {
 		select {
-		case config := <-configChan:
-			currentConfig = config
-			if err := w.writeConfig(config); err != nil {
-				w.logger.Error("failed to write config", "error", err)
+		case <-ctx.Done():
+			w.flush(latest, updates)
+			return nil
+		case update := <-updates:
+			latest = &update
+			if debounceC == nil {
+				debounceC = time.After(w.debounce)
+			}
+		case <-debounceC:
+			debounceC = nil
+
+			w.attemptWrite(*latest, time.Now())
+		case <-graceC:
+			graceC = nil
+
+			if latest != nil && debounceC == nil {
+				w.attemptWrite(*latest, time.Now())
 			}
 		case <-ticker.C:
-			if currentConfig != nil {
-				if err := w.writeConfig(currentConfig); err != nil {
-					w.logger.Error("failed to write config on timer", "error", err)
+			if latest == nil {
+				continue
+			}
+
+			for _, path := range w.paths {
+				if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+					delete(w.hashes, path)
 				}
 			}
+
+			w.attemptWrite(*latest, time.Now())
 		}
 	}
 }
 
-// writeConfig writes the configuration to the file
-func (w *FileWriter) writeConfig(config *dynamic.HTTPConfiguration) error {
-	// Ensure directory exists
-	dir := filepath.Dir(w.path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+// flush writes the most recent configuration, including one still queued,
+// skipping the debounce and any failure backoff. It is called on shutdown so
+// that the file is not left behind the last published configuration.
+func (w *FileWriter) flush(latest *fileUpdate, updates <-chan fileUpdate) {
+	select {
+	case update := <-updates:
+		latest = &update
+	default:
 	}
 
-	// Write to temporary file first
-	tmpPath := w.path + ".tmp"
+	if latest == nil {
+		return
+	}
 
-	f, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+	if _, err := w.writeUpdate(*latest); err != nil {
+		w.logger.Error("failed to write config on shutdown", "error", err)
 	}
+}
 
-	defer func() {
-		_ = f.Close()
-	}()
+// Delays before writing again after consecutive failures
+const (
+	minWriteBackoff = time.Second
+	maxWriteBackoff = 5 * time.Minute
+)
+
+// attemptWrite writes the configuration unless a previous failure is still
+// backing off. Consecutive failures double the delay before the next attempt,
+// so that an unavailable directory does not flood the logs.
+func (w *FileWriter) attemptWrite(update fileUpdate, now time.Time) {
+	if now.Before(w.retryAt) {
+		w.logger.Debug("skipping file write while backing off", "retry_at", w.retryAt)
+		return
+	}
+
+	if _, err := w.writeUpdate(update); err != nil {
+		w.failures++
 
-	// Wrap in http key for Traefik format
-	output := map[string]interface{}{
-		"http": config,
+		backoff := min(minWriteBackoff<<min(w.failures-1, 16), maxWriteBackoff)
+		w.retryAt = now.Add(backoff)
+
+		w.logger.Error("failed to write config",
+			"failures", w.failures,
+			"retry_in", backoff,
+			"error", err)
+
+		return
 	}
 
-	encoder := yaml.NewEncoder(f)
-	encoder.SetIndent(2)
+	if w.failures > 0 {
+		w.logger.Info("file writes recovered", "failures", w.failures)
 
-	if err := encoder.Encode(output); err != nil {
-		return fmt.Errorf("failed to encode YAML: %w", err)
+		w.failures = 0
+		w.retryAt = time.Time{}
 	}
+}
+
+// writeUpdate writes the queued configuration in the layout of the file
+func (w *FileWriter) writeUpdate(update fileUpdate) (bool, error) {
+	if w.layout == config.LayoutPerUpstreamDocuments {
+		groups := update.groups
+		if groups == nil {
+			// Configurations queued through Update carry no grouping
+			groups = []aggregator.UpstreamConfiguration{{Config: update.config}}
+		}
 
-	if err := encoder.Close(); err != nil {
-		return fmt.Errorf("failed to close encoder: %w", err)
+		return w.writeDocuments(groups)
 	}
 
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("failed to close file: %w", err)
+	return w.writeConfig(update.config)
+}
+
+// writeConfig writes the configuration to the file if it differs from the
+// last written one. It reports whether the file was written.
+func (w *FileWriter) writeConfig(dynConfig *dynamic.Configuration) (bool, error) {
+	dynConfig = filterConfig(dynConfig, w.selector)
+
+	var buf bytes.Buffer
+
+	encode := EncodeYAML
+	if w.format == "json" {
+		encode = EncodeJSON
+	}
+
+	if err := encode(&buf, dynConfig); err != nil {
+		return false, err
+	}
+
+	return w.writeFile(buf.Bytes(), routerCount(dynConfig))
+}
+
+// writeDocuments writes the configuration of every upstream as a separate
+// YAML document if it differs from the last written file
+func (w *FileWriter) writeDocuments(groups []aggregator.UpstreamConfiguration) (bool, error) {
+	filtered := make([]aggregator.UpstreamConfiguration, len(groups))
+	routers := 0
+
+	for i, group := range groups {
+		filtered[i] = aggregator.UpstreamConfiguration{
+			Upstream: group.Upstream,
+			Config:   filterConfig(group.Config, w.selector),
+		}
+		routers += routerCount(filtered[i].Config)
+	}
+
+	var buf bytes.Buffer
+
+	if err := EncodeYAMLDocuments(&buf, filtered); err != nil {
+		return false, err
+	}
+
+	return w.writeFile(buf.Bytes(), routers)
+}
+
+// writeFile atomically replaces every file with data unless it is unchanged
+// since the last write to it. A failing path does not prevent writing the
+// others: the failures are returned together and only the failed paths are
+// written again on the next attempt. It reports whether any file was written.
+func (w *FileWriter) writeFile(data []byte, routers int) (bool, error) {
+	if routers == 0 && w.holdEmpty() {
+		return false, nil
+	}
+
+	hash := sha256.Sum256(data)
+
+	var (
+		written bool
+		errs    []error
+	)
+
+	for _, path := range w.paths {
+		if last, ok := w.hashes[path]; ok && last == hash {
+			continue
+		}
+
+		if err := w.writePath(path, data); err != nil {
+			if len(w.paths) > 1 {
+				err = fmt.Errorf("%s: %w", path, err)
+			}
+
+			errs = append(errs, err)
+
+			continue
+		}
+
+		w.hashes[path] = hash
+		written = true
+
+		if len(w.paths) > 1 {
+			w.logger.Info("wrote configuration to file", "file", path, "routers", routers)
+		} else {
+			w.logger.Info("wrote configuration to file", "routers", routers)
+		}
+	}
+
+	if !written && len(errs) == 0 {
+		w.logger.Debug("configuration unchanged, skipping file write")
+	}
+
+	if written && routers > 0 {
+		w.wroteRouters = true
+	}
+
+	return written, errors.Join(errs...)
+}
+
+// holdEmpty reports whether a configuration without routers must not be
+// written yet. Until the writer has written a configuration with routers, as
+// when upstreams are still unreachable after a restart, empty configurations
+// are held back during the startup grace and never replace an existing
+// non-empty file. Afterwards, an empty configuration is trusted.
+func (w *FileWriter) holdEmpty() bool {
+	if w.wroteRouters {
+		return false
+	}
+
+	if time.Since(w.started) < w.startupGrace {
+		w.logger.Debug("holding back empty configuration during startup grace", "startup_grace", w.startupGrace)
+		return true
+	}
+
+	for _, path := range w.paths {
+		if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+			if !w.emptyWarned {
+				w.logger.Warn("keeping existing file instead of replacing it with an empty configuration", "file", path)
+				w.emptyWarned = true
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// routerCount returns the number of HTTP, TCP and UDP routers of the configuration
+func routerCount(dynConfig *dynamic.Configuration) int {
+	count := 0
+
+	if dynConfig.HTTP != nil {
+		count += len(dynConfig.HTTP.Routers)
+	}
+
+	if dynConfig.TCP != nil {
+		count += len(dynConfig.TCP.Routers)
+	}
+
+	if dynConfig.UDP != nil {
+		count += len(dynConfig.UDP.Routers)
+	}
+
+	return count
+}
+
+// writePath atomically replaces the file at path with data
+func (w *FileWriter) writePath(path string, data []byte) error {
+	// Ensure directory exists
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, w.dirMode); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if w.chmodDir {
+		if err := os.Chmod(dir, w.dirMode); err != nil {
+			return fmt.Errorf("failed to set directory mode: %w", err)
+		}
+	}
+
+	// Write to temporary file first
+	tmpPath := path + ".tmp"
+
+	if err := w.writeTemp(tmpPath, data); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
 	}
 
 	// Atomic rename
-	if err := os.Rename(tmpPath, w.path); err != nil {
+	if err := os.Rename(tmpPath, path); err != nil {
 		return fmt.Errorf("failed to rename file: %w", err)
 	}
 
-	w.logger.Info("wrote configuration to file", "path", w.path)
+	// OpenFile is subject to the umask and keeps the mode of a leftover temp file
+	if err := os.Chmod(path, w.fileMode); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+
+	// Persist the rename itself, which is recorded in the directory
+	if w.fsync {
+		if err := w.syncDir(dir); err != nil {
+			return fmt.Errorf("failed to sync directory: %w", err)
+		}
+	}
 
 	return nil
 }
+
+// writeTemp writes data to the temp file, flushing it to disk before it is
+// renamed when fsync is enabled
+func (w *FileWriter) writeTemp(path string, data []byte) error {
+	f, err := w.openFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, w.fileMode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if w.fsync {
+		if err := f.Sync(); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to sync: %w", err)
+		}
+	}
+
+	return f.Close()
+}
+
+// syncDir flushes the directory entries to disk
+func (w *FileWriter) syncDir(dir string) error {
+	d, err := w.openFile(dir, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Sync(); err != nil {
+		_ = d.Close()
+		return err
+	}
+
+	return d.Close()
+}
@@ -0,0 +1,603 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"gopkg.in/yaml.v3"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testDynamicConfig(rule string) *dynamic.Configuration {
+	return &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers: map[string]*dynamic.Router{
+				"host1-webapp": {Rule: rule, Service: "host1-traefik"},
+			},
+			Services: map[string]*dynamic.Service{
+				"host1-traefik": {
+					LoadBalancer: &dynamic.ServersLoadBalancer{
+						Servers: []dynamic.Server{{URL: "http://192.168.1.10:80"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestFileWriterSkipsUnchangedConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	w := NewFileWriter(config.FileOutput{Path: path, Interval: time.Minute}, discardLogger())
+
+	written, err := w.writeConfig(testDynamicConfig("Host(`app.example.com`)"))
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	// Backdate the file so a rewrite would be observable
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path, past, past))
+
+	written, err = w.writeConfig(testDynamicConfig("Host(`app.example.com`)"))
+	require.NoError(t, err)
+	assert.False(t, written)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(past))
+}
+
+func TestFileWriterMultiplePaths(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a", "federation.yml")
+	second := filepath.Join(dir, "b", "federation.yml")
+
+	w := NewFileWriter(config.FileOutput{Paths: []string{first, second}, Interval: time.Minute}, discardLogger())
+
+	written, err := w.writeConfig(testDynamicConfig("Host(`app.example.com`)"))
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	firstData, err := os.ReadFile(first)
+	require.NoError(t, err)
+	secondData, err := os.ReadFile(second)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(firstData), "app.example.com")
+	assert.Equal(t, firstData, secondData)
+	assert.Equal(t, "file:"+first+","+second, w.Name())
+}
+
+func TestFileWriterMultiplePathsPartialFailure(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.yml")
+	bad := filepath.Join(dir, "bad.yml")
+
+	w := NewFileWriter(config.FileOutput{Path: good, Paths: []string{bad}, Interval: time.Minute}, discardLogger())
+
+	var (
+		opened    []string
+		available bool
+	)
+
+	w.openFile = func(name string, flag int, perm fs.FileMode) (syncFile, error) {
+		opened = append(opened, name)
+		if name == bad+".tmp" && !available {
+			return nil, fs.ErrPermission
+		}
+
+		return os.OpenFile(name, flag, perm)
+	}
+
+	// The failing path does not prevent writing the other one
+	written, err := w.writeConfig(testDynamicConfig("Host(`app.example.com`)"))
+	require.Error(t, err)
+	assert.True(t, written)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.Contains(t, err.Error(), bad+": failed to write temp file")
+	assert.NotContains(t, err.Error(), good)
+	assert.FileExists(t, good)
+	assert.NoFileExists(t, bad)
+
+	// Only the failed path is written again
+	available = true
+	opened = nil
+
+	written, err = w.writeConfig(testDynamicConfig("Host(`app.example.com`)"))
+	require.NoError(t, err)
+	assert.True(t, written)
+	assert.Equal(t, []string{bad + ".tmp"}, opened)
+
+	goodData, err := os.ReadFile(good)
+	require.NoError(t, err)
+	badData, err := os.ReadFile(bad)
+	require.NoError(t, err)
+	assert.Equal(t, goodData, badData)
+}
+
+func TestFileWriterAppliesModes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dynamic")
+	path := filepath.Join(dir, "federation.yml")
+
+	// Leave a temp file behind with broader permissions, as after a crash
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(path+".tmp", nil, 0666))
+
+	w := NewFileWriter(config.FileOutput{Path: path, Mode: "0640", DirMode: "0750", Interval: time.Minute}, discardLogger())
+
+	_, err := w.writeConfig(testDynamicConfig("Host(`app.example.com`)"))
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	info, err = os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+}
+
+func TestFileWriterWritesChangedConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	w := NewFileWriter(config.FileOutput{Path: path, Interval: time.Minute}, discardLogger())
+
+	written, err := w.writeConfig(testDynamicConfig("Host(`app.example.com`)"))
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	written, err = w.writeConfig(testDynamicConfig("Host(`new.example.com`)"))
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "new.example.com")
+	assert.NotContains(t, string(data), "app.example.com")
+}
+
+func TestFileWriterDebounceCoalesces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	w := NewFileWriter(config.FileOutput{
+		Path:     path,
+		Interval: time.Minute,
+		Debounce: 100 * time.Millisecond,
+	}, discardLogger())
+
+	updates := make(chan fileUpdate)
+
+	go func() {
+		_ = w.start(context.Background(), updates)
+	}()
+
+	updates <- fileUpdate{config: testDynamicConfig("Host(`first.example.com`)")}
+	updates <- fileUpdate{config: testDynamicConfig("Host(`last.example.com`)")}
+
+	// Nothing written until the debounce elapses
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(path)
+		return err == nil && strings.Contains(string(data), "last.example.com")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFileWriterWritesLatestOnShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	w := NewFileWriter(config.FileOutput{
+		Path:     path,
+		Interval: time.Hour,
+		Debounce: time.Hour,
+	}, discardLogger())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	require.NoError(t, w.Update(ctx, testDynamicConfig("Host(`app.example.com`)")))
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("file writer did not stop")
+	}
+
+	// Written before returning although the debounce never elapsed
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "app.example.com")
+}
+
+// emptyDynamicConfig returns a configuration without routers, as aggregated
+// while no upstream answers
+func emptyDynamicConfig() *dynamic.Configuration {
+	return &dynamic.Configuration{HTTP: &dynamic.HTTPConfiguration{}}
+}
+
+func TestFileWriterStartupGraceWaitsForSlowFirstConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	w := NewFileWriter(config.FileOutput{
+		Path:         path,
+		Interval:     time.Hour,
+		Debounce:     10 * time.Millisecond,
+		StartupGrace: time.Hour,
+	}, discardLogger())
+
+	go func() {
+		_ = w.Run(context.Background())
+	}()
+
+	// Upstreams not answering yet: nothing is written during the grace
+	require.NoError(t, w.Update(context.Background(), emptyDynamicConfig()))
+	time.Sleep(100 * time.Millisecond)
+	assert.NoFileExists(t, path)
+
+	// The first configuration with routers is written right away
+	require.NoError(t, w.Update(context.Background(), testDynamicConfig("Host(`app.example.com`)")))
+
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(path)
+		return err == nil && strings.Contains(string(data), "app.example.com")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFileWriterStartupGraceWritesEmptyConfigAfterwards(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	w := NewFileWriter(config.FileOutput{
+		Path:         path,
+		Interval:     time.Hour,
+		Debounce:     10 * time.Millisecond,
+		StartupGrace: 200 * time.Millisecond,
+	}, discardLogger())
+
+	go func() {
+		_ = w.Run(context.Background())
+	}()
+
+	require.NoError(t, w.Update(context.Background(), emptyDynamicConfig()))
+	time.Sleep(100 * time.Millisecond)
+	assert.NoFileExists(t, path)
+
+	// Without a previous file, the empty configuration is written once the grace is over
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFileWriterKeepsExistingFileOnEmptyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	require.NoError(t, os.WriteFile(path, []byte("http:\n  routers:\n    previous: {}\n"), 0644))
+
+	var logs bytes.Buffer
+
+	w := NewFileWriter(config.FileOutput{Path: path, Interval: time.Minute}, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// A restart aggregating nothing keeps the file of the previous run
+	written, err := w.writeConfig(emptyDynamicConfig())
+	require.NoError(t, err)
+	assert.False(t, written)
+	assert.Contains(t, logs.String(), "keeping existing file instead of replacing it with an empty configuration")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "previous")
+
+	written, err = w.writeConfig(testDynamicConfig("Host(`app.example.com`)"))
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	// Once routers were written, routers disappearing upstream empty the file
+	written, err = w.writeConfig(emptyDynamicConfig())
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "app.example.com")
+}
+
+func TestFileWriterUpdateKeepsLatest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	w := NewFileWriter(config.FileOutput{Path: path, Interval: time.Minute}, discardLogger())
+
+	// Updates never block, even before Run consumes them
+	require.NoError(t, w.Update(context.Background(), testDynamicConfig("Host(`first.example.com`)")))
+	require.NoError(t, w.Update(context.Background(), testDynamicConfig("Host(`last.example.com`)")))
+
+	go func() {
+		_ = w.Run(context.Background())
+	}()
+
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(path)
+		return err == nil && strings.Contains(string(data), "last.example.com")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFileWriterRapidUpdatesWriteLatest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	w := NewFileWriter(config.FileOutput{
+		Path:     path,
+		Interval: time.Minute,
+		Debounce: 20 * time.Millisecond,
+	}, discardLogger())
+
+	go func() {
+		_ = w.Run(context.Background())
+	}()
+
+	// Push updates faster than the writer consumes them
+	for i := range 50 {
+		rule := fmt.Sprintf("Host(`app%d.example.com`)", i)
+		require.NoError(t, w.Update(context.Background(), testDynamicConfig(rule)))
+	}
+
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(path)
+		return err == nil && strings.Contains(string(data), "app49.example.com")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestFileWritersWithSelectorsAreDisjoint(t *testing.T) {
+	dir := t.TempDir()
+	httpConfig := &dynamic.HTTPConfiguration{
+		Routers: map[string]*dynamic.Router{
+			"host1-webapp":  {Rule: "Host(`app.example.com`)", Service: "host1-traefik", EntryPoints: []string{"websecure"}},
+			"host1-admin":   {Rule: "Host(`admin.lan`)", Service: "host1-traefik", EntryPoints: []string{"internal"}},
+			"host2-api":     {Rule: "Host(`api.example.com`)", Service: "host2-traefik", EntryPoints: []string{"websecure"}},
+			"host2-grafana": {Rule: "Host(`grafana.lan`)", Service: "host2-traefik", EntryPoints: []string{"internal"}},
+		},
+		Services: map[string]*dynamic.Service{
+			"host1-traefik": {LoadBalancer: &dynamic.ServersLoadBalancer{Servers: []dynamic.Server{{URL: "http://192.168.1.10:80"}}}},
+			"host2-traefik": {LoadBalancer: &dynamic.ServersLoadBalancer{Servers: []dynamic.Server{{URL: "http://192.168.1.11:80"}}}},
+		},
+	}
+
+	edgePath := filepath.Join(dir, "edge.yml")
+	internalPath := filepath.Join(dir, "internal.json")
+
+	edge := NewFileWriter(config.FileOutput{
+		Path:     edgePath,
+		Format:   "yaml",
+		Selector: config.FileSelector{EntryPoints: []string{"websecure"}},
+	}, discardLogger())
+	internal := NewFileWriter(config.FileOutput{
+		Path:     internalPath,
+		Format:   "json",
+		Selector: config.FileSelector{EntryPoints: []string{"internal"}, Names: []string{"host1-*"}},
+	}, discardLogger())
+
+	dynConfig := &dynamic.Configuration{HTTP: httpConfig}
+
+	_, err := edge.writeConfig(dynConfig)
+	require.NoError(t, err)
+
+	_, err = internal.writeConfig(dynConfig)
+	require.NoError(t, err)
+
+	var edgeOutput struct {
+		HTTP dynamic.HTTPConfiguration `yaml:"http"`
+	}
+
+	data, err := os.ReadFile(edgePath)
+	require.NoError(t, err)
+	require.NoError(t, yaml.Unmarshal(data, &edgeOutput))
+
+	var internalOutput struct {
+		HTTP dynamic.HTTPConfiguration `json:"http"`
+	}
+
+	data, err = os.ReadFile(internalPath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &internalOutput))
+
+	assert.ElementsMatch(t, []string{"host1-webapp", "host2-api"}, slices.Collect(maps.Keys(edgeOutput.HTTP.Routers)))
+	assert.ElementsMatch(t, []string{"host1-traefik", "host2-traefik"}, slices.Collect(maps.Keys(edgeOutput.HTTP.Services)))
+	assert.ElementsMatch(t, []string{"host1-admin"}, slices.Collect(maps.Keys(internalOutput.HTTP.Routers)))
+	assert.ElementsMatch(t, []string{"host1-traefik"}, slices.Collect(maps.Keys(internalOutput.HTTP.Services)))
+
+	// The source config must not be modified by per-file filtering
+	assert.Len(t, httpConfig.Routers, 4)
+}
+
+func TestFileWriterPerUpstreamDocuments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	w := NewFileWriter(config.FileOutput{
+		Path:     path,
+		Interval: time.Minute,
+		Layout:   config.LayoutPerUpstreamDocuments,
+		Selector: config.FileSelector{Names: []string{"*-webapp"}},
+	}, discardLogger())
+
+	host2 := testDynamicConfig("Host(`api.example.com`)")
+	host2.HTTP.Routers = map[string]*dynamic.Router{
+		"host2-webapp": {Rule: "Host(`api.example.com`)", Service: "host2-traefik"},
+		"host2-admin":  {Rule: "Host(`admin.lan`)", Service: "host2-traefik"},
+	}
+	host2.HTTP.Services = map[string]*dynamic.Service{
+		"host2-traefik": {LoadBalancer: &dynamic.ServersLoadBalancer{Servers: []dynamic.Server{{URL: "http://192.168.1.11:80"}}}},
+	}
+
+	groups := []aggregator.UpstreamConfiguration{
+		{Upstream: "host1", Config: testDynamicConfig("Host(`app.example.com`)")},
+		{Upstream: "host2", Config: host2},
+	}
+
+	written, err := w.writeUpdate(fileUpdate{groups: groups})
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# upstream: host2")
+
+	var documents []dynamic.HTTPConfiguration
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		var document struct {
+			HTTP dynamic.HTTPConfiguration `yaml:"http"`
+		}
+
+		if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+
+		documents = append(documents, document.HTTP)
+	}
+
+	require.Len(t, documents, 2)
+	assert.ElementsMatch(t, []string{"host1-webapp"}, slices.Collect(maps.Keys(documents[0].Routers)))
+	assert.ElementsMatch(t, []string{"host1-traefik"}, slices.Collect(maps.Keys(documents[0].Services)))
+	assert.ElementsMatch(t, []string{"host2-webapp"}, slices.Collect(maps.Keys(documents[1].Routers)))
+	assert.ElementsMatch(t, []string{"host2-traefik"}, slices.Collect(maps.Keys(documents[1].Services)))
+
+	// Unchanged groups are not written again
+	written, err = w.writeUpdate(fileUpdate{groups: groups})
+	require.NoError(t, err)
+	assert.False(t, written)
+}
+
+// recordingFile records Sync calls on a file of the local filesystem
+type recordingFile struct {
+	*os.File
+	synced *[]string
+}
+
+func (f recordingFile) Sync() error {
+	*f.synced = append(*f.synced, f.Name())
+	return f.File.Sync()
+}
+
+func TestFileWriterFsync(t *testing.T) {
+	for _, fsync := range []bool{false, true} {
+		t.Run(fmt.Sprintf("fsync=%t", fsync), func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "federation.yml")
+			w := NewFileWriter(config.FileOutput{Path: path, Interval: time.Minute, Fsync: fsync}, discardLogger())
+
+			var synced []string
+
+			w.openFile = func(name string, flag int, perm fs.FileMode) (syncFile, error) {
+				f, err := os.OpenFile(name, flag, perm)
+				if err != nil {
+					return nil, err
+				}
+
+				return recordingFile{File: f, synced: &synced}, nil
+			}
+
+			_, err := w.writeConfig(testDynamicConfig("Host(`app.example.com`)"))
+			require.NoError(t, err)
+
+			if !fsync {
+				assert.Empty(t, synced)
+				return
+			}
+
+			// The temp file is synced before the rename, the directory after it
+			assert.Equal(t, []string{path + ".tmp", dir}, synced)
+		})
+	}
+}
+
+func TestFileWriterBackoffOnFailures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.yml")
+	w := NewFileWriter(config.FileOutput{Path: path, Interval: time.Minute}, discardLogger())
+
+	var (
+		attempts  int
+		available bool
+	)
+
+	w.openFile = func(name string, flag int, perm fs.FileMode) (syncFile, error) {
+		attempts++
+		if !available {
+			return nil, fs.ErrNotExist
+		}
+
+		return os.OpenFile(name, flag, perm)
+	}
+
+	update := fileUpdate{config: testDynamicConfig("Host(`app.example.com`)")}
+	start := time.Now()
+
+	// Each failure doubles the delay before the next attempt
+	w.attemptWrite(update, start)
+	assert.Equal(t, 1, attempts)
+
+	w.attemptWrite(update, start.Add(500*time.Millisecond))
+	assert.Equal(t, 1, attempts, "attempted while backing off")
+
+	w.attemptWrite(update, start.Add(time.Second))
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 2, w.failures)
+
+	w.attemptWrite(update, start.Add(2500*time.Millisecond))
+	assert.Equal(t, 2, attempts, "attempted while backing off")
+
+	available = true
+
+	w.attemptWrite(update, start.Add(3*time.Second))
+	assert.Equal(t, 3, attempts)
+	assert.Zero(t, w.failures)
+	assert.FileExists(t, path)
+
+	// Writes are no longer delayed after recovering
+	w.attemptWrite(fileUpdate{config: testDynamicConfig("Host(`new.example.com`)")}, start.Add(3*time.Second))
+	assert.Equal(t, 4, attempts)
+}
+
+func TestFileWriterEmptyConfigKeys(t *testing.T) {
+	for _, format := range []string{"yaml", "json"} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "federation."+format)
+			w := NewFileWriter(config.FileOutput{Path: path, Format: format, Interval: time.Minute}, discardLogger())
+
+			_, err := w.writeConfig(&dynamic.Configuration{HTTP: &dynamic.HTTPConfiguration{}})
+			require.NoError(t, err)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			// JSON is valid YAML, so both formats decode the same way
+			var decoded map[string]map[string]map[string]any
+			require.NoError(t, yaml.Unmarshal(data, &decoded))
+			require.Contains(t, decoded, "http")
+
+			for _, key := range []string{"routers", "services", "middlewares"} {
+				assert.Contains(t, decoded["http"], key)
+				assert.Empty(t, decoded["http"][key])
+			}
+		})
+	}
+}
@@ -1,66 +1,271 @@
 package output
 
 import (
+	"cmp"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
 	"github.com/traefik/traefik/v3/pkg/config/dynamic"
 	"gopkg.in/yaml.v3"
 )
 
 // HTTPServer serves the aggregated configuration via HTTP
 type HTTPServer struct {
-	port   int
-	path   string
-	logger *slog.Logger
+	address   string
+	port      int
+	path      string
+	accessLog bool
+	debug     bool
+	logger    *slog.Logger
 
-	mu     sync.RWMutex
-	config *dynamic.HTTPConfiguration
+	contentDisposition bool // Suggest a file name for the served config
+
+	h2c         bool          // Accept cleartext HTTP/2
+	idleTimeout time.Duration // Zero: idle keep-alive connections are kept open
+
+	healthPath    string
+	livenessPath  string
+	readinessPath string
+	readyMaxAge   time.Duration // Zero: any successful poll counts
+
+	reloadToken string        // Empty: POST /reload is disabled
+	reloads     chan chan int // Reload requests, answered with the router count
+
+	paths []config.HTTPPath // Endpoints serving the routers matching their selector
+
+	mu       sync.RWMutex
+	config   *dynamic.Configuration
+	groups   map[string]*dynamic.Configuration // Configuration of each upstream, by name
+	mappings []aggregator.UpstreamMapping
+	stats    aggregator.Stats
+
+	rawRouters map[string][]byte // Last /http/routers response of each upstream, by name
 }
 
 // NewHTTPServer creates a new HTTP server
-func NewHTTPServer(port int, path string, logger *slog.Logger) *HTTPServer {
+func NewHTTPServer(cfg config.HTTPOutput, logger *slog.Logger) *HTTPServer {
 	return &HTTPServer{
-		port:   port,
-		path:   path,
-		logger: logger,
-		config: &dynamic.HTTPConfiguration{},
+		address:   cfg.Address,
+		port:      cfg.Port,
+		path:      cfg.Path,
+		accessLog: cfg.AccessLog,
+		debug:     cfg.Debug,
+		logger:    logger,
+		config:    &dynamic.Configuration{HTTP: &dynamic.HTTPConfiguration{}},
+
+		healthPath:    cmp.Or(cfg.HealthPath, "/health"),
+		livenessPath:  cmp.Or(cfg.LivenessPath, "/livez"),
+		readinessPath: cmp.Or(cfg.ReadinessPath, "/readyz"),
+		readyMaxAge:   cfg.ReadyMaxAge,
+
+		contentDisposition: cfg.ContentDisposition,
+
+		h2c:         cfg.H2C,
+		idleTimeout: cfg.IdleTimeout,
+
+		reloadToken: cfg.ReloadToken,
+		reloads:     make(chan chan int),
+
+		paths: cfg.Paths,
 	}
 }
 
-// UpdateConfig updates the cached configuration
-func (s *HTTPServer) UpdateConfig(config *dynamic.HTTPConfiguration) {
+// Reloads returns the reload requests made through POST /reload. The receiver
+// must aggregate, publish and send the number of HTTP routers on the request.
+func (s *HTTPServer) Reloads() <-chan chan int {
+	return s.reloads
+}
+
+// Name identifies the HTTP server in logs
+func (s *HTTPServer) Name() string {
+	return "http"
+}
+
+// Update updates the served configuration
+func (s *HTTPServer) Update(_ context.Context, dynConfig *dynamic.Configuration) error {
+	s.UpdateConfig(dynConfig)
+	return nil
+}
+
+// UpdateConfig updates the cached configuration. A deep copy is stored, so
+// the caller may keep modifying its configuration while it is being served.
+func (s *HTTPServer) UpdateConfig(config *dynamic.Configuration) {
+	config = config.DeepCopy()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.config = config
 }
 
+// UpdateGroups updates the configurations of single upstreams served with the
+// upstream query parameter. Deep copies are stored, like in UpdateConfig.
+func (s *HTTPServer) UpdateGroups(groups []aggregator.UpstreamConfiguration) {
+	copies := make(map[string]*dynamic.Configuration, len(groups))
+	for _, group := range groups {
+		copies[group.Upstream] = group.Config.DeepCopy()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.groups = copies
+}
+
+// UpdateMappings updates the cached router mappings served by the debug endpoint
+func (s *HTTPServer) UpdateMappings(mappings []aggregator.UpstreamMapping) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mappings = mappings
+}
+
+// UpdateRawRouters updates the upstream /http/routers responses served by the
+// debug endpoint
+func (s *HTTPServer) UpdateRawRouters(raw map[string][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rawRouters = raw
+}
+
+// UpdateStats updates the cached aggregation statistics served by the stats endpoint
+func (s *HTTPServer) UpdateStats(stats aggregator.Stats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats = stats
+}
+
 // Start starts the HTTP server
 func (s *HTTPServer) Start() error {
+	listener, err := s.Listen()
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(listener)
+}
+
+// Listen opens the listener on the configured address and port.
+// An empty address listens on all interfaces.
+func (s *HTTPServer) Listen() (net.Listener, error) {
+	addr := net.JoinHostPort(s.address, strconv.Itoa(s.port))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	return listener, nil
+}
+
+// Serve serves all endpoints on the listener
+func (s *HTTPServer) Serve(listener net.Listener) error {
+	s.logger.Info("starting HTTP server", "addr", listener.Addr().String(), "path", s.path, "h2c", s.h2c)
+
+	return s.server().Serve(listener)
+}
+
+// server creates the HTTP server of all endpoints. HTTP/1.1 keep-alive is
+// always enabled; with h2c, clients may also speak HTTP/2 without TLS with
+// prior knowledge. The HTTP/1.1 Upgrade to h2c is not supported by net/http.
+func (s *HTTPServer) server() *http.Server {
+	server := &http.Server{
+		Handler:     s.Handler(),
+		IdleTimeout: s.idleTimeout,
+	}
+
+	if s.h2c {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+
+		server.Protocols = protocols
+	}
+
+	return server
+}
+
+// Handler returns the HTTP handler serving all endpoints
+func (s *HTTPServer) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc(s.path, s.handleConfig)
-	mux.HandleFunc("/health", s.handleHealth)
 
-	addr := fmt.Sprintf(":%d", s.port)
-	s.logger.Info("starting HTTP server", "addr", addr, "path", s.path)
+	for _, path := range s.paths {
+		mux.HandleFunc(path.Path, func(w http.ResponseWriter, r *http.Request) {
+			s.serveConfig(w, r, path.Selector)
+		})
+	}
+
+	mux.HandleFunc(s.healthPath, s.handleHealth)
+	mux.HandleFunc(s.livenessPath, s.handleHealth)
+	mux.HandleFunc(s.readinessPath, s.handleReady)
+	mux.HandleFunc("/stats", s.handleStats)
+
+	if s.debug {
+		mux.HandleFunc("/routers", s.handleRouters)
+		mux.HandleFunc("GET /debug/upstream/{name}", s.handleRawRouters)
+	}
+
+	if s.reloadToken != "" {
+		mux.HandleFunc("POST /reload", s.handleReload)
+	}
 
-	return http.ListenAndServe(addr, mux)
+	if s.accessLog {
+		return accessLogMiddleware(mux, s.logger)
+	}
+
+	return mux
 }
 
-// handleConfig serves the aggregated configuration
+// handleConfig serves the aggregated configuration, or only the part generated
+// from one upstream when the upstream query parameter is set
 func (s *HTTPServer) handleConfig(w http.ResponseWriter, r *http.Request) {
+	s.serveConfig(w, r, config.FileSelector{})
+}
+
+// serveConfig serves the routers of the aggregated configuration matching the
+// selector, or of the part generated from one upstream when the upstream
+// query parameter is set
+func (s *HTTPServer) serveConfig(w http.ResponseWriter, r *http.Request, selector config.FileSelector) {
+	upstream := r.URL.Query().Get("upstream")
+
 	s.mu.RLock()
 	config := s.config
+	if upstream != "" {
+		config = s.groups[upstream]
+	}
 	s.mu.RUnlock()
 
-	// Support both JSON and YAML based on Accept header
-	acceptHeader := r.Header.Get("Accept")
+	// Unknown upstreams and those left out of the last aggregation
+	if config == nil {
+		http.Error(w, fmt.Sprintf("no configuration from upstream %q", upstream), http.StatusNotFound)
+		return
+	}
+
+	config = filterConfig(config, selector)
+
+	// The format query parameter takes precedence over the Accept header
+	format := r.URL.Query().Get("format")
+	if format != formatJSON && format != formatYAML {
+		format = negotiateFormat(r.Header.Get("Accept"))
 
-	if acceptHeader == "application/json" || r.URL.Query().Get("format") == "json" {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	if format == formatJSON {
 		s.serveJSON(w, config)
 	} else {
 		s.serveYAML(w, config)
@@ -68,37 +273,158 @@ func (s *HTTPServer) handleConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 // serveJSON serves configuration as JSON
-func (s *HTTPServer) serveJSON(w http.ResponseWriter, config *dynamic.HTTPConfiguration) {
+func (s *HTTPServer) serveJSON(w http.ResponseWriter, config *dynamic.Configuration) {
 	w.Header().Set("Content-Type", "application/json")
+	s.setContentDisposition(w, "traefik-fed.json")
 
-	// Wrap in http key for Traefik format
-	output := map[string]interface{}{
-		"http": config,
-	}
-
-	if err := json.NewEncoder(w).Encode(output); err != nil {
+	if err := json.NewEncoder(w).Encode(toWire(config)); err != nil {
 		s.logger.Error("failed to encode JSON", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
 // serveYAML serves configuration as YAML
-func (s *HTTPServer) serveYAML(w http.ResponseWriter, config *dynamic.HTTPConfiguration) {
+func (s *HTTPServer) serveYAML(w http.ResponseWriter, config *dynamic.Configuration) {
 	w.Header().Set("Content-Type", "application/x-yaml")
+	s.setContentDisposition(w, "traefik-fed.yaml")
+
+	if err := yaml.NewEncoder(w).Encode(toWire(config)); err != nil {
+		s.logger.Error("failed to encode YAML", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
 
-	// Wrap in http key for Traefik format
-	output := map[string]interface{}{
-		"http": config,
+// setContentDisposition marks the response as a download of the given file
+// name when enabled
+func (s *HTTPServer) setContentDisposition(w http.ResponseWriter, filename string) {
+	if s.contentDisposition {
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
 	}
+}
 
-	if err := yaml.NewEncoder(w).Encode(output); err != nil {
-		s.logger.Error("failed to encode YAML", "error", err)
+// handleRouters serves the source-to-federated router mappings from the last aggregation
+func (s *HTTPServer) handleRouters(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	mappings := s.mappings
+	s.mu.RUnlock()
+
+	if mappings == nil {
+		mappings = []aggregator.UpstreamMapping{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(mappings); err != nil {
+		s.logger.Error("failed to encode JSON", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// handleRawRouters serves the /http/routers response last received from an
+// upstream as is
+func (s *HTTPServer) handleRawRouters(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	s.mu.RLock()
+	body, ok := s.rawRouters[name]
+	s.mu.RUnlock()
+
+	if !ok {
+		http.Error(w, fmt.Sprintf("no response from upstream %q", name), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_, _ = w.Write(body)
+}
+
+// handleStats serves the statistics of the last aggregation
+func (s *HTTPServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	stats := s.stats
+	s.mu.RUnlock()
+
+	if stats.Upstreams == nil {
+		stats.Upstreams = []aggregator.UpstreamStats{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		s.logger.Error("failed to encode JSON", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
 
-// handleHealth provides a health check endpoint
+// handleReload aggregates all upstreams immediately and reports the number of
+// HTTP routers served afterwards. Requests must carry the reload token.
+func (s *HTTPServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.reloadToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	reply := make(chan int, 1)
+
+	select {
+	case s.reloads <- reply:
+	case <-r.Context().Done():
+		return
+	}
+
+	var routers int
+
+	select {
+	case routers = <-reply:
+	case <-r.Context().Done():
+		return
+	}
+
+	s.logger.Info("reload completed", "routers", routers)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(map[string]int{"routers": routers}); err != nil {
+		s.logger.Error("failed to encode JSON", "error", err)
+	}
+}
+
+// handleHealth provides a liveness endpoint, answering as long as the process is up
 func (s *HTTPServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("OK"))
 }
+
+// handleReady provides a readiness endpoint, answering 503 until at least one
+// upstream was polled successfully within the ready max age
+func (s *HTTPServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !s.ready(time.Now()) {
+		http.Error(w, "no upstream polled successfully recently", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
+
+// ready reports whether an upstream currently served was polled successfully recently
+func (s *HTTPServer) ready(now time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, upstream := range s.stats.Upstreams {
+		if upstream.Error != "" || upstream.LastSuccess.IsZero() {
+			continue
+		}
+
+		if s.readyMaxAge <= 0 || now.Sub(upstream.LastSuccess) <= s.readyMaxAge {
+			return true
+		}
+	}
+
+	return false
+}
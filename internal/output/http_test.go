@@ -0,0 +1,547 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestHTTPServerAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config", AccessLog: true}, logger)
+	server.UpdateConfig(testDynamicConfig("Host(`app.example.com`)"))
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, logs.String(), "http request")
+	assert.Contains(t, logs.String(), "method=GET")
+	assert.Contains(t, logs.String(), "path=/config")
+	assert.Contains(t, logs.String(), "status=200")
+	assert.Contains(t, logs.String(), "duration=")
+	assert.NotContains(t, logs.String(), "size=0")
+}
+
+func TestHTTPServerAccessLogDisabled(t *testing.T) {
+	var logs bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config"}, logger)
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, logs.String(), "http request")
+}
+
+func TestHTTPServerRoutersEndpoint(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config", Debug: true}, discardLogger())
+	server.UpdateMappings([]aggregator.UpstreamMapping{
+		{
+			Upstream: "host1",
+			Routers: []aggregator.RouterMapping{
+				{Name: "webapp@docker", Provider: "docker", Status: "enabled", Included: true, Router: "host1-webapp", Service: "host1-traefik"},
+			},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/routers", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `[{
+		"upstream": "host1",
+		"routers": [{
+			"name": "webapp@docker",
+			"provider": "docker",
+			"status": "enabled",
+			"included": true,
+			"router": "host1-webapp",
+			"service": "host1-traefik"
+		}]
+	}]`, rec.Body.String())
+}
+
+func TestHTTPServerRoutersEndpointDisabled(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config"}, discardLogger())
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/routers", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHTTPServerRawRoutersEndpoint(t *testing.T) {
+	// Served byte for byte, including fields traefik-fed does not decode
+	raw := `[{"name":"webapp@docker","status":"enabled","rule":"Host(` + "`app.example.com`" + `)","unknown":1}]`
+
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config", Debug: true}, discardLogger())
+	server.UpdateRawRouters(map[string][]byte{"host1": []byte(raw)})
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/upstream/host1", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, raw, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/upstream/host2", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHTTPServerRawRoutersEndpointDisabled(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config"}, discardLogger())
+	server.UpdateRawRouters(map[string][]byte{"host1": []byte(`[]`)})
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/upstream/host1", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHTTPServerStatsEndpoint(t *testing.T) {
+	polledAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config"}, discardLogger())
+	server.UpdateStats(aggregator.Stats{
+		LastPoll:   polledAt,
+		DurationMs: 12.5,
+		Routers:    3,
+		Services:   2,
+		Upstreams: []aggregator.UpstreamStats{
+			{Upstream: "host1", LastPoll: polledAt, DurationMs: 12.5, Routers: 3, Services: 2},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var stats aggregator.Stats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.True(t, polledAt.Equal(stats.LastPoll))
+	assert.InDelta(t, 12.5, stats.DurationMs, 0.001)
+	assert.Equal(t, 3, stats.Routers)
+	assert.Equal(t, 2, stats.Services)
+	require.Len(t, stats.Upstreams, 1)
+	assert.Equal(t, "host1", stats.Upstreams[0].Upstream)
+}
+
+func TestHTTPServerContentNegotiation(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config"}, discardLogger())
+	server.UpdateConfig(testDynamicConfig("Host(`app.example.com`)"))
+
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		contentType string
+	}{
+		{name: "default", target: "/config", contentType: "application/x-yaml"},
+		{name: "quality values", target: "/config", accept: "application/json;q=0.9, application/x-yaml;q=0.8", contentType: "application/json"},
+		{name: "wildcard", target: "/config", accept: "*/*", contentType: "application/x-yaml"},
+		{name: "format overrides accept", target: "/config?format=yaml", accept: "application/json", contentType: "application/x-yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestHTTPServerH2C(t *testing.T) {
+	// A client speaking cleartext HTTP/2 with prior knowledge
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	serve := func(t *testing.T, h2c bool) string {
+		t.Helper()
+
+		server := NewHTTPServer(config.HTTPOutput{Address: "127.0.0.1", Port: 0, Path: "/config", H2C: h2c}, discardLogger())
+		server.UpdateConfig(testDynamicConfig("Host(`app.example.com`)"))
+
+		listener, err := server.Listen()
+		require.NoError(t, err)
+
+		go func() {
+			_ = server.Serve(listener)
+		}()
+		t.Cleanup(func() { _ = listener.Close() })
+
+		return "http://" + listener.Addr().String() + "/config"
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		resp, err := client.Get(serve(t, true))
+		require.NoError(t, err)
+
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, resp.ProtoMajor)
+		assert.Contains(t, string(body), "app.example.com")
+
+		// HTTP/1.1 clients keep working
+		resp, err = http.Get(serve(t, true))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, 1, resp.ProtoMajor)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		resp, err := client.Get(serve(t, false))
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+
+		require.Error(t, err)
+	})
+}
+
+func TestHTTPServerBindAddress(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{Address: "127.0.0.1", Port: 0, Path: "/config"}, discardLogger())
+	server.UpdateConfig(testDynamicConfig("Host(`app.example.com`)"))
+
+	listener, err := server.Listen()
+	require.NoError(t, err)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(func() { _ = listener.Close() })
+
+	host, _, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/config")
+	require.NoError(t, err)
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "app.example.com")
+}
+
+func TestHTTPServerEmptyConfigKeys(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config"}, discardLogger())
+
+	t.Run("json", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config?format=json", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"http": {"routers": {}, "services": {}, "middlewares": {}}}`, rec.Body.String())
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.YAMLEq(t, "http:\n  routers: {}\n  services: {}\n  middlewares: {}\n", rec.Body.String())
+	})
+}
+
+func TestHTTPServerContentDisposition(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		target   string
+		expected string
+	}{
+		{name: "yaml", enabled: true, target: "/config", expected: "attachment; filename=traefik-fed.yaml"},
+		{name: "json", enabled: true, target: "/config?format=json", expected: "attachment; filename=traefik-fed.json"},
+		{name: "disabled", enabled: false, target: "/config", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config", ContentDisposition: tt.enabled}, discardLogger())
+
+			rec := httptest.NewRecorder()
+			server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.expected, rec.Header().Get("Content-Disposition"))
+		})
+	}
+}
+
+func TestHTTPServerUpstreamFilter(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config"}, discardLogger())
+
+	host1 := testDynamicConfig("Host(`app.example.com`)")
+	host2 := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers:  map[string]*dynamic.Router{"host2-api": {Rule: "Host(`api.example.com`)", Service: "host2-traefik"}},
+			Services: map[string]*dynamic.Service{"host2-traefik": {LoadBalancer: &dynamic.ServersLoadBalancer{}}},
+		},
+	}
+
+	server.UpdateConfig(testDynamicConfig("Host(`all.example.com`)"))
+	server.UpdateGroups([]aggregator.UpstreamConfiguration{
+		{Upstream: "host1", Config: host1},
+		{Upstream: "host2", Config: host2},
+	})
+
+	t.Run("known upstream", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config?upstream=host2&format=json", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var served dynamic.Configuration
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+		assert.Equal(t, []string{"host2-api"}, slices.Collect(maps.Keys(served.HTTP.Routers)))
+		assert.Equal(t, []string{"host2-traefik"}, slices.Collect(maps.Keys(served.HTTP.Services)))
+	})
+
+	t.Run("unknown upstream", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config?upstream=host9", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"host9"`)
+	})
+
+	t.Run("all upstreams", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "all.example.com")
+	})
+}
+
+func TestHTTPServerSelectorPaths(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{
+		Port: 8080,
+		Path: "/config",
+		Paths: []config.HTTPPath{
+			{Path: "/config/edge", Selector: config.FileSelector{EntryPoints: []string{"websecure"}}},
+			{Path: "/config/internal", Selector: config.FileSelector{EntryPoints: []string{"internal"}}},
+		},
+	}, discardLogger())
+
+	server.UpdateConfig(&dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers: map[string]*dynamic.Router{
+				"host1-webapp":  {Rule: "Host(`app.example.com`)", Service: "host1-traefik", EntryPoints: []string{"websecure"}},
+				"host1-admin":   {Rule: "Host(`admin.lan`)", Service: "host1-traefik", EntryPoints: []string{"internal"}},
+				"host2-api":     {Rule: "Host(`api.example.com`)", Service: "host2-traefik", EntryPoints: []string{"websecure"}},
+				"host2-grafana": {Rule: "Host(`grafana.lan`)", Service: "host2-traefik", EntryPoints: []string{"internal"}},
+			},
+			Services: map[string]*dynamic.Service{
+				"host1-traefik": {LoadBalancer: &dynamic.ServersLoadBalancer{Servers: []dynamic.Server{{URL: "http://192.168.1.10:80"}}}},
+				"host2-traefik": {LoadBalancer: &dynamic.ServersLoadBalancer{Servers: []dynamic.Server{{URL: "http://192.168.1.11:80"}}}},
+			},
+		},
+	})
+
+	routers := func(t *testing.T, target string) []string {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var served dynamic.Configuration
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+
+		return slices.Sorted(maps.Keys(served.HTTP.Routers))
+	}
+
+	assert.Equal(t, []string{"host1-webapp", "host2-api"}, routers(t, "/config/edge?format=json"))
+	assert.Equal(t, []string{"host1-admin", "host2-grafana"}, routers(t, "/config/internal?format=json"))
+	assert.Len(t, routers(t, "/config?format=json"), 4)
+}
+
+func TestHTTPServerLivenessAndReadiness(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config", ReadyMaxAge: time.Minute}, discardLogger())
+	handler := server.Handler()
+
+	status := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rec.Code
+	}
+
+	assertProbes := func(ready int) {
+		t.Helper()
+
+		assert.Equal(t, http.StatusOK, status("/livez"))
+		assert.Equal(t, http.StatusOK, status("/health"))
+		assert.Equal(t, ready, status("/readyz"))
+	}
+
+	// Nothing polled yet
+	assertProbes(http.StatusServiceUnavailable)
+
+	// Only failing upstreams
+	server.UpdateStats(aggregator.Stats{Upstreams: []aggregator.UpstreamStats{
+		{Upstream: "host1", LastPoll: time.Now(), Error: "connection refused"},
+	}})
+	assertProbes(http.StatusServiceUnavailable)
+
+	// One upstream succeeded recently
+	server.UpdateStats(aggregator.Stats{Upstreams: []aggregator.UpstreamStats{
+		{Upstream: "host1", LastPoll: time.Now(), Error: "connection refused"},
+		{Upstream: "host2", LastPoll: time.Now(), LastSuccess: time.Now()},
+	}})
+	assertProbes(http.StatusOK)
+
+	// The last success is too old
+	stale := time.Now().Add(-2 * time.Minute)
+	server.UpdateStats(aggregator.Stats{Upstreams: []aggregator.UpstreamStats{
+		{Upstream: "host2", LastPoll: time.Now(), LastSuccess: stale, Error: "timeout"},
+	}})
+	assertProbes(http.StatusServiceUnavailable)
+}
+
+func TestHTTPServerCustomHealthPaths(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{
+		Port:          8080,
+		Path:          "/config",
+		HealthPath:    "/healthz",
+		LivenessPath:  "/-/live",
+		ReadinessPath: "/-/ready",
+	}, discardLogger())
+	handler := server.Handler()
+
+	for path, expected := range map[string]int{
+		"/healthz": http.StatusOK,
+		"/-/live":  http.StatusOK,
+		"/-/ready": http.StatusServiceUnavailable,
+		"/health":  http.StatusNotFound,
+		"/readyz":  http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, expected, rec.Code, path)
+	}
+}
+
+func TestHTTPServerReloadAuthentication(t *testing.T) {
+	status := func(server *HTTPServer, method, authorization string) int {
+		req := httptest.NewRequest(method, "/reload", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	// Disabled without a token
+	disabled := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config"}, discardLogger())
+	assert.Equal(t, http.StatusNotFound, status(disabled, http.MethodPost, "Bearer secret"))
+
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config", ReloadToken: "secret"}, discardLogger())
+	assert.Equal(t, http.StatusUnauthorized, status(server, http.MethodPost, ""))
+	assert.Equal(t, http.StatusUnauthorized, status(server, http.MethodPost, "Bearer wrong"))
+	assert.Equal(t, http.StatusUnauthorized, status(server, http.MethodPost, "Basic c2VjcmV0"))
+	assert.Equal(t, http.StatusMethodNotAllowed, status(server, http.MethodGet, "Bearer secret"))
+
+	go func() {
+		reply := <-server.Reloads()
+		reply <- 3
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/reload", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"routers": 3}`, rec.Body.String())
+}
+
+// Run with -race: the producer keeps mutating the configuration it handed
+// over while it is being served
+func TestHTTPServerUpdateConfigIsolatesProducer(t *testing.T) {
+	server := NewHTTPServer(config.HTTPOutput{Port: 8080, Path: "/config"}, discardLogger())
+	handler := server.Handler()
+
+	dynConfig := testDynamicConfig("Host(`app.example.com`)")
+	server.UpdateConfig(dynConfig)
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := range 200 {
+			name := "router-" + strconv.Itoa(i)
+			dynConfig.HTTP.Routers[name] = &dynamic.Router{Rule: "Host(`" + name + ".example.com`)", Service: "host1-traefik"}
+			dynConfig.HTTP.Routers["host1-webapp"].Rule = "Host(`" + name + ".example.com`)"
+
+			if i%20 == 0 {
+				server.UpdateConfig(dynConfig)
+			}
+		}
+	}()
+
+	for range 50 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config?format=json", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	<-done
+
+	// Later mutations are only visible once handed over again
+	dynConfig.HTTP.Routers["host1-webapp"].Rule = "Host(`changed.example.com`)"
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	assert.NotContains(t, rec.Body.String(), "changed.example.com")
+}
@@ -0,0 +1,146 @@
+package output
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+)
+
+// Metadata maps every generated router to the upstream routers it was created from.
+// Traefik routers carry no free-form labels, so this is kept in a sidecar file.
+type Metadata struct {
+	Routers map[string]*RouterMetadata `json:"routers"`
+}
+
+// RouterMetadata describes the origin of a generated router
+type RouterMetadata struct {
+	Service string         `json:"service"`
+	Sources []RouterSource `json:"sources"` // Several when identical routers were merged
+}
+
+// RouterSource identifies an upstream router
+type RouterSource struct {
+	Upstream string `json:"upstream"`
+	Router   string `json:"router"` // Name on the upstream, e.g. webapp@docker
+	Provider string `json:"provider"`
+}
+
+// BuildMetadata builds the metadata of the generated routers from the router mappings
+func BuildMetadata(mappings []aggregator.UpstreamMapping) *Metadata {
+	metadata := &Metadata{Routers: make(map[string]*RouterMetadata)}
+
+	for _, mapping := range mappings {
+		// Routers of an upstream left out of the configuration were not generated
+		if mapping.Error != "" {
+			continue
+		}
+
+		for _, router := range mapping.Routers {
+			if !router.Included {
+				continue
+			}
+
+			entry, ok := metadata.Routers[router.Router]
+			if !ok {
+				entry = &RouterMetadata{Service: router.Service}
+				metadata.Routers[router.Router] = entry
+			}
+
+			entry.Sources = append(entry.Sources, RouterSource{
+				Upstream: mapping.Upstream,
+				Router:   router.Name,
+				Provider: router.Provider,
+			})
+		}
+	}
+
+	return metadata
+}
+
+// MetadataWriter writes the router metadata to a JSON file
+type MetadataWriter struct {
+	path     string
+	fileMode fs.FileMode
+	dirMode  fs.FileMode
+	chmodDir bool // Apply dirMode to an existing directory
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	lastHash [sha256.Size]byte
+	written  bool
+}
+
+// NewMetadataWriter creates a new metadata writer
+func NewMetadataWriter(cfg config.MetadataOutput, logger *slog.Logger) *MetadataWriter {
+	dirMode, chmodDir := cfg.DirFileMode()
+
+	return &MetadataWriter{
+		path:     cfg.Path,
+		fileMode: cfg.FileMode(),
+		dirMode:  dirMode,
+		chmodDir: chmodDir,
+		logger:   logger.With("path", cfg.Path),
+	}
+}
+
+// Update writes the metadata of the given mappings when it changed since the last write
+func (w *MetadataWriter) Update(mappings []aggregator.UpstreamMapping) error {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(BuildMetadata(mappings)); err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	hash := sha256.Sum256(buf.Bytes())
+	if w.written && hash == w.lastHash {
+		return nil
+	}
+
+	dir := filepath.Dir(w.path)
+	if err := os.MkdirAll(dir, w.dirMode); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if w.chmodDir {
+		if err := os.Chmod(dir, w.dirMode); err != nil {
+			return fmt.Errorf("failed to set directory mode: %w", err)
+		}
+	}
+
+	tmpPath := w.path + ".tmp"
+
+	if err := os.WriteFile(tmpPath, buf.Bytes(), w.fileMode); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, w.path); err != nil {
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+
+	// WriteFile is subject to the umask and keeps the mode of a leftover temp file
+	if err := os.Chmod(w.path, w.fileMode); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+
+	w.lastHash = hash
+	w.written = true
+
+	w.logger.Debug("wrote router metadata")
+
+	return nil
+}
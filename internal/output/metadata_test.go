@@ -0,0 +1,101 @@
+package output
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMappings() []aggregator.UpstreamMapping {
+	return []aggregator.UpstreamMapping{
+		{
+			Upstream: "host1",
+			Routers: []aggregator.RouterMapping{
+				{Name: "webapp@docker", Provider: "docker", Status: "enabled", Included: true, Router: "webapp", Service: "webapp"},
+				{Name: "admin@docker", Provider: "docker", Status: "enabled", Included: true, Router: "host1-admin", Service: "host1-traefik"},
+				{Name: "old@docker", Provider: "docker", Status: "disabled"},
+			},
+		},
+		{
+			Upstream: "host2",
+			Routers: []aggregator.RouterMapping{
+				{Name: "webapp@file", Provider: "file", Status: "enabled", Included: true, Router: "webapp", Service: "webapp"},
+			},
+		},
+		{
+			Upstream: "host3",
+			Error:    "service \"traefik\" already defined by another upstream",
+			Routers: []aggregator.RouterMapping{
+				{Name: "api@docker", Provider: "docker", Status: "enabled", Included: true, Router: "host3-api", Service: "traefik"},
+			},
+		},
+	}
+}
+
+func TestBuildMetadata(t *testing.T) {
+	metadata := BuildMetadata(testMappings())
+
+	assert.Equal(t, map[string]*RouterMetadata{
+		"webapp": {
+			Service: "webapp",
+			Sources: []RouterSource{
+				{Upstream: "host1", Router: "webapp@docker", Provider: "docker"},
+				{Upstream: "host2", Router: "webapp@file", Provider: "file"},
+			},
+		},
+		"host1-admin": {
+			Service: "host1-traefik",
+			Sources: []RouterSource{{Upstream: "host1", Router: "admin@docker", Provider: "docker"}},
+		},
+	}, metadata.Routers)
+}
+
+func TestMetadataWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta", "federation.meta.json")
+	w := NewMetadataWriter(config.MetadataOutput{Enabled: true, Path: path}, discardLogger())
+
+	require.NoError(t, w.Update(testMappings()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var metadata Metadata
+	require.NoError(t, json.Unmarshal(data, &metadata))
+	require.Contains(t, metadata.Routers, "host1-admin")
+	assert.Equal(t, "host1-traefik", metadata.Routers["host1-admin"].Service)
+	assert.Equal(t, []RouterSource{{Upstream: "host1", Router: "admin@docker", Provider: "docker"}}, metadata.Routers["host1-admin"].Sources)
+
+	// Unchanged metadata is not rewritten
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path, past, past))
+	require.NoError(t, w.Update(testMappings()))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(past))
+}
+
+func TestMetadataWriterModes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "meta")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+
+	path := filepath.Join(dir, "federation.meta.json")
+	w := NewMetadataWriter(config.MetadataOutput{Enabled: true, Path: path, Mode: "0640", DirMode: "0750"}, discardLogger())
+
+	require.NoError(t, w.Update(testMappings()))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	info, err = os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+}
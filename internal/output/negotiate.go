@@ -0,0 +1,111 @@
+package output
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Output formats of the configuration endpoint
+const (
+	formatYAML = "yaml"
+	formatJSON = "json"
+)
+
+// formatMediaTypes lists the media types accepted for each output format
+var formatMediaTypes = map[string][]string{
+	formatJSON: {"application/json"},
+	formatYAML: {"application/x-yaml", "application/yaml", "text/yaml", "text/x-yaml"},
+}
+
+// negotiateFormat picks the output format best matching an Accept header.
+// Each format gets the quality of the most specific media range matching one
+// of its media types (exact type, then type/*, then */*); the format with the
+// highest quality wins. YAML is used when the header is empty, on ties and
+// when neither format is acceptable.
+func negotiateFormat(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return formatYAML
+	}
+
+	ranges := parseAccept(accept)
+
+	if formatQuality(ranges, formatJSON) > formatQuality(ranges, formatYAML) {
+		return formatJSON
+	}
+
+	return formatYAML
+}
+
+// mediaRange is a media range of an Accept header with its quality
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept parses the media ranges of an Accept header, skipping malformed
+// ones. Ranges without a valid q parameter have quality 1.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				continue
+			}
+
+			quality = parsed
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+
+	return ranges
+}
+
+// formatQuality returns the quality of the best media type of the format
+func formatQuality(ranges []mediaRange, format string) float64 {
+	best := 0.0
+
+	for _, mediaType := range formatMediaTypes[format] {
+		best = max(best, mediaTypeQuality(ranges, mediaType))
+	}
+
+	return best
+}
+
+// mediaTypeQuality returns the quality the most specific matching media range
+// gives to the media type, or 0 when no range matches
+func mediaTypeQuality(ranges []mediaRange, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+
+	quality, specificity := 0.0, 0
+
+	for _, r := range ranges {
+		var s int
+
+		switch r.mediaType {
+		case mediaType:
+			s = 3
+		case typ + "/*":
+			s = 2
+		case "*/*":
+			s = 1
+		default:
+			continue
+		}
+
+		if s > specificity {
+			quality, specificity = r.quality, s
+		}
+	}
+
+	return quality
+}
@@ -0,0 +1,36 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: formatYAML},
+		{accept: "application/json", want: formatJSON},
+		{accept: "application/x-yaml", want: formatYAML},
+		{accept: "application/json;q=0.9, application/x-yaml;q=0.8", want: formatJSON},
+		{accept: "application/json;q=0.5, application/yaml", want: formatYAML},
+		{accept: "text/yaml;q=0.4, application/*;q=0.6", want: formatYAML}, // application/yaml ties
+		{accept: "*/*", want: formatYAML},
+		{accept: "*/*;q=0.1, application/json", want: formatJSON},
+		{accept: "application/json, application/x-yaml", want: formatYAML},
+		{accept: "application/json;q=0, */*", want: formatYAML},
+		{accept: "application/json;q=0.8, text/*;q=0.9", want: formatYAML},
+		{accept: "application/json, text/*;q=0.5", want: formatJSON},
+		{accept: "text/html, application/xhtml+xml", want: formatYAML},
+		{accept: "application/json;q=high, text/yaml;q=0.2", want: formatYAML},
+		{accept: "Application/JSON", want: formatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			assert.Equal(t, tt.want, negotiateFormat(tt.accept))
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/redis/go-redis/v9"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// RedisWriter writes the aggregated configuration to Redis in the flat key
+// layout of Traefik's KV providers, e.g. traefik/http/routers/host1-webapp/rule
+type RedisWriter struct {
+	address  string
+	rootKey  string
+	debounce time.Duration
+	client   *redis.Client
+	logger   *slog.Logger
+	updates  chan *dynamic.Configuration
+
+	written map[string]string // Keys written last, nil before the first write
+
+	failures     int           // Consecutive failed writes
+	retryBackoff time.Duration // Delay before retrying after a first failure
+}
+
+// NewRedisWriter creates a new Redis writer
+func NewRedisWriter(cfg config.RedisOutput, logger *slog.Logger) *RedisWriter {
+	return &RedisWriter{
+		address:  cfg.Address,
+		rootKey:  strings.Trim(cfg.RootKey, "/"),
+		debounce: cfg.Debounce,
+		client: redis.NewClient(&redis.Options{
+			Addr:     cfg.Address,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+		logger:  logger.With("redis", cfg.Address),
+		updates: make(chan *dynamic.Configuration, 1),
+
+		retryBackoff: minWriteBackoff,
+	}
+}
+
+// Name identifies the Redis writer in logs
+func (w *RedisWriter) Name() string {
+	return "redis:" + w.address
+}
+
+// Update queues the configuration for Run, replacing any pending one
+func (w *RedisWriter) Update(_ context.Context, dynConfig *dynamic.Configuration) error {
+	replacePending(w.updates, dynConfig)
+	return nil
+}
+
+// Run writes queued configurations until ctx is cancelled.
+// Incoming configs are coalesced for the debounce duration and only written
+// when they differ from the last written config. Failed writes are retried
+// with the latest config, so Redis catches up once it is reachable again.
+func (w *RedisWriter) Run(ctx context.Context) error {
+	defer func() {
+		_ = w.client.Close()
+	}()
+
+	var (
+		latest    *dynamic.Configuration
+		debounceC <-chan time.Time
+		retryC    <-chan time.Time
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case dynConfig := <-w.updates:
+			latest = dynConfig
+			if debounceC == nil {
+				debounceC = time.After(w.debounce)
+			}
+		case <-debounceC:
+			debounceC = nil
+			retryC = w.attemptWrite(ctx, latest)
+		case <-retryC:
+			retryC = w.attemptWrite(ctx, latest)
+		}
+	}
+}
+
+// attemptWrite writes the configuration and returns a channel firing when a
+// failed write is to be retried, nil after a successful write. Consecutive
+// failures double the delay before the next attempt, like file writes.
+func (w *RedisWriter) attemptWrite(ctx context.Context, dynConfig *dynamic.Configuration) <-chan time.Time {
+	if _, err := w.write(ctx, dynConfig); err != nil {
+		w.failures++
+
+		backoff := min(w.retryBackoff<<min(w.failures-1, 16), maxWriteBackoff)
+
+		w.logger.Error("failed to write config to redis",
+			"failures", w.failures,
+			"retry_in", backoff,
+			"error", err)
+
+		return time.After(backoff)
+	}
+
+	if w.failures > 0 {
+		w.logger.Info("redis writes recovered", "failures", w.failures)
+
+		w.failures = 0
+	}
+
+	return nil
+}
+
+// ownedKeysKey returns the Redis set recording the keys written under the
+// root key. It lies outside the root key, so Traefik does not read it.
+func (w *RedisWriter) ownedKeysKey() string {
+	return "traefik-fed:owned-keys:" + w.rootKey
+}
+
+// write stores the configuration under the root key and deletes the keys of
+// routers and services that disappeared, in a single transaction. Only keys
+// recorded as written by traefik-fed are deleted, including those of a
+// previous run on the first write; other keys under the root key are kept.
+// It reports whether anything was written.
+func (w *RedisWriter) write(ctx context.Context, dynConfig *dynamic.Configuration) (bool, error) {
+	pairs, err := kvPairs(w.rootKey, dynConfig)
+	if err != nil {
+		return false, err
+	}
+
+	if w.written != nil && maps.Equal(pairs, w.written) {
+		w.logger.Debug("configuration unchanged, skipping redis write")
+		return false, nil
+	}
+
+	previous := w.written
+	if previous == nil {
+		owned, err := w.client.SMembers(ctx, w.ownedKeysKey()).Result()
+		if err != nil {
+			return false, fmt.Errorf("failed to list previously written keys: %w", err)
+		}
+
+		previous = make(map[string]string, len(owned))
+		for _, key := range owned {
+			previous[key] = ""
+		}
+	}
+
+	var stale []string
+
+	for key := range previous {
+		if _, ok := pairs[key]; !ok {
+			stale = append(stale, key)
+		}
+	}
+
+	_, err = w.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(stale) > 0 {
+			pipe.Del(ctx, stale...)
+		}
+
+		pipe.Del(ctx, w.ownedKeysKey())
+
+		if len(pairs) > 0 {
+			pipe.MSet(ctx, pairs)
+			pipe.SAdd(ctx, w.ownedKeysKey(), slices.Collect(maps.Keys(pairs)))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to write keys: %w", err)
+	}
+
+	w.written = pairs
+
+	w.logger.Info("wrote configuration to redis",
+		"routers", len(dynConfig.HTTP.Routers),
+		"keys", len(pairs),
+		"deleted", len(stale))
+
+	return true, nil
+}
+
+// allowEmptyFields are the options Traefik enables when present without any
+// setting, e.g. tls: {} on a router. Its KV providers read them from a key
+// holding "true", since an empty section has no keys of its own.
+var allowEmptyFields = map[string]bool{
+	"tls":           true,
+	"sticky":        true,
+	"cookie":        true,
+	"healthCheck":   true,
+	"spiffe":        true,
+	"compress":      true,
+	"contentType":   true,
+	"ipStrategy":    true,
+	"proxyProtocol": true,
+}
+
+// kvPairs flattens the configuration into the keys and values read by
+// Traefik's KV providers. Map entries and list indexes become key segments
+// below the root key. Empty maps and lists produce no keys, except empty
+// allowEmptyFields sections, which are set to "true".
+func kvPairs(rootKey string, dynConfig *dynamic.Configuration) (map[string]string, error) {
+	var buf bytes.Buffer
+
+	if err := EncodeJSON(&buf, dynConfig); err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(&buf)
+	decoder.UseNumber()
+
+	var tree any
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("failed to decode configuration: %w", err)
+	}
+
+	pairs := make(map[string]string)
+	flatten(pairs, rootKey, tree)
+
+	return pairs, nil
+}
+
+// flatten adds the leaves of the decoded JSON value below key to pairs
+func flatten(pairs map[string]string, key string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 && allowEmptyFields[path.Base(key)] {
+			pairs[key] = "true"
+		}
+
+		for name, child := range v {
+			flatten(pairs, key+"/"+name, child)
+		}
+	case []any:
+		for i, child := range v {
+			flatten(pairs, key+"/"+strconv.Itoa(i), child)
+		}
+	case nil:
+	case string:
+		pairs[key] = v
+	default:
+		pairs[key] = fmt.Sprint(v)
+	}
+}
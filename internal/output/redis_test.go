@@ -0,0 +1,147 @@
+package output
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestKVPairs(t *testing.T) {
+	dynConfig := testDynamicConfig("Host(`app.example.com`)")
+	dynConfig.HTTP.Routers["host1-webapp"].EntryPoints = []string{"web", "websecure"}
+	dynConfig.HTTP.Routers["host1-webapp"].Priority = 100
+
+	pairs, err := kvPairs("traefik", dynConfig)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"traefik/http/routers/host1-webapp/rule":                         "Host(`app.example.com`)",
+		"traefik/http/routers/host1-webapp/service":                      "host1-traefik",
+		"traefik/http/routers/host1-webapp/priority":                     "100",
+		"traefik/http/routers/host1-webapp/entryPoints/0":                "web",
+		"traefik/http/routers/host1-webapp/entryPoints/1":                "websecure",
+		"traefik/http/services/host1-traefik/loadBalancer/servers/0/url": "http://192.168.1.10:80",
+	}, pairs)
+}
+
+func TestRedisWriterDeletesStaleKeys(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	// Left by a previous run, and owned by someone else
+	require.NoError(t, server.Set("traefik/http/routers/host9-gone/rule", "Host(`gone.example.com`)"))
+	_, err := server.SetAdd("traefik-fed:owned-keys:traefik", "traefik/http/routers/host9-gone/rule")
+	require.NoError(t, err)
+	require.NoError(t, server.Set("traefik/http/middlewares/auth/basicAuth/users/0", "admin:hash"))
+	require.NoError(t, server.Set("other/key", "kept"))
+
+	w := NewRedisWriter(config.RedisOutput{Address: server.Addr(), RootKey: "traefik", Debounce: time.Millisecond}, discardLogger())
+	t.Cleanup(func() {
+		_ = w.client.Close()
+	})
+
+	dynConfig := testDynamicConfig("Host(`app.example.com`)")
+	dynConfig.HTTP.Routers["host1-api"] = &dynamic.Router{Rule: "Host(`api.example.com`)", Service: "host1-traefik"}
+
+	written, err := w.write(context.Background(), dynConfig)
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	server.CheckGet(t, "traefik/http/routers/host1-webapp/rule", "Host(`app.example.com`)")
+	server.CheckGet(t, "traefik/http/routers/host1-api/service", "host1-traefik")
+	server.CheckGet(t, "other/key", "kept")
+	server.CheckGet(t, "traefik/http/middlewares/auth/basicAuth/users/0", "admin:hash")
+	assert.False(t, server.Exists("traefik/http/routers/host9-gone/rule"))
+
+	// Unchanged configurations are not written again
+	written, err = w.write(context.Background(), dynConfig)
+	require.NoError(t, err)
+	assert.False(t, written)
+
+	delete(dynConfig.HTTP.Routers, "host1-api")
+
+	written, err = w.write(context.Background(), dynConfig)
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	assert.False(t, server.Exists("traefik/http/routers/host1-api/rule"))
+	assert.False(t, server.Exists("traefik/http/routers/host1-api/service"))
+	server.CheckGet(t, "traefik/http/routers/host1-webapp/rule", "Host(`app.example.com`)")
+	server.CheckGet(t, "traefik/http/middlewares/auth/basicAuth/users/0", "admin:hash")
+
+	// A new run still knows the keys written before
+	members, err := server.Members("traefik-fed:owned-keys:traefik")
+	require.NoError(t, err)
+	assert.Contains(t, members, "traefik/http/routers/host1-webapp/rule")
+	assert.NotContains(t, members, "traefik/http/routers/host1-api/rule")
+}
+
+func TestRedisWriterEmptySections(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	w := NewRedisWriter(config.RedisOutput{Address: server.Addr(), RootKey: "traefik", Debounce: time.Millisecond}, discardLogger())
+	t.Cleanup(func() {
+		_ = w.client.Close()
+	})
+
+	dynConfig := testDynamicConfig("Host(`app.example.com`)")
+	dynConfig.HTTP.Routers["host1-webapp"].TLS = &dynamic.RouterTLSConfig{}
+	dynConfig.HTTP.Services["host1-traefik"].LoadBalancer.Sticky = &dynamic.Sticky{Cookie: &dynamic.Cookie{}}
+
+	_, err := w.write(context.Background(), dynConfig)
+	require.NoError(t, err)
+
+	// Traefik enables sections holding "true" with their defaults
+	server.CheckGet(t, "traefik/http/routers/host1-webapp/tls", "true")
+	server.CheckGet(t, "traefik/http/services/host1-traefik/loadBalancer/sticky/cookie", "true")
+	assert.False(t, server.Exists("traefik/http/services/host1-traefik/loadBalancer/sticky"))
+}
+
+func TestRedisWriterRun(t *testing.T) {
+	server := miniredis.RunT(t)
+	w := NewRedisWriter(config.RedisOutput{Address: server.Addr(), RootKey: "/federation/", Debounce: 10 * time.Millisecond}, discardLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		_ = w.Run(ctx)
+	}()
+
+	require.NoError(t, w.Update(ctx, testDynamicConfig("Host(`app.example.com`)")))
+
+	assert.Eventually(t, func() bool {
+		value, err := server.Get("federation/http/routers/host1-webapp/rule")
+		return err == nil && value == "Host(`app.example.com`)"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRedisWriterRetriesFailedWrites(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.SetError("LOADING Redis is loading the dataset in memory")
+
+	w := NewRedisWriter(config.RedisOutput{Address: server.Addr(), RootKey: "traefik", Debounce: time.Millisecond}, discardLogger())
+	w.retryBackoff = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		_ = w.Run(ctx)
+	}()
+
+	// The config is only queued once, while Redis fails
+	require.NoError(t, w.Update(ctx, testDynamicConfig("Host(`app.example.com`)")))
+	time.Sleep(50 * time.Millisecond)
+	server.SetError("")
+
+	assert.Eventually(t, func() bool {
+		value, err := server.Get("traefik/http/routers/host1-webapp/rule")
+		return err == nil && value == "Host(`app.example.com`)"
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,113 @@
+package output
+
+import (
+	"path"
+	"slices"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// filterConfig returns a copy of the configuration containing only the
+// routers matching the selector and the services they reference
+func filterConfig(dynConfig *dynamic.Configuration, selector config.FileSelector) *dynamic.Configuration {
+	if len(selector.EntryPoints) == 0 && len(selector.Names) == 0 {
+		return dynConfig
+	}
+
+	filtered := &dynamic.Configuration{}
+
+	if dynConfig.HTTP != nil {
+		filtered.HTTP = &dynamic.HTTPConfiguration{
+			Routers:  make(map[string]*dynamic.Router),
+			Services: make(map[string]*dynamic.Service),
+		}
+
+		for name, router := range dynConfig.HTTP.Routers {
+			if !selectRouter(name, router.EntryPoints, selector) {
+				continue
+			}
+
+			filtered.HTTP.Routers[name] = router
+
+			if service, ok := dynConfig.HTTP.Services[router.Service]; ok {
+				filtered.HTTP.Services[router.Service] = service
+			}
+		}
+	}
+
+	if dynConfig.TCP != nil {
+		filtered.TCP = &dynamic.TCPConfiguration{
+			Routers:  make(map[string]*dynamic.TCPRouter),
+			Services: make(map[string]*dynamic.TCPService),
+		}
+
+		for name, router := range dynConfig.TCP.Routers {
+			if !selectRouter(name, router.EntryPoints, selector) {
+				continue
+			}
+
+			filtered.TCP.Routers[name] = router
+
+			if service, ok := dynConfig.TCP.Services[router.Service]; ok {
+				filtered.TCP.Services[router.Service] = service
+			}
+		}
+	}
+
+	if dynConfig.UDP != nil {
+		filtered.UDP = &dynamic.UDPConfiguration{
+			Routers:  make(map[string]*dynamic.UDPRouter),
+			Services: make(map[string]*dynamic.UDPService),
+		}
+
+		for name, router := range dynConfig.UDP.Routers {
+			if !selectRouter(name, router.EntryPoints, selector) {
+				continue
+			}
+
+			filtered.UDP.Routers[name] = router
+
+			if service, ok := dynConfig.UDP.Services[router.Service]; ok {
+				filtered.UDP.Services[router.Service] = service
+			}
+		}
+	}
+
+	return filtered
+}
+
+// selectRouter reports whether the router matches all criteria of the selector
+func selectRouter(name string, entryPoints []string, selector config.FileSelector) bool {
+	if len(selector.EntryPoints) > 0 && !hasAnyEntryPoint(entryPoints, selector.EntryPoints) {
+		return false
+	}
+
+	if len(selector.Names) > 0 && !matchesAnyName(name, selector.Names) {
+		return false
+	}
+
+	return true
+}
+
+// hasAnyEntryPoint reports whether any of the router entrypoints is selected
+func hasAnyEntryPoint(entryPoints, selected []string) bool {
+	for _, ep := range entryPoints {
+		if slices.Contains(selected, ep) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesAnyName reports whether name matches any of the glob patterns
+func matchesAnyName(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
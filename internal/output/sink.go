@@ -0,0 +1,48 @@
+package output
+
+import (
+	"context"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// Sink receives every aggregated configuration
+type Sink interface {
+	// Name identifies the sink in logs
+	Name() string
+	// Update hands the latest aggregated configuration to the sink.
+	// Implementations must not block on slow I/O.
+	Update(ctx context.Context, dynConfig *dynamic.Configuration) error
+}
+
+// UpstreamSink is a Sink that can also write the configuration grouped by
+// upstream. Publishing calls UpdateUpstreams instead of Update on it.
+type UpstreamSink interface {
+	Sink
+	// UpdateUpstreams hands the latest aggregated configuration to the sink
+	// together with its grouping by upstream
+	UpdateUpstreams(ctx context.Context, dynConfig *dynamic.Configuration, groups []aggregator.UpstreamConfiguration) error
+}
+
+var (
+	_ Sink         = (*HTTPServer)(nil)
+	_ UpstreamSink = (*FileWriter)(nil)
+	_ Sink         = (*WebhookSender)(nil)
+	_ Sink         = (*RedisWriter)(nil)
+)
+
+// replacePending queues update on a single-slot channel without blocking,
+// replacing any update that has not been consumed yet
+func replacePending[T any](updates chan T, update T) {
+	select {
+	case <-updates:
+	default:
+	}
+
+	select {
+	case updates <- update:
+	default:
+		// A concurrent update won the race; its config is as recent as ours
+	}
+}
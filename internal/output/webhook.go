@@ -0,0 +1,221 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// WebhookSender pushes the aggregated configuration to an HTTP endpoint
+// whenever it changes
+type WebhookSender struct {
+	url          string
+	method       string
+	headers      map[string]string
+	format       string
+	debounce     time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	client       *http.Client
+	logger       *slog.Logger
+	updates      chan *dynamic.Configuration
+
+	lastHash [sha256.Size]byte
+	sent     bool
+}
+
+// NewWebhookSender creates a new webhook sender
+func NewWebhookSender(cfg config.WebhookOutput, logger *slog.Logger) *WebhookSender {
+	return &WebhookSender{
+		url:          cfg.URL,
+		method:       cfg.Method,
+		headers:      cfg.Headers,
+		format:       cfg.Format,
+		debounce:     cfg.Debounce,
+		maxRetries:   cfg.Retries(),
+		retryBackoff: cfg.RetryBackoff,
+		client:       &http.Client{Timeout: cfg.Timeout},
+		logger:       logger.With("webhook", cfg.URL),
+		updates:      make(chan *dynamic.Configuration, 1),
+	}
+}
+
+// Name identifies the webhook in logs
+func (s *WebhookSender) Name() string {
+	return "webhook:" + s.url
+}
+
+// Update queues the configuration for Run, replacing any pending one
+func (s *WebhookSender) Update(_ context.Context, dynConfig *dynamic.Configuration) error {
+	replacePending(s.updates, dynConfig)
+	return nil
+}
+
+// Run sends queued configurations until ctx is cancelled, then flushes the
+// pending one. Incoming configs are coalesced for the debounce duration and
+// only sent when they differ from the last successfully sent config.
+func (s *WebhookSender) Run(ctx context.Context) error {
+	var (
+		latest    *dynamic.Configuration
+		debounceC <-chan time.Time
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush(context.WithoutCancel(ctx), latest)
+			return nil
+		case dynConfig := <-s.updates:
+			latest = dynConfig
+			if debounceC == nil {
+				debounceC = time.After(s.debounce)
+			}
+		case <-debounceC:
+			debounceC = nil
+
+			if _, err := s.send(ctx, latest); err != nil {
+				s.logger.Error("failed to send config", "error", err)
+			}
+		}
+	}
+}
+
+// flush sends the most recent configuration, including one still queued,
+// skipping the debounce. It is called on shutdown and makes a single attempt,
+// bounded by the request timeout, since retries would outlast the shutdown.
+func (s *WebhookSender) flush(ctx context.Context, latest *dynamic.Configuration) {
+	select {
+	case dynConfig := <-s.updates:
+		latest = dynConfig
+	default:
+	}
+
+	if latest == nil {
+		return
+	}
+
+	s.maxRetries = 0
+
+	if _, err := s.send(ctx, latest); err != nil {
+		s.logger.Error("failed to send config on shutdown, discarding it", "error", err)
+	}
+}
+
+// send pushes the configuration if it differs from the last sent one,
+// retrying with exponential backoff. It reports whether the config was sent.
+func (s *WebhookSender) send(ctx context.Context, dynConfig *dynamic.Configuration) (bool, error) {
+	var buf bytes.Buffer
+
+	encode, contentType := EncodeJSON, "application/json"
+	if s.format == "yaml" {
+		encode, contentType = EncodeYAML, "application/x-yaml"
+	}
+
+	if err := encode(&buf, dynConfig); err != nil {
+		return false, err
+	}
+
+	hash := sha256.Sum256(buf.Bytes())
+	if s.sent && hash == s.lastHash {
+		s.logger.Debug("configuration unchanged, skipping webhook")
+		return false, nil
+	}
+
+	backoff := s.retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := s.post(ctx, buf.Bytes(), contentType)
+		if err == nil {
+			break
+		}
+
+		if !retryable(err) {
+			return false, err
+		}
+
+		if attempt >= s.maxRetries {
+			return false, fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+
+		s.logger.Warn("webhook request failed, retrying",
+			"attempt", attempt+1,
+			"backoff", backoff,
+			"error", err)
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+
+	s.lastHash = hash
+	s.sent = true
+
+	s.logger.Info("sent configuration to webhook", "routers", len(dynConfig.HTTP.Routers))
+
+	return true, nil
+}
+
+// post performs a single webhook request, failing on non-2xx responses
+func (s *WebhookSender) post(ctx context.Context, body []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, s.method, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &statusError{status: resp.StatusCode, body: string(respBody)}
+	}
+
+	return nil
+}
+
+// statusError is a non-2xx webhook response
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook returned status %d: %s", e.status, e.body)
+}
+
+// retryable reports whether a failed request may succeed when sent again:
+// transport errors, 5xx and 429 responses. Other 4xx responses would fail
+// the same way.
+func retryable(err error) bool {
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+
+	return statusErr.status >= 500 || statusErr.status == http.StatusTooManyRequests
+}
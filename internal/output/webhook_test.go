@@ -0,0 +1,188 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver records request bodies and answers with the queued statuses,
+// then 200 once they are exhausted
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+	headers  []http.Header
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bodies = append(r.bodies, string(body))
+	r.headers = append(r.headers, req.Header.Clone())
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+
+	w.WriteHeader(status)
+}
+
+func (r *webhookReceiver) requests() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.bodies...)
+}
+
+func newWebhookSender(t *testing.T, receiver *webhookReceiver) *WebhookSender {
+	t.Helper()
+
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	retries := 2
+
+	return NewWebhookSender(config.WebhookOutput{
+		URL:          server.URL,
+		Method:       http.MethodPost,
+		Headers:      map[string]string{"Authorization": "Bearer secret"},
+		Format:       "json",
+		Debounce:     10 * time.Millisecond,
+		Timeout:      time.Second,
+		MaxRetries:   &retries,
+		RetryBackoff: 5 * time.Millisecond,
+	}, discardLogger())
+}
+
+func TestWebhookSenderPostsConfig(t *testing.T) {
+	receiver := &webhookReceiver{}
+	sender := newWebhookSender(t, receiver)
+	dynConfig := testDynamicConfig("Host(`app.example.com`)")
+
+	sent, err := sender.send(context.Background(), dynConfig)
+	require.NoError(t, err)
+	assert.True(t, sent)
+
+	var expected bytes.Buffer
+	require.NoError(t, EncodeJSON(&expected, dynConfig))
+
+	require.Len(t, receiver.requests(), 1)
+	assert.JSONEq(t, expected.String(), receiver.requests()[0])
+	assert.Equal(t, "application/json", receiver.headers[0].Get("Content-Type"))
+	assert.Equal(t, "Bearer secret", receiver.headers[0].Get("Authorization"))
+
+	// An unchanged config is not sent again
+	sent, err = sender.send(context.Background(), dynConfig)
+	require.NoError(t, err)
+	assert.False(t, sent)
+	assert.Len(t, receiver.requests(), 1)
+}
+
+func TestWebhookSenderRetriesOnFailure(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable}}
+	sender := newWebhookSender(t, receiver)
+
+	sent, err := sender.send(context.Background(), testDynamicConfig("Host(`app.example.com`)"))
+	require.NoError(t, err)
+	assert.True(t, sent)
+	assert.Len(t, receiver.requests(), 3)
+}
+
+func TestWebhookSenderRetriesOnlyTransientFailures(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusTooManyRequests, http.StatusBadRequest}}
+	sender := newWebhookSender(t, receiver)
+
+	// 429 is retried, other 4xx responses are not
+	_, err := sender.send(context.Background(), testDynamicConfig("Host(`app.example.com`)"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 400")
+	assert.Len(t, receiver.requests(), 2)
+}
+
+func TestWebhookSenderWithoutRetries(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable}}
+	sender := newWebhookSender(t, receiver)
+	sender.maxRetries = config.WebhookOutput{MaxRetries: new(int)}.Retries()
+
+	_, err := sender.send(context.Background(), testDynamicConfig("Host(`app.example.com`)"))
+	require.Error(t, err)
+	assert.Len(t, receiver.requests(), 1)
+}
+
+func TestWebhookSenderGivesUp(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{500, 500, 500, 500}}
+	sender := newWebhookSender(t, receiver)
+	dynConfig := testDynamicConfig("Host(`app.example.com`)")
+
+	_, err := sender.send(context.Background(), dynConfig)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 500")
+	assert.Len(t, receiver.requests(), 3)
+
+	// A failed config is retried on the next change notification
+	sent, err := sender.send(context.Background(), dynConfig)
+	require.NoError(t, err)
+	assert.True(t, sent)
+}
+
+func TestWebhookSenderRunDebounces(t *testing.T) {
+	receiver := &webhookReceiver{}
+	sender := newWebhookSender(t, receiver)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		_ = sender.Run(ctx)
+	}()
+
+	require.NoError(t, sender.Update(ctx, testDynamicConfig("Host(`first.example.com`)")))
+	require.NoError(t, sender.Update(ctx, testDynamicConfig("Host(`last.example.com`)")))
+
+	assert.Eventually(t, func() bool {
+		return len(receiver.requests()) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Contains(t, receiver.requests()[0], "last.example.com")
+}
+
+func TestWebhookSenderFlushesOnShutdown(t *testing.T) {
+	receiver := &webhookReceiver{}
+	sender := newWebhookSender(t, receiver)
+	sender.debounce = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		_ = sender.Run(ctx)
+		close(done)
+	}()
+
+	// Still debouncing when the shutdown starts
+	require.NoError(t, sender.Update(ctx, testDynamicConfig("Host(`app.example.com`)")))
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook sender did not stop")
+	}
+
+	require.Len(t, receiver.requests(), 1)
+	assert.Contains(t, receiver.requests()[0], "app.example.com")
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefik/traefik/v3/pkg/safe"
+)
+
+// Name is the provider name attached to every pushed message
+const Name = "traefik-fed"
+
+// Provider exposes the aggregator through Traefik's provider contract,
+// pushing the aggregated configuration after every upstream poll
+type Provider struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+// New creates a new provider
+func New(cfg *config.Config, logger *slog.Logger) *Provider {
+	return &Provider{
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// Init validates the provider configuration
+func (p *Provider) Init() error {
+	return p.config.Validate()
+}
+
+// Provide starts polling upstreams in the pool and pushes the aggregated
+// configuration to configChan until the pool is stopped
+func (p *Provider) Provide(configChan chan<- dynamic.Message, pool *safe.Pool) error {
+	pool.GoCtx(func(ctx context.Context) {
+		agg := aggregator.New(p.config, p.logger)
+
+		push := func() {
+			message := dynamic.Message{
+				ProviderName:  Name,
+				Configuration: agg.Snapshot(),
+			}
+
+			select {
+			case configChan <- message:
+			case <-ctx.Done():
+			}
+		}
+
+		agg.Refresh(ctx)
+		push()
+		agg.Run(ctx, push)
+	})
+
+	return nil
+}
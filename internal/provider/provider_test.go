@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefik/traefik/v3/pkg/safe"
+)
+
+func TestProvidePushesAggregatedRouters(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"name":"webapp@docker","provider":"docker","status":"enabled","rule":"Host(` + "`app.example.com`" + `)"}]`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := &config.Config{
+		Upstreams: []config.Upstream{
+			{Name: "host1", AdminURL: upstream.URL, ServerURL: "http://192.168.1.10:80"},
+		},
+		Output: config.OutputConfig{
+			HTTP: config.HTTPOutput{Enabled: true, Port: 8080, Path: "/config"},
+		},
+		Server: config.ServerConfig{PollInterval: 20 * time.Millisecond},
+	}
+
+	p := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, p.Init())
+
+	pool := safe.NewPool(context.Background())
+	t.Cleanup(pool.Stop)
+
+	configChan := make(chan dynamic.Message)
+	require.NoError(t, p.Provide(configChan, pool))
+
+	// The initial aggregation and the following polls are all pushed
+	for range 2 {
+		select {
+		case message := <-configChan:
+			assert.Equal(t, Name, message.ProviderName)
+			require.NotNil(t, message.Configuration)
+			assert.Contains(t, message.Configuration.HTTP.Routers, "host1-webapp")
+			assert.Contains(t, message.Configuration.HTTP.Services, "host1-traefik")
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a configuration message")
+		}
+	}
+}
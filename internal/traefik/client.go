@@ -1,19 +1,73 @@
 package traefik
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/chickenzord/traefik-fed/internal/version"
 	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 // Client handles communication with Traefik API
 type Client struct {
 	httpClient *http.Client
-	baseURL    string
+	baseURLs   []string // API base URLs of the same Traefik, tried in order
+
+	// skipMalformed logs and skips list entries that fail to decode
+	// instead of failing the whole response (nil: disabled)
+	skipMalformed *slog.Logger
+
+	mu     sync.Mutex
+	cache  map[string]*cachedList // Decoded list responses by API path
+	active int                    // Index of the base URL that last answered
+
+	spacer *requestSpacer // nil: requests are not spaced
+
+	username, password string // Basic auth, used when username is set
+	bearerToken        string
+	userAgent          string
+
+	maxResponseBytes int64 // Largest response body read
+
+	fixtureFile string // Serve routers from this file instead of the API (optional)
+
+	raw map[string][]byte // Last list response bodies by API path, nil: not kept
+}
+
+// TLSOptions configures how the client verifies the upstream API certificate
+type TLSOptions struct {
+	InsecureSkipVerify bool   // Skip certificate verification entirely
+	CAFile             string // PEM file with additional CAs to trust (optional)
+}
+
+// TransportOptions tunes the connection pooling of API requests.
+// Zero values keep the defaults of the Go HTTP client.
+type TransportOptions struct {
+	MaxIdleConns        int           // Idle connections kept across all hosts of the client
+	MaxIdleConnsPerHost int           // Idle connections kept per host
+	IdleConnTimeout     time.Duration // How long an idle connection is kept
+	DisableKeepAlives   bool          // Use a new connection for every request
 }
 
 // NewClient creates a new Traefik API client
@@ -22,7 +76,155 @@ func NewClient(baseURL string) *Client {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		baseURL: baseURL,
+		baseURLs:  []string{baseURL},
+		cache:     make(map[string]*cachedList),
+		userAgent: version.UserAgent(),
+
+		maxResponseBytes: DefaultMaxResponseBytes,
+	}
+}
+
+// NewClientWithTLS creates a new Traefik API client using the given TLS options
+func NewClientWithTLS(baseURL string, opts TLSOptions) (*Client, error) {
+	client := NewClient(baseURL)
+
+	if !opts.InsecureSkipVerify && opts.CAFile == "" {
+		return client, nil
+	}
+
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	client.httpClient.Transport = transport
+
+	return client, nil
+}
+
+// tlsConfig builds the TLS client configuration for the options
+func (o TLSOptions) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: o.InsecureSkipVerify, //nolint:gosec // explicitly enabled by the user
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to parse CA file %s: no certificates found", o.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// SkipMalformed makes router lookups decode entries one by one, logging and
+// skipping entries with an unexpected shape instead of failing the whole response
+func (c *Client) SkipMalformed(logger *slog.Logger) {
+	c.skipMalformed = logger
+}
+
+// SetBasicAuth authenticates API requests with HTTP basic auth
+func (c *Client) SetBasicAuth(username, password string) {
+	c.username = username
+	c.password = password
+}
+
+// SetBearerToken authenticates API requests with a bearer token
+func (c *Client) SetBearerToken(token string) {
+	c.bearerToken = token
+}
+
+// SetUserAgent overrides the User-Agent of API requests, traefik-fed/<version>
+// by default. An empty value keeps the default.
+func (c *Client) SetUserAgent(userAgent string) {
+	if userAgent != "" {
+		c.userAgent = userAgent
+	}
+}
+
+// SetMaxResponseBytes limits the size of response bodies; larger responses
+// fail instead of being read into memory. Values below one keep the default.
+func (c *Client) SetMaxResponseBytes(limit int64) {
+	if limit > 0 {
+		c.maxResponseBytes = limit
+	}
+}
+
+// SetTransportOptions applies connection pooling settings to the client's
+// transport, keeping its TLS configuration
+func (c *Client) SetTransportOptions(opts TransportOptions) {
+	if opts == (TransportOptions{}) {
+		return
+	}
+
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+		c.httpClient.Transport = transport
+	}
+
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+	}
+
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+}
+
+// KeepRawResponses makes the client keep the last body received for each list
+// endpoint, returned by RawResponse for debugging
+func (c *Client) KeepRawResponses() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.raw == nil {
+		c.raw = make(map[string][]byte)
+	}
+}
+
+// RawResponse returns the last body received from the list endpoint at
+// apiPath, e.g. "/http/routers", or nil when none was kept. For paginated
+// lists this is the first page. A 304 Not Modified keeps the previous body.
+func (c *Client) RawResponse(apiPath string) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.raw[apiPath]
+}
+
+// AddFailoverURLs adds API base URLs of the same Traefik, tried in order
+// when the URLs before them fail
+func (c *Client) AddFailoverURLs(baseURLs ...string) {
+	c.baseURLs = append(c.baseURLs, baseURLs...)
+}
+
+// SetMinRequestInterval spaces successive API requests of this client,
+// including retries, by at least interval
+func (c *Client) SetMinRequestInterval(interval time.Duration) {
+	c.spacer = nil
+	if interval > 0 {
+		c.spacer = &requestSpacer{interval: interval}
 	}
 }
 
@@ -52,58 +254,475 @@ type RouterInfo struct {
 
 // GetRouters fetches all HTTP routers from the Traefik API
 func (c *Client) GetRouters() ([]*RouterInfo, error) {
-	url := fmt.Sprintf("%s/http/routers", c.baseURL)
+	return c.GetRoutersContext(context.Background())
+}
 
-	resp, err := c.httpClient.Get(url)
+// GetRoutersContext fetches all HTTP routers from the Traefik API,
+// aborting the request when the context is cancelled
+func (c *Client) GetRoutersContext(ctx context.Context) ([]*RouterInfo, error) {
+	// Traefik API returns an array of routers
+	routers, err := getList[RouterInfo](ctx, c, "/http/routers")
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch routers: %w", err)
 	}
 
+	return routers, nil
+}
+
+// EntryPointInfo represents an entrypoint from the Traefik API
+type EntryPointInfo struct {
+	Name    string `json:"name"`
+	Address string `json:"address"` // e.g., ":53/udp"
+}
+
+// GetEntryPointsContext fetches all entrypoints from the Traefik API
+func (c *Client) GetEntryPointsContext(ctx context.Context) ([]*EntryPointInfo, error) {
+	var entryPoints []*EntryPointInfo
+	if err := c.getJSON(ctx, "/entrypoints", &entryPoints); err != nil {
+		return nil, fmt.Errorf("failed to fetch entrypoints: %w", err)
+	}
+
+	return entryPoints, nil
+}
+
+// errNotModified is returned by fetch when the cached response is still current
+var errNotModified = errors.New("not modified")
+
+// cachedList is a decoded list response along with its cache validators
+type cachedList struct {
+	etag         string
+	lastModified string
+	items        any
+}
+
+// maxPages bounds how many pages of a paginated list response are fetched
+const maxPages = 100
+
+// getList fetches a JSON array from the API path, following the X-Next-Page
+// header of paginated responses. When skipMalformed is set, elements are
+// decoded individually and those that fail are skipped.
+//
+// Responses carrying an ETag or Last-Modified header are cached per path and
+// revalidated on the next call; on 304 Not Modified the previously decoded
+// items are returned as-is, so callers must not modify them. Lists spanning
+// several pages are not cached.
+func getList[T any](ctx context.Context, c *Client, apiPath string) ([]*T, error) {
+	c.mu.Lock()
+	cached := c.cache[apiPath]
+	c.mu.Unlock()
+
+	body, header, err := c.fetch(ctx, apiPath, cached)
+	if errors.Is(err, errNotModified) {
+		return cached.items.([]*T), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.raw != nil {
+		c.raw[apiPath] = body
+	}
+	c.mu.Unlock()
+
+	items, err := decodeList[T](c, apiPath, body)
+	if err != nil {
+		return nil, err
+	}
+
+	etag, lastModified := header.Get("ETag"), header.Get("Last-Modified")
+	next := nextPage(header, 1)
+
+	c.mu.Lock()
+	if next == 0 && (etag != "" || lastModified != "") {
+		c.cache[apiPath] = &cachedList{etag: etag, lastModified: lastModified, items: items}
+	} else {
+		delete(c.cache, apiPath)
+	}
+	c.mu.Unlock()
+
+	for page := 1; next != 0; page++ {
+		if page >= maxPages {
+			return nil, fmt.Errorf("response has more than %d pages", maxPages)
+		}
+
+		pagePath := fmt.Sprintf("%s?page=%d", apiPath, next)
+
+		body, header, err := c.fetch(ctx, pagePath, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		pageItems, err := decodeList[T](c, pagePath, body)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, pageItems...)
+		next = nextPage(header, next)
+	}
+
+	return items, nil
+}
+
+// nextPage returns the page announced by the X-Next-Page header, or 0 when
+// there is none. Traefik points the last page back to page 1.
+func nextPage(header http.Header, current int) int {
+	next, err := strconv.Atoi(header.Get("X-Next-Page"))
+	if err != nil || next <= current {
+		return 0
+	}
+
+	return next
+}
+
+// decodeList decodes a JSON array, skipping malformed elements when enabled
+func decodeList[T any](c *Client, apiPath string, body []byte) ([]*T, error) {
+	// Some proxies answer errors with 200 and a JSON object or HTML page
+	trimmed := bytes.TrimSpace(body)
+	if !bytes.HasPrefix(trimmed, []byte("[")) && !bytes.Equal(trimmed, []byte("null")) {
+		return nil, fmt.Errorf("expected a JSON array from %s, got: %s", apiPath, bodySnippet(trimmed))
+	}
+
+	if c.skipMalformed == nil {
+		var items []*T
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		return items, nil
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	items := make([]*T, 0, len(raw))
+
+	for i, element := range raw {
+		var item T
+		if err := json.Unmarshal(element, &item); err != nil {
+			c.skipMalformed.Warn("skipping malformed API entry",
+				"path", apiPath,
+				"index", i,
+				"error", err)
+
+			continue
+		}
+
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+// DefaultMaxResponseBytes is the default limit of upstream API response
+// bodies, also the default max_response_bytes of upstreams
+const DefaultMaxResponseBytes = 32 << 20
+
+// maxSnippet is the number of body bytes quoted in errors
+const maxSnippet = 200
+
+// bodySnippet returns the start of a response body for error messages
+func bodySnippet(body []byte) string {
+	if len(body) == 0 {
+		return "empty body"
+	}
+
+	if len(body) > maxSnippet {
+		return strconv.Quote(string(body[:maxSnippet])) + "..."
+	}
+
+	return strconv.Quote(string(body))
+}
+
+// getJSON performs a GET request against the API path and decodes the JSON response into v
+func (c *Client) getJSON(ctx context.Context, apiPath string, v any) error {
+	body, _, err := c.fetch(ctx, apiPath, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
+
+// joinURL joins the API path, optionally carrying a query, onto the base URL.
+// Any path prefix and query of the base URL are kept, so the client works
+// behind proxies serving the API below a prefix such as /traefik/api.
+func joinURL(baseURL, apiPath string) string {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return strings.TrimSuffix(baseURL, "/") + apiPath
+	}
+
+	apiPath, rawQuery, _ := strings.Cut(apiPath, "?")
+
+	u := base.JoinPath(apiPath)
+	if rawQuery != "" {
+		u.RawQuery = strings.TrimPrefix(u.RawQuery+"&"+rawQuery, "&")
+	}
+
+	return u.String()
+}
+
+// fetch performs a GET request against the API path and returns the response body.
+// When cached is set, the request is made conditional and errNotModified is
+// returned if the upstream answers 304 Not Modified.
+//
+// With several base URLs, the one that answered last is tried first and the
+// others follow in order until one succeeds.
+func (c *Client) fetch(ctx context.Context, apiPath string, cached *cachedList) ([]byte, http.Header, error) {
+	if c.fixtureFile != "" {
+		return c.readFixture(apiPath)
+	}
+
+	c.mu.Lock()
+	active := c.active
+	c.mu.Unlock()
+
+	var errs []error
+
+	for i := range c.baseURLs {
+		index := (active + i) % len(c.baseURLs)
+		baseURL := c.baseURLs[index]
+
+		body, header, err := c.fetchFrom(ctx, baseURL, apiPath, cached)
+		if err == nil || errors.Is(err, errNotModified) {
+			c.mu.Lock()
+			c.active = index
+			c.mu.Unlock()
+
+			return body, header, err
+		}
+
+		if len(c.baseURLs) == 1 || ctx.Err() != nil {
+			return nil, nil, err
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", baseURL, err))
+	}
+
+	return nil, nil, fmt.Errorf("all %d API URLs failed: %w", len(c.baseURLs), errors.Join(errs...))
+}
+
+// fetchFrom performs a GET request against the API path of one base URL
+func (c *Client) fetchFrom(ctx context.Context, baseURL, apiPath string, cached *cachedList) (_ []byte, _ http.Header, err error) {
+	ctx, span := startRequestSpan(ctx, apiPath)
+	defer func() {
+		endRequestSpan(span, err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, joinURL(baseURL, apiPath), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	span.SetAttributes(
+		semconv.HTTPRequestMethodGet,
+		semconv.URLFull(req.URL.Redacted()),
+		semconv.ServerAddress(req.URL.Hostname()),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	req.Header.Set("User-Agent", c.userAgent)
+
+	switch {
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	case c.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	if c.spacer != nil {
+		if err := c.spacer.wait(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if cached != nil {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	var trace *requestTrace
+
+	if logger := traceLogger(ctx); logger != nil {
+		trace = newRequestTrace()
+		req = req.WithContext(httptrace.WithClientTrace(ctx, trace.clientTrace()))
+
+		defer func() {
+			trace.log(logger, req.URL.Redacted(), err)
+		}()
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 
+	if trace != nil {
+		trace.status = resp.StatusCode
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return nil, resp.Header, errNotModified
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxSnippet+1))
+		return nil, nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, bodySnippet(body))
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	// Read one byte past the limit to tell a full body from a truncated one
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxResponseBytes+1))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Traefik API returns an array of routers
-	var routers []*RouterInfo
-	if err := json.Unmarshal(body, &routers); err != nil {
-		return nil, fmt.Errorf("failed to parse routers: %w", err)
+	if int64(len(body)) > c.maxResponseBytes {
+		return nil, nil, fmt.Errorf("response body exceeds the limit of %d bytes", c.maxResponseBytes)
 	}
 
-	return routers, nil
+	return body, resp.Header, nil
+}
+
+// RouterFilter defines the criteria used by FilterRouters
+type RouterFilter struct {
+	Provider  string         // Keep only routers from this provider (optional)
+	Status    string         // Keep only routers with this status (optional)
+	Exclude   []string       // Drop routers whose full name (e.g., "dashboard@docker") matches any glob
+	RuleRegex *regexp.Regexp // Keep only routers whose raw rule matches (optional)
+
+	// Keep only routers with a middleware matching this glob (optional).
+	// Patterns without "@" are matched against the name without its provider.
+	HasMiddleware string
+
+	// Keep only routers listening on any of these entrypoints (optional).
+	// Routers without explicit entrypoints are dropped when set.
+	EntryPoints []string
+
+	// Keep routers of Traefik's internal provider (e.g. api@internal),
+	// which are dropped by default
+	IncludeInternal bool
 }
 
-// FilterRouters filters routers based on provider and status
-func FilterRouters(routers []*RouterInfo, provider, status string) []*RouterInfo {
+// ExclusionReason tells which filter left a router out
+type ExclusionReason string
+
+// Reasons reported by RouterFilter.Exclusion
+const (
+	ExcludedInternal   ExclusionReason = "internal"   // Routers of Traefik's internal provider
+	ExcludedProvider   ExclusionReason = "provider"   // Provider differs from the filter
+	ExcludedStatus     ExclusionReason = "status"     // Status differs from the filter
+	ExcludedRule       ExclusionReason = "rule"       // Rule does not match the rule regex
+	ExcludedMiddleware ExclusionReason = "middleware" // No middleware matches the filter
+	ExcludedEntryPoint ExclusionReason = "entrypoint" // None of the entrypoints matches the filter
+	ExcludedByName     ExclusionReason = "exclude"    // Name matches an exclude pattern
+)
+
+// Exclusion returns why the filter leaves the router out, or an empty reason
+// when the router is kept. Exclusions by name take precedence over inclusions,
+// so they are only reported for routers passing every other filter.
+func (f RouterFilter) Exclusion(router *RouterInfo) ExclusionReason {
+	switch {
+	case router.Provider == "internal" && !f.IncludeInternal:
+		return ExcludedInternal
+	case f.Provider != "" && router.Provider != f.Provider:
+		return ExcludedProvider
+	case f.Status != "" && router.Status != f.Status:
+		return ExcludedStatus
+	case f.RuleRegex != nil && !f.RuleRegex.MatchString(router.Rule):
+		return ExcludedRule
+	case f.HasMiddleware != "" && !hasMiddleware(router.Middlewares, f.HasMiddleware):
+		return ExcludedMiddleware
+	case len(f.EntryPoints) > 0 && !hasAnyEntryPoint(router.EntryPoints, f.EntryPoints):
+		return ExcludedEntryPoint
+	case matchesAny(router.Name, f.Exclude):
+		return ExcludedByName
+	}
+
+	return ""
+}
+
+// FilterRouters filters routers based on the given filter.
+// Exclusions are applied last and take precedence over inclusions.
+func FilterRouters(routers []*RouterInfo, filter RouterFilter) []*RouterInfo {
+	filtered, _ := FilterRoutersWithReasons(routers, filter)
+	return filtered
+}
+
+// FilterRoutersWithReasons filters routers like FilterRouters and also
+// returns why each dropped router was excluded, keyed by router name
+func FilterRoutersWithReasons(routers []*RouterInfo, filter RouterFilter) ([]*RouterInfo, map[string]ExclusionReason) {
 	filtered := make([]*RouterInfo, 0)
+	reasons := make(map[string]ExclusionReason)
 
 	for _, router := range routers {
-		// Always exclude internal provider
-		if router.Provider == "internal" {
+		if reason := filter.Exclusion(router); reason != "" {
+			reasons[router.Name] = reason
 			continue
 		}
 
-		// Filter by provider if specified
-		if provider != "" && router.Provider != provider {
-			continue
+		filtered = append(filtered, router)
+	}
+
+	return filtered, reasons
+}
+
+// hasAnyEntryPoint reports whether any of the router entrypoints is allowed
+func hasAnyEntryPoint(entryPoints, allowed []string) bool {
+	for _, ep := range entryPoints {
+		if slices.Contains(allowed, ep) {
+			return true
 		}
+	}
 
-		// Filter by status if specified
-		if status != "" && router.Status != status {
-			continue
+	return false
+}
+
+// hasMiddleware reports whether any middleware matches the glob pattern.
+// Without "@" in the pattern, the provider suffix of middleware names is ignored,
+// so "auth" matches "auth@file".
+func hasMiddleware(middlewares []string, pattern string) bool {
+	withProvider := strings.Contains(pattern, "@")
+
+	for _, middleware := range middlewares {
+		if !withProvider {
+			middleware, _, _ = strings.Cut(middleware, "@")
 		}
 
-		filtered = append(filtered, router)
+		if ok, _ := path.Match(pattern, middleware); ok {
+			return true
+		}
 	}
 
-	return filtered
+	return false
+}
+
+// matchesAny reports whether name matches any of the glob patterns
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,805 @@
+package traefik
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chickenzord/traefik-fed/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func routerNames(routers []*RouterInfo) []string {
+	names := make([]string, 0, len(routers))
+	for _, router := range routers {
+		names = append(names, router.Name)
+	}
+
+	return names
+}
+
+func TestFilterRoutersExclude(t *testing.T) {
+	routers := []*RouterInfo{
+		{Name: "webapp@docker", Provider: "docker", Status: "enabled"},
+		{Name: "admin-ui@docker", Provider: "docker", Status: "enabled"},
+		{Name: "admin-db@docker", Provider: "docker", Status: "enabled"},
+		{Name: "whoami@docker", Provider: "docker", Status: "enabled"},
+		{Name: "api@internal", Provider: "internal", Status: "enabled"},
+	}
+
+	tests := []struct {
+		name     string
+		exclude  []string
+		expected []string
+	}{
+		{
+			name:     "no exclusions",
+			exclude:  nil,
+			expected: []string{"webapp@docker", "admin-ui@docker", "admin-db@docker", "whoami@docker"},
+		},
+		{
+			name:     "literal exclusion",
+			exclude:  []string{"whoami@docker"},
+			expected: []string{"webapp@docker", "admin-ui@docker", "admin-db@docker"},
+		},
+		{
+			name:     "glob exclusion",
+			exclude:  []string{"admin-*"},
+			expected: []string{"webapp@docker", "whoami@docker"},
+		},
+		{
+			name:     "base name without provider does not match literally",
+			exclude:  []string{"whoami"},
+			expected: []string{"webapp@docker", "admin-ui@docker", "admin-db@docker", "whoami@docker"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := FilterRouters(routers, RouterFilter{Status: "enabled", Exclude: tt.exclude})
+			assert.Equal(t, tt.expected, routerNames(filtered))
+		})
+	}
+}
+
+func TestFilterRoutersExcludeTakesPrecedence(t *testing.T) {
+	routers := []*RouterInfo{
+		{Name: "webapp@docker", Provider: "docker", Status: "enabled"},
+		{Name: "dashboard@docker", Provider: "docker", Status: "enabled"},
+	}
+
+	filtered := FilterRouters(routers, RouterFilter{
+		Provider: "docker",
+		Status:   "enabled",
+		Exclude:  []string{"dashboard@*"},
+	})
+
+	assert.Equal(t, []string{"webapp@docker"}, routerNames(filtered))
+}
+
+func TestFilterRoutersWithReasons(t *testing.T) {
+	routers := []*RouterInfo{
+		{Name: "webapp@docker", Provider: "docker", Status: "enabled", Rule: "Host(`app.example.com`)", EntryPoints: []string{"websecure"}},
+		{Name: "api@internal", Provider: "internal", Status: "enabled"},
+		{Name: "static@file", Provider: "file", Status: "enabled"},
+		{Name: "old@docker", Provider: "docker", Status: "disabled"},
+		{Name: "lan@docker", Provider: "docker", Status: "enabled", Rule: "Host(`app.lan`)"},
+		{Name: "web@docker", Provider: "docker", Status: "enabled", Rule: "Host(`web.example.com`)", EntryPoints: []string{"web"}},
+		{Name: "admin@docker", Provider: "docker", Status: "enabled", Rule: "Host(`admin.example.com`)", EntryPoints: []string{"websecure"}},
+	}
+
+	filtered, reasons := FilterRoutersWithReasons(routers, RouterFilter{
+		Provider:    "docker",
+		Status:      "enabled",
+		RuleRegex:   regexp.MustCompile(`example\.com`),
+		EntryPoints: []string{"websecure"},
+		Exclude:     []string{"admin@*"},
+	})
+
+	assert.Equal(t, []string{"webapp@docker"}, routerNames(filtered))
+	assert.Equal(t, map[string]ExclusionReason{
+		"api@internal": ExcludedInternal,
+		"static@file":  ExcludedProvider,
+		"old@docker":   ExcludedStatus,
+		"lan@docker":   ExcludedRule,
+		"web@docker":   ExcludedEntryPoint,
+		"admin@docker": ExcludedByName,
+	}, reasons)
+}
+
+func TestFilterRoutersInternal(t *testing.T) {
+	routers := []*RouterInfo{
+		{Name: "webapp@docker", Provider: "docker", Status: "enabled"},
+		{Name: "api@internal", Provider: "internal", Status: "enabled"},
+		{Name: "dashboard@internal", Provider: "internal", Status: "enabled"},
+	}
+
+	// Internal routers are excluded by default
+	filtered, reasons := FilterRoutersWithReasons(routers, RouterFilter{Status: "enabled"})
+	assert.Equal(t, []string{"webapp@docker"}, routerNames(filtered))
+	assert.Equal(t, ExcludedInternal, reasons["api@internal"])
+
+	// Other filters still apply to included internal routers
+	filtered = FilterRouters(routers, RouterFilter{
+		Status:          "enabled",
+		Exclude:         []string{"dashboard@*"},
+		IncludeInternal: true,
+	})
+	assert.Equal(t, []string{"webapp@docker", "api@internal"}, routerNames(filtered))
+}
+
+func TestFilterRoutersRuleRegex(t *testing.T) {
+	routers := []*RouterInfo{
+		{Name: "app@docker", Rule: "Host(`app.example.com`)"},
+		{Name: "api@docker", Rule: "Host(`api.example.com`) && PathPrefix(`/v1`)"},
+		{Name: "multi@docker", Rule: "Host(`a.other.org`) || Host(`b.example.com`)"},
+		{Name: "other@docker", Rule: "Host(`www.other.org`)"},
+		{Name: "path@docker", Rule: "PathPrefix(`/static`)"},
+		{Name: "regexp@docker", Rule: "HostRegexp(`^.+\\.example\\.com$`)"},
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{
+			name:     "domain suffix",
+			pattern:  `\.example\.com\x60`, // \x60 is a backtick
+			expected: []string{"app@docker", "api@docker", "multi@docker"},
+		},
+		{
+			name:     "substring",
+			pattern:  `PathPrefix`,
+			expected: []string{"api@docker", "path@docker"},
+		},
+		{
+			name:     "anchored",
+			pattern:  `^HostRegexp`,
+			expected: []string{"regexp@docker"},
+		},
+		{
+			name:     "no match",
+			pattern:  `example\.net`,
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := FilterRouters(routers, RouterFilter{RuleRegex: regexp.MustCompile(tt.pattern)})
+			assert.Equal(t, tt.expected, routerNames(filtered))
+		})
+	}
+}
+
+func TestGetRoutersContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/http/routers", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"name": "webapp@docker", "provider": "docker", "status": "enabled"}]`))
+	}))
+	defer server.Close()
+
+	routers, err := NewClient(server.URL + "/api").GetRoutersContext(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"webapp@docker"}, routerNames(routers))
+}
+
+func TestGetRoutersPrefixedBaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/traefik/api/http/routers", r.URL.Path)
+		_, _ = w.Write([]byte(`[{"name": "webapp@docker", "provider": "docker", "status": "enabled"}]`))
+	}))
+	defer server.Close()
+
+	for _, baseURL := range []string{server.URL + "/traefik/api", server.URL + "/traefik/api/"} {
+		routers, err := NewClient(baseURL).GetRoutersContext(context.Background())
+		require.NoError(t, err, baseURL)
+		assert.Equal(t, []string{"webapp@docker"}, routerNames(routers))
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		baseURL  string
+		apiPath  string
+		expected string
+	}{
+		{baseURL: "http://traefik:8080/api", apiPath: "/http/routers", expected: "http://traefik:8080/api/http/routers"},
+		{baseURL: "https://proxy.internal/traefik/api", apiPath: "/http/routers", expected: "https://proxy.internal/traefik/api/http/routers"},
+		{baseURL: "https://proxy.internal/traefik/api/", apiPath: "/http/routers", expected: "https://proxy.internal/traefik/api/http/routers"},
+		{baseURL: "https://proxy.internal/traefik/api", apiPath: "/http/routers?page=2", expected: "https://proxy.internal/traefik/api/http/routers?page=2"},
+		{baseURL: "https://proxy.internal/api?tenant=a", apiPath: "/http/routers?page=2", expected: "https://proxy.internal/api/http/routers?tenant=a&page=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseURL+tt.apiPath, func(t *testing.T) {
+			assert.Equal(t, tt.expected, joinURL(tt.baseURL, tt.apiPath))
+		})
+	}
+}
+
+func TestClientFailoverURLs(t *testing.T) {
+	var primaryHits, secondaryHits atomic.Int32
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryHits.Add(1)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	t.Cleanup(primary.Close)
+
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryHits.Add(1)
+		_, _ = w.Write([]byte(`[{"name": "webapp@docker", "provider": "docker", "status": "enabled"}]`))
+	}))
+	t.Cleanup(secondary.Close)
+
+	client := NewClient(primary.URL)
+	client.AddFailoverURLs(secondary.URL)
+
+	routers, err := client.GetRouters()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"webapp@docker"}, routerNames(routers))
+	assert.Equal(t, int32(1), primaryHits.Load())
+
+	// The URL that answered is tried first from now on
+	_, err = client.GetRouters()
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), primaryHits.Load())
+	assert.Equal(t, int32(2), secondaryHits.Load())
+}
+
+func TestClientFailoverURLsAllFailing(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(failing.Close)
+
+	client := NewClient(failing.URL)
+	client.AddFailoverURLs("http://127.0.0.1:1")
+
+	_, err := client.GetRouters()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all 2 API URLs failed")
+	assert.Contains(t, err.Error(), failing.URL+": API returned status 503")
+	assert.Contains(t, err.Error(), "http://127.0.0.1:1: ")
+}
+
+func TestClientErrorStatusQuotesBodySnippet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(strings.Repeat("x", 10000)))
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := NewClient(server.URL).GetRouters()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "API returned status 500: \""+strings.Repeat("x", maxSnippet)+"\"...")
+	assert.Less(t, len(err.Error()), 1000)
+}
+
+func TestClientUserAgent(t *testing.T) {
+	var userAgent atomic.Value
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent.Store(r.Header.Get("User-Agent"))
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL)
+
+	_, err := client.GetRouters()
+	require.NoError(t, err)
+	assert.Equal(t, "traefik-fed/"+version.Version, userAgent.Load())
+
+	client.SetUserAgent("federation/1.0 (ops@example.com)")
+
+	_, err = client.GetRouters()
+	require.NoError(t, err)
+	assert.Equal(t, "federation/1.0 (ops@example.com)", userAgent.Load())
+}
+
+func TestGetRoutersResponseTooLarge(t *testing.T) {
+	body := `[` + strings.Repeat(`{"name":"web@docker","status":"enabled"},`, 100) + `{}]`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL)
+	client.SetMaxResponseBytes(int64(len(body)) - 1)
+
+	_, err := client.GetRouters()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("response body exceeds the limit of %d bytes", len(body)-1))
+
+	client.SetMaxResponseBytes(int64(len(body)))
+
+	routers, err := client.GetRouters()
+	require.NoError(t, err)
+	assert.Len(t, routers, 101)
+}
+
+func TestGetRoutersContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := NewClient(server.URL + "/api").GetRoutersContext(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFilterUDPRouters(t *testing.T) {
+	routers := []*UDPRouterInfo{
+		{Name: "dns@docker", Provider: "docker"},
+		{Name: "syslog@docker", Provider: "docker", Status: "enabled"},
+		{Name: "ntp@docker", Provider: "docker", Status: "disabled"},
+		{Name: "dns@file", Provider: "file", Status: "enabled"},
+		{Name: "internal@internal", Provider: "internal"},
+	}
+
+	filtered := FilterUDPRouters(routers, RouterFilter{
+		Provider:  "docker",
+		Status:    "enabled",
+		RuleRegex: regexp.MustCompile(`never-matches`),
+	})
+
+	assert.Equal(t, []string{"dns@docker", "syslog@docker"}, udpRouterNames(filtered))
+}
+
+func TestFilterUDPRoutersIncludeInternal(t *testing.T) {
+	routers := []*UDPRouterInfo{
+		{Name: "dns@docker", Provider: "docker"},
+		{Name: "internal@internal", Provider: "internal"},
+	}
+
+	assert.Equal(t, []string{"dns@docker"}, udpRouterNames(FilterUDPRouters(routers, RouterFilter{})))
+	assert.Equal(t, []string{"dns@docker", "internal@internal"},
+		udpRouterNames(FilterUDPRouters(routers, RouterFilter{IncludeInternal: true})))
+}
+
+func TestFilterTCPRouters(t *testing.T) {
+	routers := []*TCPRouterInfo{
+		{Name: "postgres@file", Provider: "file", Status: "enabled", Rule: "HostSNI(`db.example.com`)", EntryPoints: []string{"postgres"}},
+		{Name: "redis@file", Provider: "file", Rule: "HostSNI(`*`)", EntryPoints: []string{"redis"}},
+		{Name: "mqtt@file", Provider: "file", Status: "disabled", Rule: "HostSNI(`*`)", EntryPoints: []string{"mqtt"}},
+		{Name: "ssh@docker", Provider: "docker", Status: "enabled", Rule: "HostSNI(`*`)", EntryPoints: []string{"ssh"}},
+		{Name: "admin@file", Provider: "file", Status: "enabled", Rule: "HostSNI(`*`)", Middlewares: []string{"allowlist@file"}},
+	}
+
+	tests := []struct {
+		name   string
+		filter RouterFilter
+		want   []string
+	}{
+		{name: "provider and status", filter: RouterFilter{Provider: "file", Status: "enabled"}, want: []string{"postgres@file", "redis@file", "admin@file"}},
+		{name: "rule regex", filter: RouterFilter{RuleRegex: regexp.MustCompile(`example\.com`)}, want: []string{"postgres@file"}},
+		{name: "middleware", filter: RouterFilter{HasMiddleware: "allowlist"}, want: []string{"admin@file"}},
+		{name: "entrypoints", filter: RouterFilter{EntryPoints: []string{"ssh", "mqtt"}}, want: []string{"mqtt@file", "ssh@docker"}},
+		{name: "exclude", filter: RouterFilter{Status: "enabled", Exclude: []string{"*@file"}}, want: []string{"ssh@docker"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, router := range FilterTCPRouters(routers, tt.filter) {
+				names = append(names, router.Name)
+			}
+
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func udpRouterNames(routers []*UDPRouterInfo) []string {
+	names := make([]string, 0, len(routers))
+	for _, router := range routers {
+		names = append(names, router.Name)
+	}
+
+	return names
+}
+
+func newTLSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"name":"web@docker","provider":"docker","status":"enabled","rule":"Host(` + "`a.example.com`" + `)"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestClientTLSVerification(t *testing.T) {
+	server := newTLSServer(t)
+
+	// The httptest certificate is self-signed, so default verification fails
+	_, err := NewClient(server.URL).GetRouters()
+	require.Error(t, err)
+
+	t.Run("insecure skip verify", func(t *testing.T) {
+		client, err := NewClientWithTLS(server.URL, TLSOptions{InsecureSkipVerify: true})
+		require.NoError(t, err)
+
+		routers, err := client.GetRouters()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"web@docker"}, routerNames(routers))
+	})
+
+	t.Run("custom CA file", func(t *testing.T) {
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		require.NoError(t, os.WriteFile(caFile, certPEM, 0o600))
+
+		client, err := NewClientWithTLS(server.URL, TLSOptions{CAFile: caFile})
+		require.NoError(t, err)
+
+		routers, err := client.GetRouters()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"web@docker"}, routerNames(routers))
+	})
+
+	t.Run("invalid CA file", func(t *testing.T) {
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+
+		_, err := NewClientWithTLS(server.URL, TLSOptions{CAFile: caFile})
+		require.Error(t, err)
+	})
+}
+
+func TestClientTransportOptions(t *testing.T) {
+	opts := TransportOptions{
+		MaxIdleConns:        50,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     30 * time.Second,
+		DisableKeepAlives:   true,
+	}
+
+	t.Run("default transport", func(t *testing.T) {
+		client := NewClient("http://localhost:8080")
+		client.SetTransportOptions(opts)
+
+		transport, ok := client.httpClient.Transport.(*http.Transport)
+		require.True(t, ok)
+		assert.Equal(t, 50, transport.MaxIdleConns)
+		assert.Equal(t, 10, transport.MaxIdleConnsPerHost)
+		assert.Equal(t, 30*time.Second, transport.IdleConnTimeout)
+		assert.True(t, transport.DisableKeepAlives)
+
+		// The shared default transport is left untouched
+		assert.NotSame(t, http.DefaultTransport, transport)
+		assert.False(t, http.DefaultTransport.(*http.Transport).DisableKeepAlives)
+	})
+
+	t.Run("keeps TLS configuration", func(t *testing.T) {
+		client, err := NewClientWithTLS("https://localhost:8443", TLSOptions{InsecureSkipVerify: true})
+		require.NoError(t, err)
+
+		client.SetTransportOptions(TransportOptions{MaxIdleConnsPerHost: 10})
+
+		transport, ok := client.httpClient.Transport.(*http.Transport)
+		require.True(t, ok)
+		assert.Equal(t, 10, transport.MaxIdleConnsPerHost)
+		assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+	})
+
+	t.Run("zero options keep the default client", func(t *testing.T) {
+		client := NewClient("http://localhost:8080")
+		client.SetTransportOptions(TransportOptions{})
+
+		assert.Nil(t, client.httpClient.Transport)
+	})
+}
+
+func TestGetRoutersSkipMalformed(t *testing.T) {
+	// The second router has a string priority, which fails to decode
+	body := `[
+		{"name":"web@docker","provider":"docker","status":"enabled","priority":10},
+		{"name":"broken@docker","provider":"docker","status":"enabled","priority":"high"}
+	]`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL)
+
+	// By default a single malformed router fails the whole response
+	_, err := client.GetRouters()
+	require.Error(t, err)
+
+	client.SkipMalformed(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	routers, err := client.GetRouters()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web@docker"}, routerNames(routers))
+	assert.Equal(t, 10, routers[0].Priority)
+}
+
+func TestGetRoutersNonArrayResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "error object",
+			body:     `{"error": "upstream authentication required"}`,
+			expected: `expected a JSON array from /http/routers, got: "{\"error\": \"upstream authentication required\"}"`,
+		},
+		{
+			name:     "long html page",
+			body:     "<html>" + strings.Repeat("x", 500) + "</html>",
+			expected: `got: "<html>` + strings.Repeat("x", 194) + `"...`,
+		},
+		{
+			name:     "empty body",
+			body:     "",
+			expected: "got: empty body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(server.Close)
+
+			_, err := NewClient(server.URL).GetRouters()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+func TestGetRoutersPagination(t *testing.T) {
+	pages := map[string]string{
+		"":  `[{"name":"a@docker","provider":"docker","status":"enabled"},{"name":"b@docker","provider":"docker","status":"enabled"}]`,
+		"2": `[{"name":"c@docker","provider":"docker","status":"enabled"}]`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+
+		body, ok := pages[page]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Like Traefik, the last page points back to the first one
+		next := "1"
+		if page == "" {
+			next = "2"
+		}
+
+		w.Header().Set("X-Next-Page", next)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL)
+
+	routers, err := client.GetRouters()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a@docker", "b@docker", "c@docker"}, routerNames(routers))
+
+	// Paginated lists are not cached since page 1 alone cannot be revalidated
+	assert.Empty(t, client.cache)
+}
+
+func TestGetRoutersPaginationLimit(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		w.Header().Set("X-Next-Page", strconv.Itoa(max(page, 1)+1))
+		_, _ = w.Write([]byte(`[{"name":"a@docker","provider":"docker","status":"enabled"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := NewClient(server.URL).GetRouters()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "more than 100 pages")
+	assert.Equal(t, int32(maxPages), requests.Load())
+}
+
+func TestGetRoutersETagCache(t *testing.T) {
+	var requests, notModified atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`[{"name":"web@docker","provider":"docker","status":"enabled"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL)
+
+	first, err := client.GetRouters()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web@docker"}, routerNames(first))
+
+	second, err := client.GetRouters()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web@docker"}, routerNames(second))
+
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, int32(1), notModified.Load())
+
+	// The previously parsed routers are reused as-is
+	assert.Same(t, first[0], second[0])
+}
+
+func TestFilterRoutersHasMiddleware(t *testing.T) {
+	routers := []*RouterInfo{
+		{Name: "secured@docker", Provider: "docker", Status: "enabled", Middlewares: []string{"compress@file", "auth@file"}},
+		{Name: "secured-docker@docker", Provider: "docker", Status: "enabled", Middlewares: []string{"auth@docker"}},
+		{Name: "public@docker", Provider: "docker", Status: "enabled", Middlewares: []string{"compress@file"}},
+		{Name: "bare@docker", Provider: "docker", Status: "enabled"},
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{name: "unset keeps all", pattern: "", expected: []string{"secured@docker", "secured-docker@docker", "public@docker", "bare@docker"}},
+		{name: "with provider", pattern: "auth@file", expected: []string{"secured@docker"}},
+		{name: "without provider", pattern: "auth", expected: []string{"secured@docker", "secured-docker@docker"}},
+		{name: "glob", pattern: "auth*@*", expected: []string{"secured@docker", "secured-docker@docker"}},
+		{name: "no match", pattern: "ratelimit", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := FilterRouters(routers, RouterFilter{HasMiddleware: tt.pattern})
+			assert.Equal(t, tt.expected, routerNames(filtered))
+		})
+	}
+}
+
+func TestFilterRoutersEntryPoints(t *testing.T) {
+	routers := []*RouterInfo{
+		{Name: "public@docker", Provider: "docker", Status: "enabled", EntryPoints: []string{"websecure"}},
+		{Name: "both@docker", Provider: "docker", Status: "enabled", EntryPoints: []string{"web", "websecure"}},
+		{Name: "private@docker", Provider: "docker", Status: "enabled", EntryPoints: []string{"internal"}},
+		{Name: "default@docker", Provider: "docker", Status: "enabled"},
+	}
+
+	t.Run("allowlist", func(t *testing.T) {
+		filtered := FilterRouters(routers, RouterFilter{EntryPoints: []string{"websecure"}})
+		assert.Equal(t, []string{"public@docker", "both@docker"}, routerNames(filtered))
+	})
+
+	t.Run("multiple entrypoints", func(t *testing.T) {
+		filtered := FilterRouters(routers, RouterFilter{EntryPoints: []string{"web", "internal"}})
+		assert.Equal(t, []string{"both@docker", "private@docker"}, routerNames(filtered))
+	})
+
+	t.Run("unset keeps routers without entrypoints", func(t *testing.T) {
+		filtered := FilterRouters(routers, RouterFilter{})
+		assert.Contains(t, routerNames(filtered), "default@docker")
+	})
+}
+
+func TestClientMinRequestInterval(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	interval := 100 * time.Millisecond
+
+	client := NewClient(server.URL)
+	client.SetMinRequestInterval(interval)
+
+	_, err := client.GetRouters()
+	require.NoError(t, err)
+
+	_, err = client.GetRouters()
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, times, 2)
+	assert.GreaterOrEqual(t, times[1].Sub(times[0]), interval)
+}
+
+func TestClientMinRequestIntervalCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL)
+	client.SetMinRequestInterval(time.Hour)
+
+	_, err := client.GetRouters()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = client.GetRoutersContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClientAuthentication(t *testing.T) {
+	var authorization atomic.Value
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("basic auth", func(t *testing.T) {
+		client := NewClient(server.URL)
+		client.SetBasicAuth("admin", "secret")
+
+		_, err := client.GetRouters()
+		require.NoError(t, err)
+		assert.Equal(t, "Basic YWRtaW46c2VjcmV0", authorization.Load())
+	})
+
+	t.Run("bearer token", func(t *testing.T) {
+		client := NewClient(server.URL)
+		client.SetBearerToken("tok3n")
+
+		_, err := client.GetRouters()
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer tok3n", authorization.Load())
+	})
+}
@@ -0,0 +1,34 @@
+package traefik
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// fixturePath is the API path served from the fixture file
+const fixturePath = "/http/routers"
+
+// NewFixtureClient creates a client serving HTTP routers from a captured
+// /http/routers response instead of a live API. The file is read on every
+// request, so edits take effect on the next poll. Other endpoints fail.
+func NewFixtureClient(path string) *Client {
+	client := NewClient("")
+	client.fixtureFile = path
+
+	return client
+}
+
+// readFixture returns the fixture file contents for the routers path
+func (c *Client) readFixture(apiPath string) ([]byte, http.Header, error) {
+	if apiPath != fixturePath {
+		return nil, nil, fmt.Errorf("%s is not available from fixture %s", apiPath, c.fixtureFile)
+	}
+
+	body, err := os.ReadFile(c.fixtureFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	return body, http.Header{}, nil
+}
@@ -0,0 +1,42 @@
+package traefik
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixtureClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routers.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"name": "webapp@docker", "provider": "docker", "status": "enabled"},
+		{"name": "api@internal", "provider": "internal", "status": "enabled"}
+	]`), 0600))
+
+	client := NewFixtureClient(path)
+
+	routers, err := client.GetRoutersContext(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"webapp@docker", "api@internal"}, routerNames(routers))
+
+	// The file is read again on every request
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name": "other@file", "provider": "file", "status": "enabled"}]`), 0600))
+
+	routers, err = client.GetRoutersContext(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other@file"}, routerNames(routers))
+
+	_, err = client.GetUDPRoutersContext(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not available from fixture")
+}
+
+func TestFixtureClientMissingFile(t *testing.T) {
+	_, err := NewFixtureClient(filepath.Join(t.TempDir(), "missing.json")).GetRoutersContext(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read fixture")
+}
@@ -0,0 +1,35 @@
+package traefik
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// requestSpacer enforces a minimum interval between successive requests
+type requestSpacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next request may start, or ctx is cancelled
+func (s *requestSpacer) wait(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if delay := time.Until(s.next); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	s.next = time.Now().Add(s.interval)
+
+	return nil
+}
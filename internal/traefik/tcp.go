@@ -0,0 +1,56 @@
+package traefik
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// TCPRouterInfo represents a TCP router from the Traefik API
+type TCPRouterInfo struct {
+	EntryPoints []string                    `json:"entryPoints"`
+	Middlewares []string                    `json:"middlewares,omitempty"`
+	Service     string                      `json:"service"`
+	Rule        string                      `json:"rule"`
+	Priority    int                         `json:"priority"`
+	Status      string                      `json:"status,omitempty"`
+	Using       []string                    `json:"using"`
+	Name        string                      `json:"name"`
+	Provider    string                      `json:"provider"`
+	TLS         *dynamic.RouterTCPTLSConfig `json:"tls,omitempty"`
+}
+
+// GetTCPRoutersContext fetches all TCP routers from the Traefik API,
+// aborting the request when the context is cancelled
+func (c *Client) GetTCPRoutersContext(ctx context.Context) ([]*TCPRouterInfo, error) {
+	routers, err := getList[TCPRouterInfo](ctx, c, "/tcp/routers")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch TCP routers: %w", err)
+	}
+
+	return routers, nil
+}
+
+// FilterTCPRouters filters TCP routers based on the given filter, like
+// FilterRouters does for HTTP routers. Routers reporting no status are kept
+// when filtering by status.
+func FilterTCPRouters(routers []*TCPRouterInfo, filter RouterFilter) []*TCPRouterInfo {
+	filtered := make([]*TCPRouterInfo, 0)
+
+	for _, router := range routers {
+		switch {
+		case router.Provider == "internal" && !filter.IncludeInternal:
+		case filter.Provider != "" && router.Provider != filter.Provider:
+		case filter.Status != "" && router.Status != "" && router.Status != filter.Status:
+		case filter.RuleRegex != nil && !filter.RuleRegex.MatchString(router.Rule):
+		case filter.HasMiddleware != "" && !hasMiddleware(router.Middlewares, filter.HasMiddleware):
+		case len(filter.EntryPoints) > 0 && !hasAnyEntryPoint(router.EntryPoints, filter.EntryPoints):
+		case matchesAny(router.Name, filter.Exclude):
+		default:
+			filtered = append(filtered, router)
+		}
+	}
+
+	return filtered
+}
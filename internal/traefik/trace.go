@@ -0,0 +1,166 @@
+package traefik
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the API request spans
+const tracerName = "github.com/chickenzord/traefik-fed/internal/traefik"
+
+// traceLoggerKey is the context key of the logger set by WithTraceLogger
+type traceLoggerKey struct{}
+
+// WithTraceLogger returns a context under which API requests log their
+// latency breakdown to logger at debug level: DNS lookup, connection, TLS
+// handshake, time to first byte and total. Requests are only traced while
+// debug logging is enabled.
+func WithTraceLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, traceLoggerKey{}, logger)
+}
+
+// traceLogger returns the logger set by WithTraceLogger when it logs at
+// debug level, or nil
+func traceLogger(ctx context.Context) *slog.Logger {
+	logger, _ := ctx.Value(traceLoggerKey{}).(*slog.Logger)
+	if logger == nil || !logger.Enabled(ctx, slog.LevelDebug) {
+		return nil
+	}
+
+	return logger
+}
+
+// requestTrace records the phases of a single API request
+type requestTrace struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	reused       bool
+	status       int
+}
+
+// newRequestTrace starts tracing a request
+func newRequestTrace() *requestTrace {
+	return &requestTrace{start: time.Now()}
+}
+
+// clientTrace returns the hooks recording the phases of the request.
+// Connection attempts to several addresses may run concurrently, so the first
+// start and the first successful completion of each phase are kept.
+func (t *requestTrace) clientTrace() *httptrace.ClientTrace {
+	record := func(at *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		if at.IsZero() {
+			*at = time.Now()
+		}
+	}
+
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+
+			t.reused = info.Reused
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { record(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { record(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			record(&t.connectStart)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				record(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() { record(&t.tlsStart) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				record(&t.tlsDone)
+			}
+		},
+		GotFirstResponseByte: func() { record(&t.firstByte) },
+	}
+}
+
+// log writes the latency breakdown of the finished request. Phases that did
+// not happen, such as the connection of a reused one, are left out.
+func (t *requestTrace) log(logger *slog.Logger, url string, err error) {
+	total := time.Since(t.start)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	args := []any{"url", url, "reused_conn", t.reused}
+
+	phases := []struct {
+		key        string
+		start, end time.Time
+	}{
+		{"dns_ms", t.dnsStart, t.dnsDone},
+		{"connect_ms", t.connectStart, t.connectDone},
+		{"tls_ms", t.tlsStart, t.tlsDone},
+		{"ttfb_ms", t.start, t.firstByte},
+	}
+
+	for _, phase := range phases {
+		if !phase.start.IsZero() && !phase.end.IsZero() {
+			args = append(args, phase.key, durationMs(phase.end.Sub(phase.start)))
+		}
+	}
+
+	args = append(args, "total_ms", durationMs(total))
+
+	if t.status != 0 {
+		args = append(args, "status", t.status)
+	}
+
+	if err != nil {
+		args = append(args, "error", err)
+	}
+
+	logger.Debug("upstream request timing", args...)
+}
+
+// durationMs converts a duration to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// startRequestSpan starts the client span of an API request. Spans are
+// created by the tracer provider of the span in ctx, so requests made outside
+// of a traced poll are not recorded.
+func startRequestSpan(ctx context.Context, apiPath string) (context.Context, trace.Span) {
+	route, _, _ := strings.Cut(apiPath, "?")
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName)
+
+	return tracer.Start(ctx, "GET "+route, trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// endRequestSpan ends the span of an API request, marking it failed on
+// errors other than a not modified response
+func endRequestSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, errNotModified) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
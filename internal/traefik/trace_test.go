@@ -0,0 +1,113 @@
+package traefik
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// traceLines decodes the request timing records of a JSON log
+func traceLines(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+
+	for line := range strings.SplitSeq(strings.TrimSpace(logs.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+
+		if record["msg"] == "upstream request timing" {
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+func TestClientTraceRequests(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	var logs bytes.Buffer
+
+	client := NewClient(server.URL + "/api")
+	ctx := WithTraceLogger(t.Context(), slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	_, err := client.GetRoutersContext(ctx)
+	require.NoError(t, err)
+
+	_, err = client.GetRoutersContext(ctx)
+	require.NoError(t, err)
+
+	records := traceLines(t, &logs)
+	require.Len(t, records, 2)
+
+	first := records[0]
+	assert.Equal(t, server.URL+"/api/http/routers", first["url"])
+	assert.Equal(t, false, first["reused_conn"])
+	assert.Contains(t, first, "connect_ms")
+	assert.NotContains(t, first, "tls_ms")
+	assert.InDelta(t, 200, first["status"], 0)
+
+	// The delayed response shows up in the time to first byte
+	ttfb, ok := first["ttfb_ms"].(float64)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, ttfb, float64(delay.Milliseconds()))
+	assert.GreaterOrEqual(t, first["total_ms"], ttfb)
+
+	// Keep-alive connections are reused without connecting again
+	second := records[1]
+	assert.Equal(t, true, second["reused_conn"])
+	assert.NotContains(t, second, "connect_ms")
+	assert.Contains(t, second, "ttfb_ms")
+}
+
+func TestClientTraceRequestsFailure(t *testing.T) {
+	var logs bytes.Buffer
+
+	client := NewClient("http://127.0.0.1:1/api")
+	ctx := WithTraceLogger(t.Context(), slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	_, err := client.GetRoutersContext(ctx)
+	require.Error(t, err)
+
+	records := traceLines(t, &logs)
+	require.Len(t, records, 1)
+	assert.Contains(t, records[0], "error")
+	assert.Contains(t, records[0], "total_ms")
+	assert.NotContains(t, records[0], "ttfb_ms")
+}
+
+func TestClientTraceRequestsBelowDebug(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	var logs bytes.Buffer
+
+	client := NewClient(server.URL + "/api")
+	ctx := WithTraceLogger(t.Context(), slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	_, err := client.GetRoutersContext(ctx)
+	require.NoError(t, err)
+
+	assert.Empty(t, logs.String())
+}
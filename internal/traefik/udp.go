@@ -0,0 +1,70 @@
+package traefik
+
+import (
+	"context"
+	"fmt"
+)
+
+// UDPRouterInfo represents a UDP router from the Traefik API
+type UDPRouterInfo struct {
+	EntryPoints []string `json:"entryPoints"`
+	Service     string   `json:"service"`
+	Status      string   `json:"status,omitempty"`
+	Using       []string `json:"using"`
+	Name        string   `json:"name"`
+	Provider    string   `json:"provider"`
+}
+
+// GetUDPRouters fetches all UDP routers from the Traefik API
+func (c *Client) GetUDPRouters() ([]*UDPRouterInfo, error) {
+	return c.GetUDPRoutersContext(context.Background())
+}
+
+// GetUDPRoutersContext fetches all UDP routers from the Traefik API,
+// aborting the request when the context is cancelled
+func (c *Client) GetUDPRoutersContext(ctx context.Context) ([]*UDPRouterInfo, error) {
+	routers, err := getList[UDPRouterInfo](ctx, c, "/udp/routers")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch UDP routers: %w", err)
+	}
+
+	return routers, nil
+}
+
+// FilterUDPRouters filters UDP routers based on the given filter.
+// UDP routers have no rule or middlewares, so RuleRegex and HasMiddleware are
+// ignored. Routers reporting no status are kept when filtering by status.
+func FilterUDPRouters(routers []*UDPRouterInfo, filter RouterFilter) []*UDPRouterInfo {
+	filtered := make([]*UDPRouterInfo, 0)
+
+	for _, router := range routers {
+		// Exclude internal provider unless asked for
+		if router.Provider == "internal" && !filter.IncludeInternal {
+			continue
+		}
+
+		// Filter by provider if specified
+		if filter.Provider != "" && router.Provider != filter.Provider {
+			continue
+		}
+
+		// Filter by status if specified and reported
+		if filter.Status != "" && router.Status != "" && router.Status != filter.Status {
+			continue
+		}
+
+		// Filter by entrypoints if specified
+		if len(filter.EntryPoints) > 0 && !hasAnyEntryPoint(router.EntryPoints, filter.EntryPoints) {
+			continue
+		}
+
+		// Exclusions take precedence over inclusions
+		if matchesAny(router.Name, filter.Exclude) {
+			continue
+		}
+
+		filtered = append(filtered, router)
+	}
+
+	return filtered
+}
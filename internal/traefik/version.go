@@ -0,0 +1,50 @@
+package traefik
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SupportedMajorVersion is the Traefik major version whose API and rule
+// syntax traefik-fed is built and tested against
+const SupportedMajorVersion = 3
+
+// VersionInfo represents the response of the Traefik version endpoint
+type VersionInfo struct {
+	Version   string    `json:"Version"`
+	Codename  string    `json:"Codename"`
+	StartDate time.Time `json:"startDate"`
+}
+
+// MajorVersion returns the major component of the version, e.g. 3 for "3.6.6"
+// or "v3.6.6". It reports false for development or unparsable versions.
+func (v *VersionInfo) MajorVersion() (int, bool) {
+	version := strings.TrimPrefix(v.Version, "v")
+	major, _, _ := strings.Cut(version, ".")
+
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// GetVersion fetches the version of the upstream Traefik instance
+func (c *Client) GetVersion() (*VersionInfo, error) {
+	return c.GetVersionContext(context.Background())
+}
+
+// GetVersionContext fetches the version of the upstream Traefik instance,
+// aborting the request when the context is cancelled
+func (c *Client) GetVersionContext(ctx context.Context) (*VersionInfo, error) {
+	var version VersionInfo
+	if err := c.getJSON(ctx, "/version", &version); err != nil {
+		return nil, fmt.Errorf("failed to fetch version: %w", err)
+	}
+
+	return &version, nil
+}
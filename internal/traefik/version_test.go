@@ -0,0 +1,63 @@
+package traefik
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/version", r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Version":"3.6.6","Codename":"ramequin","startDate":"2026-01-02T03:04:05.123456789Z"}`))
+	}))
+	defer server.Close()
+
+	version, err := NewClient(server.URL + "/api").GetVersion()
+	require.NoError(t, err)
+
+	assert.Equal(t, "3.6.6", version.Version)
+	assert.Equal(t, "ramequin", version.Codename)
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC), version.StartDate)
+
+	major, ok := version.MajorVersion()
+	require.True(t, ok)
+	assert.Equal(t, 3, major)
+}
+
+func TestGetVersionError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := NewClient(server.URL + "/api").GetVersion()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to fetch version")
+}
+
+func TestMajorVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected int
+		ok       bool
+	}{
+		{version: "3.6.6", expected: 3, ok: true},
+		{version: "v2.11.0", expected: 2, ok: true},
+		{version: "10.0", expected: 10, ok: true},
+		{version: "dev", ok: false},
+		{version: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			major, ok := (&VersionInfo{Version: tt.version}).MajorVersion()
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, major)
+		})
+	}
+}
@@ -34,6 +34,11 @@ func Get() Info {
 	}
 }
 
+// UserAgent returns the User-Agent sent on outgoing requests
+func UserAgent() string {
+	return "traefik-fed/" + Version
+}
+
 // String returns a formatted version string
 func (i Info) String() string {
 	return fmt.Sprintf("traefik-fed %s (%s) built on %s with %s for %s",
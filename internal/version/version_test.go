@@ -42,3 +42,11 @@ func TestDefaultValues(t *testing.T) {
 	assert.Equal(t, "unknown", GitCommit)
 	assert.Equal(t, "unknown", BuildDate)
 }
+
+func TestUserAgent(t *testing.T) {
+	original := Version
+	t.Cleanup(func() { Version = original })
+
+	Version = "v1.2.3"
+	assert.Equal(t, "traefik-fed/v1.2.3", UserAgent())
+}
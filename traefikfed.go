@@ -0,0 +1,165 @@
+// Package traefikfed embeds the traefik-fed aggregation in Go programs.
+// A Federation polls the API of several Traefik instances and merges their
+// HTTP routers into one dynamic configuration routing back to them, exactly
+// like the traefik-fed command but without its outputs.
+package traefikfed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/chickenzord/traefik-fed/internal/aggregator"
+	"github.com/chickenzord/traefik-fed/internal/config"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// Configuration types, shared with the traefik-fed configuration file. Every
+// type reachable from Config is aliased so that callers can build it.
+type (
+	Config          = config.Config
+	ValidationError = config.ValidationError
+
+	Upstream    = config.Upstream
+	URLRewrite  = config.URLRewrite
+	HealthCheck = config.HealthCheck
+	BasicAuth   = config.BasicAuth
+
+	RouterConfig       = config.RouterConfig
+	RouterSelector     = config.RouterSelector
+	RouterDefaults     = config.RouterDefaults
+	ServiceHealthCheck = config.ServiceHealthCheck
+	RuleTransform      = config.RuleTransform
+	TCPRouterConfig    = config.TCPRouterConfig
+	TCPRouterDefaults  = config.TCPRouterDefaults
+	UDPRouterConfig    = config.UDPRouterConfig
+	UDPRouterDefaults  = config.UDPRouterDefaults
+
+	OutputConfig   = config.OutputConfig
+	HTTPOutput     = config.HTTPOutput
+	HTTPPath       = config.HTTPPath
+	FileOutput     = config.FileOutput
+	FileSelector   = config.FileSelector
+	WebhookOutput  = config.WebhookOutput
+	RedisOutput    = config.RedisOutput
+	MetadataOutput = config.MetadataOutput
+
+	ServerConfig   = config.ServerConfig
+	StartupCheck   = config.StartupCheck
+	CircuitBreaker = config.CircuitBreaker
+	HTTPTransport  = config.HTTPTransport
+	Tracing        = config.Tracing
+	LogConfig      = config.LogConfig
+)
+
+// LoadConfig reads a traefik-fed configuration file, applying the documented
+// defaults, and validates it
+func LoadConfig(path string) (*Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Federation aggregates the routers of the configured upstreams
+type Federation struct {
+	agg      *aggregator.Aggregator
+	logger   *slog.Logger
+	onUpdate func(*dynamic.HTTPConfiguration)
+}
+
+// Option customizes a Federation
+type Option func(*Federation)
+
+// WithLogger sets the logger of the federation; nothing is logged by default
+func WithLogger(logger *slog.Logger) Option {
+	return func(f *Federation) {
+		f.logger = logger
+	}
+}
+
+// OnUpdate sets a function called by Run with the configuration aggregated
+// after every upstream poll
+func OnUpdate(fn func(*dynamic.HTTPConfiguration)) Option {
+	return func(f *Federation) {
+		f.onUpdate = fn
+	}
+}
+
+// New creates a federation of the upstreams in cfg, failing when cfg is
+// invalid. The output settings of cfg are ignored. Unlike LoadConfig, only
+// the poll interval is defaulted: other zero values take effect as is, so an
+// empty router selector keeps routers of any status.
+func New(cfg *Config, opts ...Option) (*Federation, error) {
+	f := &Federation{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	federated := *cfg
+	if federated.Server.PollInterval == 0 {
+		federated.Server.PollInterval = config.DefaultPollInterval
+	}
+
+	if err := federated.ValidateAggregation(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	f.agg = aggregator.New(&federated, f.logger)
+
+	return f, nil
+}
+
+// Aggregate polls every upstream once and returns the merged HTTP
+// configuration. Upstreams that fail are left out; an error is only returned
+// when none of them could be aggregated.
+func (f *Federation) Aggregate(ctx context.Context) (*dynamic.HTTPConfiguration, error) {
+	f.agg.Refresh(ctx)
+
+	result := f.agg.Snapshot()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	for _, mapping := range f.agg.Mappings() {
+		if mapping.Error == "" {
+			return result.HTTP, nil
+		}
+
+		errs = append(errs, errors.New(mapping.Upstream+": "+mapping.Error))
+	}
+
+	return result.HTTP, errors.Join(errs...)
+}
+
+// Run aggregates all upstreams and then polls each of them on its own
+// interval until ctx is cancelled, passing every new aggregation to the
+// OnUpdate function
+func (f *Federation) Run(ctx context.Context) {
+	notify := func() {
+		result := f.agg.Snapshot()
+
+		if f.onUpdate != nil {
+			f.onUpdate(result.HTTP)
+		}
+	}
+
+	f.agg.Refresh(ctx)
+	notify()
+
+	f.agg.Run(ctx, notify)
+}
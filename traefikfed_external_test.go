@@ -0,0 +1,62 @@
+package traefikfed_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	traefikfed "github.com/chickenzord/traefik-fed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// The configuration is built with exported types only, as callers outside
+// the module have to
+func TestFederationWithBasicAuth(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path != "/api/http/routers" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write([]byte(`[{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)"}]`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	federation, err := traefikfed.New(&traefikfed.Config{
+		Upstreams: []traefikfed.Upstream{
+			{
+				Name:        "host1",
+				AdminURL:    upstream.URL,
+				ServerURL:   "http://192.168.1.10:80",
+				BasicAuth:   &traefikfed.BasicAuth{Username: "admin", Password: "secret"},
+				HealthCheck: &traefikfed.HealthCheck{Interval: time.Minute, Timeout: time.Second},
+			},
+		},
+		Routers: traefikfed.RouterConfig{
+			Selector: traefikfed.RouterSelector{Status: "enabled"},
+			Defaults: traefikfed.RouterDefaults{
+				TLS:         &dynamic.RouterTLSConfig{CertResolver: "letsencrypt"},
+				HealthCheck: &traefikfed.ServiceHealthCheck{Path: "/ping"},
+			},
+		},
+		Server: traefikfed.ServerConfig{
+			HTTPTransport: traefikfed.HTTPTransport{MaxIdleConns: 10},
+		},
+	})
+	require.NoError(t, err)
+
+	httpConfig, err := federation.Aggregate(context.Background())
+	require.NoError(t, err)
+
+	require.Contains(t, httpConfig.Routers, "host1-webapp")
+	assert.Equal(t, "letsencrypt", httpConfig.Routers["host1-webapp"].TLS.CertResolver)
+}
@@ -0,0 +1,177 @@
+package traefikfed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+const upstreamRouters = `[
+	{"name": "webapp@docker", "provider": "docker", "status": "enabled", "rule": "Host(` + "`app.example.com`" + `)"},
+	{"name": "api@internal", "provider": "internal", "status": "enabled", "rule": "PathPrefix(` + "`/api`" + `)"}
+]`
+
+func mockUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/http/routers" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write([]byte(upstreamRouters))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestFederationAggregate(t *testing.T) {
+	host1 := mockUpstream(t)
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(unreachable.Close)
+
+	federation, err := New(&Config{
+		Upstreams: []Upstream{
+			{Name: "host1", AdminURL: host1.URL, ServerURL: "http://192.168.1.10:80"},
+			{Name: "host2", AdminURL: unreachable.URL, ServerURL: "http://192.168.1.11:80"},
+		},
+	})
+	require.NoError(t, err)
+
+	// A failing upstream is left out without failing the aggregation
+	httpConfig, err := federation.Aggregate(context.Background())
+	require.NoError(t, err)
+
+	require.Contains(t, httpConfig.Routers, "host1-webapp")
+	assert.NotContains(t, httpConfig.Routers, "host1-api")
+	assert.Equal(t, "host1-traefik", httpConfig.Routers["host1-webapp"].Service)
+	assert.Equal(t, "http://192.168.1.10:80", httpConfig.Services["host1-traefik"].LoadBalancer.Servers[0].URL)
+}
+
+func TestFederationAggregateAllFailing(t *testing.T) {
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(unreachable.Close)
+
+	federation, err := New(&Config{
+		Upstreams: []Upstream{{Name: "host1", AdminURL: unreachable.URL, ServerURL: "http://192.168.1.10:80"}},
+	})
+	require.NoError(t, err)
+
+	_, err = federation.Aggregate(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "host1: ")
+}
+
+func TestFederationRun(t *testing.T) {
+	host1 := mockUpstream(t)
+
+	updates := make(chan *dynamic.HTTPConfiguration, 10)
+	federation, err := New(&Config{
+		Upstreams: []Upstream{{Name: "host1", AdminURL: host1.URL, ServerURL: "http://192.168.1.10:80"}},
+		Server:    ServerConfig{PollInterval: 20 * time.Millisecond},
+	}, OnUpdate(func(httpConfig *dynamic.HTTPConfiguration) {
+		select {
+		case updates <- httpConfig:
+		default:
+		}
+	}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		federation.Run(ctx)
+		close(done)
+	}()
+
+	// The initial aggregation and the following polls are all passed on
+	for range 2 {
+		select {
+		case httpConfig := <-updates:
+			assert.Contains(t, httpConfig.Routers, "host1-webapp")
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for an update")
+		}
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	host1 := mockUpstream(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`upstreams:
+  - name: host1
+    admin_url: `+host1.URL+`
+    server_url: http://192.168.1.10:80
+output:
+  http:
+    enabled: true
+    port: 8080
+`), 0o600))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "enabled", cfg.Routers.Selector.Status)
+
+	federation, err := New(cfg)
+	require.NoError(t, err)
+
+	httpConfig, err := federation.Aggregate(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, httpConfig.Routers, "host1-webapp")
+
+	// Invalid files are rejected like by the traefik-fed command
+	require.NoError(t, os.WriteFile(path, []byte(`upstreams:
+  - name: host1
+    admin_url: `+host1.URL+`
+output:
+  http:
+    enabled: true
+    port: 8080
+`), 0o600))
+
+	_, err = LoadConfig(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream host1: server_url is required")
+}
+
+func TestNewValidatesConfig(t *testing.T) {
+	_, err := New(&Config{
+		Upstreams: []Upstream{{Name: "host1", AdminURL: "http://192.168.1.10:8080", ServerURL: "http://192.168.1.10:80"}},
+		Routers:   RouterConfig{Selector: RouterSelector{RuleRegex: "(unclosed"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routers.selector.rule_regex")
+
+	// Outputs are not required
+	host1 := mockUpstream(t)
+
+	federation, err := New(&Config{
+		Upstreams: []Upstream{{Name: "host1", AdminURL: host1.URL, ServerURL: "http://192.168.1.10:80"}},
+		Routers:   RouterConfig{Selector: RouterSelector{RuleRegex: `^PathPrefix`}},
+	})
+	require.NoError(t, err)
+
+	httpConfig, err := federation.Aggregate(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, httpConfig.Routers, "rule_regex is applied")
+}